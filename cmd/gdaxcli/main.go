@@ -10,12 +10,14 @@ import (
 	"syscall"
 
 	"github.com/LAtanassov/godax/pkg/gdax"
+	"github.com/LAtanassov/godax/pkg/products"
 	kitlog "github.com/go-kit/kit/log"
 	"github.com/gorilla/websocket"
 )
 
 var feedURI = flag.String("FEED_URI", "wss://ws-feed.gdax.com", "GDAX websocket feed")
-var snapshotURI = flag.String("SNAPSHOT_URI", "https://api.gdax.com/products/ETH-USD/book?level=3", "GDAX book snapshot")
+var apiURI = flag.String("API_URI", "https://api.gdax.com", "GDAX rest api to request book snapshots")
+var productsFile = flag.String("PRODUCTS_FILE", "", "JSON file of the product catalog, subscribes to ETH-USD if empty")
 
 func main() {
 
@@ -24,6 +26,12 @@ func main() {
 	logger := kitlog.NewLogfmtLogger(kitlog.NewSyncWriter(os.Stderr))
 	logger = kitlog.With(logger, "ts", kitlog.DefaultTimestampUTC)
 
+	productIDs, err := loadProductIDs(*productsFile)
+	if err != nil {
+		logger.Log("could not load products", err)
+		return
+	}
+
 	u, err := url.Parse(*feedURI)
 	if err != nil {
 		logger.Log("could not parse feed uri", err)
@@ -39,24 +47,26 @@ func main() {
 	}
 	defer c.Disconnect()
 
-	oc, err := c.Subscribe([]gdax.ProductID{gdax.EthUsd})
+	oc, err := c.Subscribe(productIDs)
 	if err != nil {
-		logger.Log("could not subscribe for Eth-Usd", err)
+		logger.Log("could not subscribe for", fmt.Sprint(productIDs), "err", err)
 		return
 	}
 
-	s, err := url.Parse(*snapshotURI)
-	if err != nil {
-		logger.Log("could not parse feed uri", err)
-		return
-	}
+	for _, p := range productIDs {
+		s, err := url.Parse(fmt.Sprintf("%s/products/%s/book?level=3", *apiURI, p))
+		if err != nil {
+			logger.Log("could not parse snapshot uri", err)
+			return
+		}
 
-	b, err := gdax.Snapshot(s)
-	if err != nil {
-		logger.Log("could not parse feed uri", err)
-		return
+		b, err := gdax.Snapshot(s)
+		if err != nil {
+			logger.Log("could not get snapshot", err)
+			return
+		}
+		logger.Log(p, b)
 	}
-	logger.Log(b)
 
 	go func() {
 		for o := range oc {
@@ -74,3 +84,29 @@ func main() {
 
 	log.Fatal("terminated", <-errs)
 }
+
+// loadProductIDs returns the ids of all online products of the catalog
+func loadProductIDs(file string) ([]gdax.ProductID, error) {
+	if file == "" {
+		return []gdax.ProductID{gdax.EthUsd}, nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	catalog, err := products.Load(f)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []gdax.ProductID{}
+	for _, p := range catalog {
+		if p.Status == products.Online {
+			ids = append(ids, gdax.ProductID(p.ID))
+		}
+	}
+	return ids, nil
+}
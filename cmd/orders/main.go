@@ -6,13 +6,21 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/gdax"
+	"github.com/LAtanassov/godax/pkg/orderbook"
 	"github.com/LAtanassov/godax/pkg/orders"
+	productsvc "github.com/LAtanassov/godax/pkg/products"
 	kitlog "github.com/go-kit/kit/log"
+	"github.com/gorilla/websocket"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
@@ -22,30 +30,122 @@ import (
 func main() {
 
 	var ( // configuration
-		envHTTPAddr  = envString("HTTP_ADDR", ":8080")
-		envDbDriver  = envString("DB_DRIVER", "inmem")
-		envDbURL     = envString("DB_URL", "")
-		envTableName = envString("DB_TABLE_NAME", "orders")
-
-		httpAddr  = *flag.String("http.addr", envHTTPAddr, "HTTP listen address")
-		dbDriver  = *flag.String("db.driver", envDbDriver, "database driver")
-		dbURL     = *flag.String("db.url", envDbURL, "database connection url")
-		tableName = *flag.String("sql.tabname", envTableName, "Table name")
+		envHTTPAddr   = envString("HTTP_ADDR", ":8080")
+		envDbDriver   = envString("DB_DRIVER", "inmem")
+		envDbURL      = envString("DB_URL", "")
+		envTableName  = envString("DB_TABLE_NAME", "orders")
+		envProducts   = envString("PRODUCTS_FILE", "")
+		envFeedURI    = envString("GDAX_FEED_URI", "")
+		envSnapshots  = envInt("SNAPSHOT_INTERVAL", 100)
+		envInvalidate = envBool("SNAPSHOT_INVALIDATE", false)
+		envAPIKeys    = envString("API_KEYS_FILE", "")
+		envClearing   = envInt("CLEARING_WINDOW", 60)
 	)
+
+	var ( // flags, read after flag.Parse
+		httpAddr   string
+		dbDriver   string
+		dbURL      string
+		tableName  string
+		products   string
+		feedURI    string
+		snapshots  int
+		invalidate bool
+		apiKeys    string
+		clearing   int
+	)
+
+	flag.StringVar(&httpAddr, "http.addr", envHTTPAddr, "HTTP listen address")
+	flag.StringVar(&dbDriver, "db.driver", envDbDriver, "database driver")
+	flag.StringVar(&dbURL, "db.url", envDbURL, "database connection url")
+	flag.StringVar(&tableName, "sql.tabname", envTableName, "Table name")
+	flag.StringVar(&products, "products.file", envProducts, "JSON file of the product catalog")
+	flag.StringVar(&feedURI, "gdax.feed", envFeedURI, "GDAX websocket feed to trigger stop orders, disabled if empty")
+	flag.IntVar(&snapshots, "snapshot.interval", envSnapshots, "take a snapshot of an order every n events, disabled if 0")
+	flag.BoolVar(&invalidate, "snapshot.invalidate", envInvalidate, "delete all snapshots at startup, orders are rebuilt from their events")
+	flag.StringVar(&apiKeys, "auth.keys", envAPIKeys, "JSON file of the API keys and their accounts, all requests are rejected if empty")
+	flag.IntVar(&clearing, "clearing.window", envClearing, "net and clear the confirmed orders every n seconds, disabled if 0")
 	flag.Parse()
 
 	logger := kitlog.NewLogfmtLogger(kitlog.NewSyncWriter(os.Stderr))
 	logger = kitlog.With(logger, "ts", kitlog.DefaultTimestampUTC)
 
-	repo, err := orders.NewRepository(dbDriver, dbURL, tableName)
+	catalog, err := newCatalog(products)
+	if err != nil {
+		log.Fatal("terminated", err)
+	}
+
+	clearingHouse, err := orders.NewClearingHouse(dbDriver, dbURL, tableName, catalog, kitlog.With(logger, "component", "clearing"))
+	if err != nil {
+		log.Fatal("terminated", err)
+	}
+
+	books := orders.NewBookProjection()
+	repo, err := orders.NewRepository(dbDriver, dbURL, tableName, snapshots, books.On, clearingHouse.On)
+	if err != nil {
+		log.Fatal("terminated", err)
+	}
+	if i, ok := repo.(orders.Invalidator); ok && invalidate {
+		if err := i.Invalidate(context.Background()); err != nil {
+			log.Fatal("terminated", err)
+		}
+		logger.Log("msg", "snapshots invalidated")
+	}
+
+	authenticator, err := newAuthenticator(apiKeys)
+	if err != nil {
+		log.Fatal("terminated", err)
+	}
+	if apiKeys == "" {
+		logger.Log("msg", "no API keys configured, all order requests are rejected")
+	}
+
+	wallet, err := orders.NewWallet(dbDriver, dbURL, tableName, catalog)
+	if err != nil {
+		log.Fatal("terminated", err)
+	}
+
+	ledger, err := orders.NewLedger(dbDriver, dbURL, tableName)
+	if err != nil {
+		log.Fatal("terminated", err)
+	}
+
+	confirmations, err := orders.NewConfirmations(dbDriver, dbURL, tableName)
+	if err != nil {
+		log.Fatal("terminated", err)
+	}
+
+	markets, err := orders.NewMarkets(dbDriver, dbURL, tableName)
 	if err != nil {
 		log.Fatal("terminated", err)
 	}
 
 	idg := orders.NewIDGenerator()
 
+	matcher := orders.NewMatcher()
+	scheduler := orders.NewExpiryScheduler(kitlog.With(logger, "component", "scheduler"))
+
+	deps := orders.Dependencies{
+		IDGenerator:   idg,
+		Repository:    repo,
+		Matcher:       matcher,
+		Catalog:       catalog,
+		Scheduler:     scheduler,
+		Volumes:       orders.NewVolumeTracker(),
+		Books:         books,
+		Trades:        orders.NewTradeHistory(),
+		Wallet:        wallet,
+		Ledger:        ledger,
+		Clearing:      clearingHouse,
+		Confirmations: confirmations,
+		Markets:       markets,
+	}
+	if err := orders.Restore(context.Background(), deps); err != nil {
+		log.Fatal("terminated", err)
+	}
+
 	fieldKeys := []string{"method"}
-	o := orders.NewService(idg, repo)
+	o := orders.NewService(deps)
 	o = orders.NewLoggingMiddleware(kitlog.With(logger, "component", "orders"))(o)
 	o = orders.NewInstrumentingMiddleware(
 		kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
@@ -61,12 +161,28 @@ func main() {
 			Help:      "Total duration of requests in microseconds.",
 		}, fieldKeys))(o)
 
+	go scheduler.Run(context.Background(), o, time.Second)
+
+	if clearing > 0 {
+		go clearingHouse.Run(context.Background(), o, time.Duration(clearing)*time.Second)
+	}
+
+	if feedURI != "" {
+		if err := watchFeed(feedURI, catalog, o, kitlog.With(logger, "component", "feed")); err != nil {
+			log.Fatal("terminated", err)
+		}
+	}
+
+	var p productsvc.Service
+	p = productsvc.NewService(catalog)
+	p = productsvc.NewLoggingMiddleware(kitlog.With(logger, "component", "products"))(p)
+
 	httpLogger := kitlog.With(logger, "component", "http")
 
-	mux := http.NewServeMux()
-	mux.Handle("/godax/v1/", orders.MakeHandler(o, httpLogger))
+	ordersHandler := orders.MakeHandler(o, authenticator, httpLogger)
+	productsHandler := productsvc.MakeHandler(p, orders.OperatorsOnly(authenticator), httpLogger)
 
-	http.Handle("/", accessControl(mux))
+	http.Handle("/", accessControl(newMux(ordersHandler, productsHandler)))
 	http.Handle("/metrics", promhttp.Handler())
 	http.Handle("/_status/liveness", livenessHandler())
 	http.Handle("/_status/readiness", readinessHandler())
@@ -100,11 +216,33 @@ func main() {
 	os.Exit(1)
 }
 
+// newMux routes the products to the products handler and everything else to the orders handler
+func newMux(orders, products http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/godax/v1/", orders)
+	mux.Handle("/godax/v1/products", products)
+	mux.Handle("/godax/v1/products/", subresourcesOf(products, orders))
+	return mux
+}
+
+// subresourcesOf passes the sub-resources of a product, e.g. its trades, trading status and auction,
+// to the orders handler and the product itself to the products handler
+func subresourcesOf(products, orders http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(strings.TrimPrefix(r.URL.Path, "/godax/v1/products/"), "/") {
+			orders.ServeHTTP(w, r)
+			return
+		}
+		products.ServeHTTP(w, r)
+	})
+}
+
 func accessControl(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, "+orders.APIKeyHeader+", "+orders.IdempotencyKeyHeader+", "+orders.RequestIDHeader+", "+orders.ReasonHeader+", "+orders.SourceHeader)
+		w.Header().Set("Access-Control-Expose-Headers", orders.RequestIDHeader+", "+orders.BeforeHeader+", "+orders.AfterHeader)
 
 		if r.Method == "OPTIONS" {
 			return
@@ -128,6 +266,88 @@ func livenessHandler() http.Handler {
 	})
 }
 
+// watchFeed triggers stop orders with the last trade prices of the GDAX feed
+func watchFeed(feedURI string, catalog productsvc.Catalog, s orders.Service, logger kitlog.Logger) error {
+	u, err := url.Parse(feedURI)
+	if err != nil {
+		return err
+	}
+
+	c := gdax.NewClient(websocket.DefaultDialer)
+	c.WithLogger(logger)
+	if err := c.Connect(u); err != nil {
+		return err
+	}
+
+	products, err := catalog.List()
+	if err != nil {
+		return err
+	}
+	ids := []gdax.ProductID{}
+	for _, p := range products {
+		ids = append(ids, gdax.ProductID(p.ID))
+	}
+
+	events, err := c.Subscribe(ids)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for e := range events {
+			if e.Type != "match" {
+				continue
+			}
+			price, err := decimal.NewFromString(e.Price)
+			if err != nil {
+				logger.Log("price", e.Price, "err", err)
+				continue
+			}
+			md := orderbook.Metadata{Actor: "gdax-feed", Source: orders.ServiceName}
+			s.TriggerOrders(orderbook.NewContext(context.Background(), md), orderbook.ProductID(e.ProductID), price)
+		}
+	}()
+	return nil
+}
+
+// newCatalog loads the product catalog from a JSON file or uses the default products
+func newCatalog(file string) (productsvc.Catalog, error) {
+	if file == "" {
+		return productsvc.NewCatalog(productsvc.DefaultProducts...)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	p, err := productsvc.Load(f)
+	if err != nil {
+		return nil, err
+	}
+	return productsvc.NewCatalog(p...)
+}
+
+// newAuthenticator loads the API keys from a JSON file, without file no API key is valid
+func newAuthenticator(file string) (orders.Authenticator, error) {
+	if file == "" {
+		return orders.NewAPIKeyAuthenticator(map[string]orders.Principal{}), nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	keys, err := orders.LoadAPIKeys(f)
+	if err != nil {
+		return nil, err
+	}
+	return orders.NewAPIKeyAuthenticator(keys), nil
+}
+
 func envString(env, fallback string) string {
 	e, ok := os.LookupEnv(env)
 	if !ok {
@@ -135,3 +355,27 @@ func envString(env, fallback string) string {
 	}
 	return e
 }
+
+func envBool(env string, fallback bool) bool {
+	e, ok := os.LookupEnv(env)
+	if !ok {
+		return fallback
+	}
+	b, err := strconv.ParseBool(e)
+	if err != nil {
+		return fallback
+	}
+	return b
+}
+
+func envInt(env string, fallback int) int {
+	e, ok := os.LookupEnv(env)
+	if !ok {
+		return fallback
+	}
+	i, err := strconv.Atoi(e)
+	if err != nil {
+		return fallback
+	}
+	return i
+}
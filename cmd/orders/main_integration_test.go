@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http"
+	"os"
 	"testing"
 )
 
@@ -18,7 +19,7 @@ func Test_main_create(t *testing.T) {
 		StatusCode int
 	}{
 		{Name: "should create an order", Method: "POST", URL: "http://localhost:8080/godax/v1/orders",
-			Order:      orderRequest{Size: 1.34, Price: 13.34, OrderType: "limit", OrderSide: "sell", ProductID: "BTC-USD"},
+			Order:      orderRequest{Size: "1.34", Price: "13.34", OrderType: "limit", OrderSide: "sell", ProductID: "BTC-USD"},
 			StatusCode: http.StatusOK},
 		{Name: "should return Bad Request (400) for invalid body", Method: "POST", URL: "http://localhost:8080/godax/v1/orders",
 			Order:      orderRequest{},
@@ -36,6 +37,7 @@ func Test_main_create(t *testing.T) {
 			req, err := http.NewRequest(tt.Method, tt.URL, bytes.NewBuffer(b))
 			req.Header.Set("Content-Type", "application/json")
 			req.Header.Set("Accept", "application/pdf")
+			req.Header.Set("X-Api-Key", os.Getenv("API_KEY"))
 
 			client := &http.Client{}
 			resp, err := client.Do(req)
@@ -51,9 +53,9 @@ func Test_main_create(t *testing.T) {
 }
 
 type orderRequest struct {
-	Size      float32 `json:"size,omitempty"`
-	Price     float32 `json:"price,omitempty"`
-	OrderType string  `json:"type,omitempty"`
-	OrderSide string  `json:"side,omitempty"`
-	ProductID string  `json:"product_id,omitempty"`
+	Size      string `json:"size,omitempty"`
+	Price     string `json:"price,omitempty"`
+	OrderType string `json:"type,omitempty"`
+	OrderSide string `json:"side,omitempty"`
+	ProductID string `json:"product_id,omitempty"`
 }
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_newMux(t *testing.T) {
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name))
+		})
+	}
+	mux := newMux(handler("orders"), handler("products"))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   string
+	}{
+		{"should route the orders to the orders handler", "POST", "/godax/v1/orders", "orders"},
+		{"should route the product list to the products handler", "GET", "/godax/v1/products", "products"},
+		{"should route a product to the products handler", "PUT", "/godax/v1/products/BTC-USD", "products"},
+		{"should route the trades of a product to the orders handler", "GET", "/godax/v1/products/BTC-USD/trades", "orders"},
+		{"should route the trading status of a product to the orders handler", "GET", "/godax/v1/products/BTC-USD/status", "orders"},
+		{"should route a change of the trading status to the orders handler", "PUT", "/godax/v1/products/BTC-USD/status", "orders"},
+		{"should route the auction of a product to the orders handler", "GET", "/godax/v1/products/BTC-USD/auction", "orders"},
+		{"should route the book of a product to the orders handler", "GET", "/godax/v1/books/BTC-USD", "orders"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Body.String() != tt.want {
+				t.Errorf("newMux() routed %v %v to %v, want %v", tt.method, tt.path, w.Body.String(), tt.want)
+			}
+		})
+	}
+}
@@ -1,24 +1,29 @@
 module github.com/LAtanassov/godax
 
+go 1.27.1
+
 require (
-	github.com/afex/hystrix-go v0.0.0-20180502004556-fa1af6a1f4f5 // indirect
 	github.com/altairsix/eventsource v0.0.0-20170815104732-7b6859b7a009
-	github.com/beorn7/perks v0.0.0-20180321164747-3a771d992973 // indirect
 	github.com/go-kit/kit v0.7.0
-	github.com/go-logfmt/logfmt v0.3.0 // indirect
 	github.com/go-sql-driver/mysql v1.4.0
-	github.com/go-stack/stack v1.8.0 // indirect
-	github.com/golang/protobuf v1.2.0 // indirect
 	github.com/gorilla/mux v1.6.2
 	github.com/gorilla/websocket v1.4.0
+	github.com/prometheus/client_golang v0.8.0
+	github.com/streadway/amqp v0.0.0-20180806233856-70e15c650864
+	golang.org/x/time v0.0.0-20180412165947-fbb02b2291d2
+)
+
+require (
+	github.com/afex/hystrix-go v0.0.0-20180502004556-fa1af6a1f4f5 // indirect
+	github.com/beorn7/perks v0.0.0-20180321164747-3a771d992973 // indirect
+	github.com/go-logfmt/logfmt v0.3.0 // indirect
+	github.com/go-stack/stack v1.8.0 // indirect
+	github.com/golang/protobuf v1.2.0 // indirect
 	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
 	github.com/pkg/errors v0.8.0 // indirect
-	github.com/prometheus/client_golang v0.8.0
 	github.com/prometheus/client_model v0.0.0-20180712105110-5c3871d89910 // indirect
 	github.com/prometheus/common v0.0.0-20180801064454-c7de2306084e // indirect
 	github.com/prometheus/procfs v0.0.0-20180920065004-418d78d0b9a7 // indirect
 	github.com/sony/gobreaker v0.0.0-20180905101324-b2a34562d02c // indirect
-	github.com/streadway/amqp v0.0.0-20180806233856-70e15c650864
 	github.com/streadway/handy v0.0.0-20160402200321-f450267a206e // indirect
-	golang.org/x/time v0.0.0-20180412165947-fbb02b2291d2
 )
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 
 	"github.com/altairsix/eventsource/mysqlstore"
 
@@ -14,7 +15,57 @@ import (
 // ErrTypeCast if returned when the expected type does not match
 var ErrTypeCast = errors.New("type cast failed")
 
-// New return a MySQL accessor and creates a table if not exists
+const createSnapshotTableSQL = `CREATE TABLE IF NOT EXISTS %s (
+  aggregate_id   VARCHAR(255) NOT NULL,
+  version        INT NOT NULL,
+  schema_version INT NOT NULL,
+  data           MEDIUMBLOB NOT NULL,
+  PRIMARY KEY (aggregate_id)
+)`
+
+const createDedupeTableSQL = `CREATE TABLE IF NOT EXISTS %s (
+  idempotency_key VARCHAR(255) NOT NULL,
+  aggregate_id    VARCHAR(255) NOT NULL,
+  PRIMARY KEY (idempotency_key)
+)`
+
+// SnapshotTableName returns the name of the table which keeps the latest snapshot of each aggregate
+func SnapshotTableName(tableName string) string {
+	return tableName + "_snapshots"
+}
+
+// DedupeTableName returns the name of the table which maps idempotency keys to aggregates
+func DedupeTableName(tableName string) string {
+	return tableName + "_dedupe"
+}
+
+// AccountsTableName returns the name of the event table of the account aggregates
+func AccountsTableName(tableName string) string {
+	return tableName + "_accounts"
+}
+
+// LedgerTableName returns the name of the event table of the journal entries
+func LedgerTableName(tableName string) string {
+	return tableName + "_ledger"
+}
+
+// ClearingTableName returns the name of the event table of the clearing cycles
+func ClearingTableName(tableName string) string {
+	return tableName + "_clearing"
+}
+
+// ConfirmationsTableName returns the name of the event table of the trade confirmations
+func ConfirmationsTableName(tableName string) string {
+	return tableName + "_confirmations"
+}
+
+// MarketsTableName returns the name of the event table of the trading status of the products
+func MarketsTableName(tableName string) string {
+	return tableName + "_markets"
+}
+
+// New return a MySQL accessor and creates the event, the snapshot, the dedupe, the accounts, the ledger,
+// the clearing, the confirmations and the markets table if not exists
 func New(driver, dsn, tableName string) (mysqlstore.Accessor, error) {
 	db, err := sql.Open(driver, dsn)
 	if err != nil {
@@ -31,6 +82,34 @@ func New(driver, dsn, tableName string) (mysqlstore.Accessor, error) {
 		return nil, err
 	}
 
+	if _, err := db.Exec(fmt.Sprintf(createSnapshotTableSQL, SnapshotTableName(tableName))); err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(fmt.Sprintf(createDedupeTableSQL, DedupeTableName(tableName))); err != nil {
+		return nil, err
+	}
+
+	if err := mysqlstore.CreateIfNotExists(db, AccountsTableName(tableName)); err != nil {
+		return nil, err
+	}
+
+	if err := mysqlstore.CreateIfNotExists(db, LedgerTableName(tableName)); err != nil {
+		return nil, err
+	}
+
+	if err := mysqlstore.CreateIfNotExists(db, ClearingTableName(tableName)); err != nil {
+		return nil, err
+	}
+
+	if err := mysqlstore.CreateIfNotExists(db, ConfirmationsTableName(tableName)); err != nil {
+		return nil, err
+	}
+
+	if err := mysqlstore.CreateIfNotExists(db, MarketsTableName(tableName)); err != nil {
+		return nil, err
+	}
+
 	return &accessor{
 		driver: driver,
 		dsn:    dsn,
@@ -0,0 +1,8 @@
+// Package clearing represents the multilateral netting of confirmed orders.
+// The exchange is the central counterparty of every trade, so the obligations of the orders confirmed
+// within a window are netted per account and currency into one instruction each: the account either
+// pays or receives the net amount. The clearing account takes the opposite side of the instructions
+// whose counterparty is cleared in a later cycle.
+//
+// netting - https://www.bis.org/cpmi/publ/d04.htm
+package clearing
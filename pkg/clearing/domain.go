@@ -0,0 +1,187 @@
+package clearing
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/ledger"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/altairsix/eventsource"
+)
+
+var (
+	// ErrEmptyCycle is returned when a cycle is cleared without orders
+	ErrEmptyCycle = errors.New("empty clearing cycle")
+	// ErrUnknownCommand is returned when a command is not supported by the cycle
+	ErrUnknownCommand = errors.New("unknown command")
+	// ErrUnknownEvent is returned when an event is not supported by the cycle
+	ErrUnknownEvent = errors.New("unknown event")
+)
+
+// Instruction is the net amount an account receives (positive) or pays (negative) in a currency
+type Instruction struct {
+	Account  string
+	Currency string
+	Amount   decimal.Decimal
+}
+
+// Obligations returns what the account of an order receives and pays for its fills:
+// a seller delivers the size and receives the value less fees, a buyer pays the value plus fees
+// and receives the size. The fees are received by the fee account.
+func Obligations(account string, side orderbook.OrderSide, base, quote string, size, value, fees decimal.Decimal) []Instruction {
+	if side == orderbook.Sell {
+		return []Instruction{
+			{Account: account, Currency: base, Amount: size.Neg()},
+			{Account: account, Currency: quote, Amount: value.Sub(fees)},
+			{Account: ledger.FeeAccount, Currency: quote, Amount: fees},
+		}
+	}
+	return []Instruction{
+		{Account: account, Currency: quote, Amount: value.Add(fees).Neg()},
+		{Account: account, Currency: base, Amount: size},
+		{Account: ledger.FeeAccount, Currency: quote, Amount: fees},
+	}
+}
+
+// Net sums the obligations per account and currency, balances each currency against the clearing account
+// and returns the non zero instructions sorted by account and currency
+func Net(obligations []Instruction) []Instruction {
+	type key struct{ account, currency string }
+
+	sums := map[key]decimal.Decimal{}
+	for _, o := range obligations {
+		k := key{o.Account, o.Currency}
+		sums[k] = sums[k].Add(o.Amount)
+		clearing := key{ledger.ClearingAccount, o.Currency}
+		sums[clearing] = sums[clearing].Sub(o.Amount)
+	}
+
+	instructions := []Instruction{}
+	for k, amount := range sums {
+		if !amount.IsZero() {
+			instructions = append(instructions, Instruction{Account: k.account, Currency: k.currency, Amount: amount})
+		}
+	}
+	sort.Slice(instructions, func(i, j int) bool {
+		if instructions[i].Account != instructions[j].Account {
+			return instructions[i].Account < instructions[j].Account
+		}
+		return instructions[i].Currency < instructions[j].Currency
+	})
+	return instructions
+}
+
+// Events --------------
+
+// CycleCleared Event - the orders confirmed within a window were netted into instructions
+type CycleCleared struct {
+	OrderIDs     []string
+	Obligations  int // number of gross obligations before netting
+	Instructions []Instruction
+	WindowStart  time.Time
+	WindowEnd    time.Time
+	Metadata     orderbook.Metadata
+	eventsource.Model
+}
+
+// Commands --------------
+
+// ClearCycle Command - nets the obligations of the orders, a cycle is cleared once
+type ClearCycle struct {
+	OrderIDs    []string
+	Obligations []Instruction
+	WindowStart time.Time
+	WindowEnd   time.Time
+
+	Metadata orderbook.Metadata
+	eventsource.CommandModel
+}
+
+// Aggregates --------------
+
+// Cycle is a clearing cycle and an Aggregate which apply Events
+type Cycle struct {
+	OrderIDs     []string
+	Obligations  int
+	Instructions []Instruction
+	WindowStart  time.Time
+	WindowEnd    time.Time
+
+	id        string
+	version   int
+	clearedAt time.Time
+}
+
+// ID returns the aggregate id of the cycle
+func (c Cycle) ID() string {
+	return c.id
+}
+
+// Version returns the version of the last event applied to the cycle
+func (c Cycle) Version() int {
+	return c.version
+}
+
+// ClearedAt returns the time the cycle was cleared
+func (c Cycle) ClearedAt() time.Time {
+	return c.clearedAt
+}
+
+// Owes returns the instructions of an account
+func (c Cycle) Owes(account string) []Instruction {
+	instructions := []Instruction{}
+	for _, i := range c.Instructions {
+		if i.Account == account {
+			instructions = append(instructions, i)
+		}
+	}
+	return instructions
+}
+
+// On applies events
+func (c *Cycle) On(event eventsource.Event) error {
+	switch v := event.(type) {
+	case *CycleCleared:
+		c.OrderIDs = v.OrderIDs
+		c.Obligations = v.Obligations
+		c.Instructions = v.Instructions
+		c.WindowStart = v.WindowStart
+		c.WindowEnd = v.WindowEnd
+		c.clearedAt = v.At
+	default:
+		return ErrUnknownEvent
+	}
+
+	c.id = event.AggregateID()
+	c.version = event.EventVersion()
+	return nil
+}
+
+// Apply generates events from a command
+func (c *Cycle) Apply(ctx context.Context, command eventsource.Command) ([]eventsource.Event, error) {
+	switch v := command.(type) {
+	case *ClearCycle:
+		// a cycle is cleared once, clearing it again is ignored
+		if c.version > 0 {
+			return []eventsource.Event{}, nil
+		}
+		if len(v.OrderIDs) == 0 {
+			return nil, ErrEmptyCycle
+		}
+		cycleCleared := &CycleCleared{
+			OrderIDs:     v.OrderIDs,
+			Obligations:  len(v.Obligations),
+			Instructions: Net(v.Obligations),
+			WindowStart:  v.WindowStart,
+			WindowEnd:    v.WindowEnd,
+			Metadata:     v.Metadata,
+			Model:        eventsource.Model{ID: command.AggregateID(), Version: c.version + 1, At: time.Now()},
+		}
+		return []eventsource.Event{cycleCleared}, nil
+	default:
+		return nil, ErrUnknownCommand
+	}
+}
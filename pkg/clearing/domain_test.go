@@ -0,0 +1,89 @@
+package clearing
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/ledger"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/altairsix/eventsource"
+)
+
+func TestNet(t *testing.T) {
+	n := decimal.NewFromInt
+	// alice sells 2 BTC to bob and buys 1 BTC back from carol, bob's counterparty of a second trade is not cleared yet
+	obligations := [][]Instruction{
+		Obligations("alice", orderbook.Sell, "BTC", "USD", n(2), n(200), n(0)),
+		Obligations("bob", orderbook.Buy, "BTC", "USD", n(2), n(200), n(0)),
+		Obligations("alice", orderbook.Buy, "BTC", "USD", n(1), n(100), n(1)),
+		Obligations("carol", orderbook.Sell, "BTC", "USD", n(1), n(100), n(1)),
+		Obligations("bob", orderbook.Buy, "BTC", "USD", n(1), n(100), n(0)),
+	}
+	all := []Instruction{}
+	for _, o := range obligations {
+		all = append(all, o...)
+	}
+
+	want := []Instruction{
+		{Account: "alice", Currency: "BTC", Amount: n(-1)},
+		{Account: "alice", Currency: "USD", Amount: n(99)},
+		{Account: "bob", Currency: "BTC", Amount: n(3)},
+		{Account: "bob", Currency: "USD", Amount: n(-300)},
+		{Account: "carol", Currency: "BTC", Amount: n(-1)},
+		{Account: "carol", Currency: "USD", Amount: n(99)},
+		{Account: ledger.ClearingAccount, Currency: "BTC", Amount: n(-1)},
+		{Account: ledger.ClearingAccount, Currency: "USD", Amount: n(100)},
+		{Account: ledger.FeeAccount, Currency: "USD", Amount: n(2)},
+	}
+	got := Net(all)
+	if len(got) != len(want) {
+		t.Fatalf("Net() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i].Account != want[i].Account || got[i].Currency != want[i].Currency || !got[i].Amount.Equal(want[i].Amount) {
+			t.Errorf("Net()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCycle_Apply(t *testing.T) {
+	obligations := Obligations("alice", orderbook.Sell, "BTC", "USD", decimal.NewFromInt(1), decimal.NewFromInt(100), decimal.Zero)
+	cleared := &CycleCleared{OrderIDs: []string{"o1"}, Model: eventsource.Model{ID: "c1", Version: 1}}
+
+	tests := []struct {
+		name       string
+		events     []eventsource.Event
+		command    eventsource.Command
+		wantErr    error
+		wantEvents int
+	}{
+		{"should clear a cycle", nil, &ClearCycle{OrderIDs: []string{"o1"}, Obligations: obligations}, nil, 1},
+		{"should clear a cycle only once", []eventsource.Event{cleared}, &ClearCycle{OrderIDs: []string{"o1"}, Obligations: obligations}, nil, 0},
+		{"should reject a cycle without orders", nil, &ClearCycle{}, ErrEmptyCycle, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Cycle{}
+			for _, e := range tt.events {
+				if err := c.On(e); err != nil {
+					t.Fatalf("Cycle.On() error = %v", err)
+				}
+			}
+			events, err := c.Apply(context.Background(), tt.command)
+			if err != tt.wantErr {
+				t.Fatalf("Cycle.Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(events) != tt.wantEvents {
+				t.Errorf("Cycle.Apply() = %v, want %v events", events, tt.wantEvents)
+			}
+			for _, e := range events {
+				c.On(e)
+			}
+			if tt.wantEvents > 0 && !reflect.DeepEqual(c.OrderIDs, []string{"o1"}) {
+				t.Errorf("Cycle.OrderIDs = %v, want [o1]", c.OrderIDs)
+			}
+		})
+	}
+}
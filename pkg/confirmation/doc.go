@@ -0,0 +1,5 @@
+// Package confirmation represents the two-party confirmation of a trade.
+// The buyer and the seller confirm or dispute a trade independently, the trade is confirmed once both
+// confirmed it. A dispute opens a case which stays open until the risk team resolves it, the resolution
+// confirms the trade for both parties.
+package confirmation
@@ -0,0 +1,272 @@
+package confirmation
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/altairsix/eventsource"
+)
+
+var (
+	// ErrDisputed is returned when a disputed trade is confirmed before the dispute was resolved
+	ErrDisputed = errors.New("trade is disputed")
+	// ErrNotDisputed is returned when a dispute is resolved for a trade without dispute
+	ErrNotDisputed = errors.New("trade is not disputed")
+	// ErrConfirmed is returned when a trade is disputed after both parties confirmed it
+	ErrConfirmed = errors.New("trade is already confirmed")
+	// ErrUnknownCommand is returned when a command is not supported by the confirmation
+	ErrUnknownCommand = errors.New("unknown command")
+	// ErrUnknownEvent is returned when an event is not supported by the confirmation
+	ErrUnknownEvent = errors.New("unknown event")
+)
+
+// Status of the confirmation of a trade
+type Status string
+
+const (
+	// Pending trades wait for the confirmation of at least one party
+	Pending Status = "pending"
+	// Disputed trades wait for the resolution of the risk team
+	Disputed Status = "disputed"
+	// Confirmed trades were confirmed by both parties or by the resolution of their dispute
+	Confirmed Status = "confirmed"
+)
+
+// Events --------------
+
+// ConfirmationOpened Event - a trade was executed and waits for the confirmation of its parties
+type ConfirmationOpened struct {
+	ProductID       orderbook.ProductID
+	BuyOrderID      string
+	SellOrderID     string
+	BuyerAccountID  string
+	SellerAccountID string
+	Metadata        orderbook.Metadata
+	eventsource.Model
+}
+
+// PartyConfirmed Event - the buyer or the seller confirmed the trade
+type PartyConfirmed struct {
+	Party    orderbook.OrderSide
+	Metadata orderbook.Metadata
+	eventsource.Model
+}
+
+// TradeDisputed Event - the buyer or the seller disputed the trade
+type TradeDisputed struct {
+	Party    orderbook.OrderSide
+	Message  string
+	Metadata orderbook.Metadata
+	eventsource.Model
+}
+
+// DisputeResolved Event - the risk team resolved the dispute, the trade stands
+type DisputeResolved struct {
+	Message  string
+	Metadata orderbook.Metadata
+	eventsource.Model
+}
+
+// TradeConfirmed Event - the trade is confirmed for both parties
+type TradeConfirmed struct {
+	Metadata orderbook.Metadata
+	eventsource.Model
+}
+
+// Commands --------------
+
+// OpenConfirmation Command - a confirmation is opened once per trade
+type OpenConfirmation struct {
+	ProductID       orderbook.ProductID
+	BuyOrderID      string
+	SellOrderID     string
+	BuyerAccountID  string
+	SellerAccountID string
+
+	Metadata orderbook.Metadata
+	eventsource.CommandModel
+}
+
+// ConfirmTrade Command - the party confirms the trade, confirming it again is ignored
+type ConfirmTrade struct {
+	Party orderbook.OrderSide
+
+	Metadata orderbook.Metadata
+	eventsource.CommandModel
+}
+
+// DisputeTrade Command - the party disputes the trade, a disputed trade stays disputed until it is resolved
+type DisputeTrade struct {
+	Party   orderbook.OrderSide
+	Message string
+
+	Metadata orderbook.Metadata
+	eventsource.CommandModel
+}
+
+// ResolveDispute Command - the risk team resolves the dispute of a trade
+type ResolveDispute struct {
+	Message string
+
+	Metadata orderbook.Metadata
+	eventsource.CommandModel
+}
+
+// Aggregates --------------
+
+// Confirmation of a trade is an Aggregate which apply Events, its id is the trade id
+type Confirmation struct {
+	ProductID       orderbook.ProductID
+	BuyOrderID      string
+	SellOrderID     string
+	BuyerAccountID  string
+	SellerAccountID string
+
+	BuyerConfirmed  bool
+	SellerConfirmed bool
+	DisputedBy      orderbook.OrderSide
+	Dispute         string
+	Resolution      string
+
+	id        string
+	version   int
+	updatedAt time.Time
+	status    Status
+}
+
+// ID returns the aggregate id of the confirmation, the trade id
+func (c Confirmation) ID() string {
+	return c.id
+}
+
+// Version returns the version of the last event applied to the confirmation
+func (c Confirmation) Version() int {
+	return c.version
+}
+
+// Status returns the status of the confirmation
+func (c Confirmation) Status() Status {
+	return c.status
+}
+
+// PartyOf returns the side an account is on, false if the account is not a party of the trade
+func (c Confirmation) PartyOf(accountID string) (orderbook.OrderSide, bool) {
+	switch accountID {
+	case c.BuyerAccountID:
+		return orderbook.Buy, true
+	case c.SellerAccountID:
+		return orderbook.Sell, true
+	}
+	return 0, false
+}
+
+// OrderIDs returns the buy and the sell order of the trade
+func (c Confirmation) OrderIDs() []string {
+	return []string{c.BuyOrderID, c.SellOrderID}
+}
+
+// confirmed returns true if the party confirmed the trade
+func (c Confirmation) confirmed(party orderbook.OrderSide) bool {
+	if party == orderbook.Buy {
+		return c.BuyerConfirmed
+	}
+	return c.SellerConfirmed
+}
+
+// On applies events
+func (c *Confirmation) On(event eventsource.Event) error {
+	switch v := event.(type) {
+	case *ConfirmationOpened:
+		c.ProductID = v.ProductID
+		c.BuyOrderID = v.BuyOrderID
+		c.SellOrderID = v.SellOrderID
+		c.BuyerAccountID = v.BuyerAccountID
+		c.SellerAccountID = v.SellerAccountID
+		c.status = Pending
+	case *PartyConfirmed:
+		if v.Party == orderbook.Buy {
+			c.BuyerConfirmed = true
+		} else {
+			c.SellerConfirmed = true
+		}
+	case *TradeDisputed:
+		c.DisputedBy = v.Party
+		c.Dispute = v.Message
+		c.status = Disputed
+	case *DisputeResolved:
+		c.Resolution = v.Message
+	case *TradeConfirmed:
+		c.status = Confirmed
+	default:
+		return ErrUnknownEvent
+	}
+
+	c.id = event.AggregateID()
+	c.version = event.EventVersion()
+	c.updatedAt = event.EventAt()
+	return nil
+}
+
+// Apply generates events from a command
+func (c *Confirmation) Apply(ctx context.Context, command eventsource.Command) ([]eventsource.Event, error) {
+	version := c.version
+	model := func() eventsource.Model {
+		version++
+		return eventsource.Model{ID: command.AggregateID(), Version: version, At: time.Now()}
+	}
+
+	switch v := command.(type) {
+	case *OpenConfirmation:
+		if c.version > 0 {
+			return []eventsource.Event{}, nil
+		}
+		confirmationOpened := &ConfirmationOpened{
+			ProductID:       v.ProductID,
+			BuyOrderID:      v.BuyOrderID,
+			SellOrderID:     v.SellOrderID,
+			BuyerAccountID:  v.BuyerAccountID,
+			SellerAccountID: v.SellerAccountID,
+			Metadata:        v.Metadata,
+			Model:           model(),
+		}
+		return []eventsource.Event{confirmationOpened}, nil
+	case *ConfirmTrade:
+		if c.status == Disputed {
+			return nil, ErrDisputed
+		}
+		if c.confirmed(v.Party) || c.status == Confirmed {
+			return []eventsource.Event{}, nil
+		}
+		events := []eventsource.Event{&PartyConfirmed{Party: v.Party, Metadata: v.Metadata, Model: model()}}
+		if c.BuyerConfirmed || c.SellerConfirmed {
+			events = append(events, &TradeConfirmed{Metadata: v.Metadata, Model: model()})
+		}
+		return events, nil
+	case *DisputeTrade:
+		if c.status == Confirmed {
+			return nil, ErrConfirmed
+		}
+		if c.status == Disputed {
+			return []eventsource.Event{}, nil
+		}
+		tradeDisputed := &TradeDisputed{
+			Party:    v.Party,
+			Message:  v.Message,
+			Metadata: v.Metadata,
+			Model:    model(),
+		}
+		return []eventsource.Event{tradeDisputed}, nil
+	case *ResolveDispute:
+		if c.status != Disputed {
+			return nil, ErrNotDisputed
+		}
+		return []eventsource.Event{
+			&DisputeResolved{Message: v.Message, Metadata: v.Metadata, Model: model()},
+			&TradeConfirmed{Metadata: v.Metadata, Model: model()},
+		}, nil
+	default:
+		return nil, ErrUnknownCommand
+	}
+}
@@ -0,0 +1,77 @@
+package confirmation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/altairsix/eventsource"
+)
+
+func TestConfirmation_Apply(t *testing.T) {
+	model := func(version int) eventsource.Model { return eventsource.Model{ID: "t1", Version: version} }
+	opened := &ConfirmationOpened{BuyOrderID: "b1", SellOrderID: "s1", BuyerAccountID: "bob", SellerAccountID: "alice", Model: model(1)}
+	buyerConfirmed := &PartyConfirmed{Party: orderbook.Buy, Model: model(2)}
+	disputed := &TradeDisputed{Party: orderbook.Sell, Message: "wrong price", Model: model(2)}
+
+	tests := []struct {
+		name       string
+		events     []eventsource.Event
+		command    eventsource.Command
+		wantErr    error
+		wantStatus Status
+	}{
+		{"should wait for the other party", []eventsource.Event{opened},
+			&ConfirmTrade{Party: orderbook.Sell}, nil, Pending},
+		{"should confirm the trade once both parties confirmed", []eventsource.Event{opened, buyerConfirmed},
+			&ConfirmTrade{Party: orderbook.Sell}, nil, Confirmed},
+		{"should ignore a repeated confirmation", []eventsource.Event{opened, buyerConfirmed},
+			&ConfirmTrade{Party: orderbook.Buy}, nil, Pending},
+		{"should open a dispute", []eventsource.Event{opened, buyerConfirmed},
+			&DisputeTrade{Party: orderbook.Sell, Message: "wrong price"}, nil, Disputed},
+		{"should not confirm a disputed trade", []eventsource.Event{opened, disputed},
+			&ConfirmTrade{Party: orderbook.Buy}, ErrDisputed, Disputed},
+		{"should confirm the trade when the dispute is resolved", []eventsource.Event{opened, disputed},
+			&ResolveDispute{Message: "price was correct"}, nil, Confirmed},
+		{"should not resolve a trade without dispute", []eventsource.Event{opened},
+			&ResolveDispute{}, ErrNotDisputed, Pending},
+		{"should not dispute a confirmed trade", []eventsource.Event{opened, buyerConfirmed,
+			&PartyConfirmed{Party: orderbook.Sell, Model: model(3)}, &TradeConfirmed{Model: model(4)}},
+			&DisputeTrade{Party: orderbook.Buy}, ErrConfirmed, Confirmed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Confirmation{}
+			for _, e := range tt.events {
+				if err := c.On(e); err != nil {
+					t.Fatalf("Confirmation.On() error = %v", err)
+				}
+			}
+			events, err := c.Apply(context.Background(), tt.command)
+			if err != tt.wantErr {
+				t.Fatalf("Confirmation.Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, e := range events {
+				if err := c.On(e); err != nil {
+					t.Fatalf("Confirmation.On() error = %v", err)
+				}
+			}
+			if c.Status() != tt.wantStatus {
+				t.Errorf("Confirmation.Status() = %v, want %v", c.Status(), tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestConfirmation_PartyOf(t *testing.T) {
+	c := Confirmation{BuyerAccountID: "bob", SellerAccountID: "alice"}
+	if party, ok := c.PartyOf("bob"); !ok || party != orderbook.Buy {
+		t.Errorf("Confirmation.PartyOf(bob) = %v, %v, want buy", party, ok)
+	}
+	if party, ok := c.PartyOf("alice"); !ok || party != orderbook.Sell {
+		t.Errorf("Confirmation.PartyOf(alice) = %v, %v, want sell", party, ok)
+	}
+	if _, ok := c.PartyOf("carol"); ok {
+		t.Errorf("Confirmation.PartyOf(carol) = true, want false")
+	}
+}
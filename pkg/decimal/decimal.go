@@ -0,0 +1,355 @@
+package decimal
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Places is the number of fractional digits a Decimal can hold (1 satoshi = 0.00000001 BTC)
+const Places = 8
+
+const scale int64 = 100000000
+
+var (
+	// ErrInvalidFormat is returned when a string is not a decimal number
+	ErrInvalidFormat = errors.New("invalid decimal format")
+	// ErrPrecision is returned when a number has more fractional digits than Places
+	ErrPrecision = errors.New("decimal exceeds precision")
+	// ErrOutOfRange is returned when a number or the result of an operation does not fit into a Decimal
+	ErrOutOfRange = errors.New("decimal out of range")
+	// ErrDivisionByZero is returned when a Decimal is divided by zero
+	ErrDivisionByZero = errors.New("decimal division by zero")
+)
+
+// maxDigits is the number of digits of the largest Decimal in units
+const maxDigits = 19
+
+// Zero represents the decimal 0
+var Zero = Decimal{}
+
+// Decimal is a fixed-point number with Places fractional digits.
+// Arithmetic is exact; only Mul and Div truncate results which do not fit into Places.
+// Results out of range saturate, the checked variants return ErrOutOfRange instead and are meant for untrusted input.
+type Decimal struct {
+	units int64
+}
+
+// NewFromInt returns the Decimal of an integer
+func NewFromInt(i int64) Decimal {
+	return Decimal{units: i * scale}
+}
+
+// NewFromString parses a decimal number like "0.00000001", "-12.5" or "1e-8".
+// Numbers with more than Places fractional digits are rejected rather than rounded.
+func NewFromString(s string) (Decimal, error) {
+	mantissa, exp := s, 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return Zero, ErrInvalidFormat
+		}
+		mantissa, exp = s[:i], e
+	}
+
+	negative := false
+	switch {
+	case strings.HasPrefix(mantissa, "-"):
+		negative, mantissa = true, mantissa[1:]
+	case strings.HasPrefix(mantissa, "+"):
+		mantissa = mantissa[1:]
+	}
+
+	integer, fraction := mantissa, ""
+	if i := strings.Index(mantissa, "."); i >= 0 {
+		integer, fraction = mantissa[:i], mantissa[i+1:]
+	}
+	if integer+fraction == "" || !isDigits(integer) || !isDigits(fraction) {
+		return Zero, ErrInvalidFormat
+	}
+
+	// shift the decimal point by the exponent and scale to Places,
+	// the exponent is bounded first so that neither places overflows nor the padding grows unbounded
+	digits := strings.TrimLeft(integer+fraction, "0")
+	if digits == "" {
+		return Zero, nil
+	}
+	if exp > len(fraction)+maxDigits {
+		return Zero, ErrOutOfRange
+	}
+	if exp < -(len(integer) + maxDigits) {
+		return Zero, ErrPrecision
+	}
+	places := len(fraction) - exp
+	for places > Places && strings.HasSuffix(digits, "0") {
+		digits, places = digits[:len(digits)-1], places-1
+	}
+	if places > Places {
+		return Zero, ErrPrecision
+	}
+	if len(digits)+Places-places > maxDigits {
+		return Zero, ErrOutOfRange
+	}
+	digits += strings.Repeat("0", Places-places)
+
+	units, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Zero, ErrOutOfRange
+	}
+	if negative {
+		units = -units
+	}
+	return Decimal{units: units}, nil
+}
+
+// RequireFromString parses a decimal number and panics on failure, useful for constants
+func RequireFromString(s string) Decimal {
+	d, err := NewFromString(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Add returns d + o
+func (d Decimal) Add(o Decimal) Decimal {
+	r, err := d.CheckedAdd(o)
+	if err != nil {
+		return saturate(o.Sign())
+	}
+	return r
+}
+
+// CheckedAdd returns d + o or ErrOutOfRange if the sum does not fit into a Decimal
+func (d Decimal) CheckedAdd(o Decimal) (Decimal, error) {
+	r := d.units + o.units
+	if (r > d.units) != (o.units > 0) {
+		return Zero, ErrOutOfRange
+	}
+	return Decimal{units: r}, nil
+}
+
+// Sub returns d - o
+func (d Decimal) Sub(o Decimal) Decimal {
+	r, err := d.CheckedSub(o)
+	if err != nil {
+		return saturate(-o.Sign())
+	}
+	return r
+}
+
+// CheckedSub returns d - o or ErrOutOfRange if the difference does not fit into a Decimal
+func (d Decimal) CheckedSub(o Decimal) (Decimal, error) {
+	r := d.units - o.units
+	if (r < d.units) != (o.units > 0) {
+		return Zero, ErrOutOfRange
+	}
+	return Decimal{units: r}, nil
+}
+
+// Neg returns -d
+func (d Decimal) Neg() Decimal {
+	return Decimal{units: -d.units}
+}
+
+// Abs returns |d|
+func (d Decimal) Abs() Decimal {
+	if d.units < 0 {
+		return d.Neg()
+	}
+	return d
+}
+
+// Mul returns d * o truncated to Places
+func (d Decimal) Mul(o Decimal) Decimal {
+	r := d.mul(o)
+	if !r.IsInt64() {
+		return saturate(r.Sign())
+	}
+	return Decimal{units: r.Int64()}
+}
+
+// CheckedMul returns d * o truncated to Places or ErrOutOfRange if the product does not fit into a Decimal
+func (d Decimal) CheckedMul(o Decimal) (Decimal, error) {
+	r := d.mul(o)
+	if !r.IsInt64() {
+		return Zero, ErrOutOfRange
+	}
+	return Decimal{units: r.Int64()}, nil
+}
+
+func (d Decimal) mul(o Decimal) *big.Int {
+	r := new(big.Int).Mul(big.NewInt(d.units), big.NewInt(o.units))
+	return r.Quo(r, big.NewInt(scale))
+}
+
+// Div returns d / o truncated to Places, it panics if o is zero
+func (d Decimal) Div(o Decimal) Decimal {
+	r := d.div(o)
+	if !r.IsInt64() {
+		return saturate(r.Sign())
+	}
+	return Decimal{units: r.Int64()}
+}
+
+// CheckedDiv returns d / o truncated to Places, ErrDivisionByZero if o is zero
+// or ErrOutOfRange if the quotient does not fit into a Decimal
+func (d Decimal) CheckedDiv(o Decimal) (Decimal, error) {
+	if o.IsZero() {
+		return Zero, ErrDivisionByZero
+	}
+	r := d.div(o)
+	if !r.IsInt64() {
+		return Zero, ErrOutOfRange
+	}
+	return Decimal{units: r.Int64()}, nil
+}
+
+func (d Decimal) div(o Decimal) *big.Int {
+	r := new(big.Int).Mul(big.NewInt(d.units), big.NewInt(scale))
+	return r.Quo(r, big.NewInt(o.units))
+}
+
+// saturate returns the largest Decimal for a positive and the smallest for a negative sign
+func saturate(sign int) Decimal {
+	if sign < 0 {
+		return Decimal{units: math.MinInt64}
+	}
+	return Decimal{units: math.MaxInt64}
+}
+
+// Truncate drops all fractional digits after places
+func (d Decimal) Truncate(places int32) Decimal {
+	if places >= Places || places < 0 {
+		return d
+	}
+	f := pow10(Places - places)
+	return Decimal{units: d.units / f * f}
+}
+
+// IsMultipleOf returns true if d is an integer multiple of the increment o, e.g. a tick size
+func (d Decimal) IsMultipleOf(o Decimal) bool {
+	if o.units == 0 {
+		return true
+	}
+	return d.units%o.units == 0
+}
+
+func pow10(n int32) int64 {
+	r := int64(1)
+	for i := int32(0); i < n; i++ {
+		r *= 10
+	}
+	return r
+}
+
+// Cmp returns -1 if d < o, 0 if d == o and +1 if d > o
+func (d Decimal) Cmp(o Decimal) int {
+	switch {
+	case d.units < o.units:
+		return -1
+	case d.units > o.units:
+		return 1
+	}
+	return 0
+}
+
+// Equal returns true if d == o
+func (d Decimal) Equal(o Decimal) bool { return d.units == o.units }
+
+// LessThan returns true if d < o
+func (d Decimal) LessThan(o Decimal) bool { return d.units < o.units }
+
+// GreaterThan returns true if d > o
+func (d Decimal) GreaterThan(o Decimal) bool { return d.units > o.units }
+
+// Sign returns -1 if d < 0, 0 if d == 0 and +1 if d > 0
+func (d Decimal) Sign() int { return d.Cmp(Zero) }
+
+// IsZero returns true if d == 0
+func (d Decimal) IsZero() bool { return d.units == 0 }
+
+// IsPositive returns true if d > 0
+func (d Decimal) IsPositive() bool { return d.units > 0 }
+
+// Min returns the smaller of d and o
+func (d Decimal) Min(o Decimal) Decimal {
+	if o.units < d.units {
+		return o
+	}
+	return d
+}
+
+// Float64 returns the nearest float64, only meant for metrics and display
+func (d Decimal) Float64() float64 {
+	return float64(d.units) / float64(scale)
+}
+
+// String returns the shortest exact representation like "0.00000001" or "100"
+func (d Decimal) String() string {
+	s := d.StringFixed(Places)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+// StringFixed returns the representation with exactly places fractional digits (truncated)
+func (d Decimal) StringFixed(places int32) string {
+	if places > Places {
+		places = Places
+	}
+	u := d.units
+	sign := ""
+	if u < 0 {
+		sign = "-"
+	}
+	abs := new(big.Int).Abs(big.NewInt(u)).String()
+	if len(abs) <= Places {
+		abs = strings.Repeat("0", Places-len(abs)+1) + abs
+	}
+	integer, fraction := abs[:len(abs)-Places], abs[len(abs)-Places:]
+	if places <= 0 {
+		return sign + integer
+	}
+	return sign + integer + "." + fraction[:places]
+}
+
+// MarshalJSON encodes a Decimal as string to keep its precision
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.String())), nil
+}
+
+// UnmarshalJSON decodes a Decimal from a string or a number
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if string(b) == "null" {
+		return nil
+	}
+	s := string(b)
+	if len(b) > 0 && b[0] == '"' {
+		var err error
+		if s, err = strconv.Unquote(s); err != nil {
+			return ErrInvalidFormat
+		}
+	}
+
+	v, err := NewFromString(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
@@ -0,0 +1,114 @@
+package decimal
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNewFromString(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    string
+		wantErr error
+	}{
+		{"should parse an integer", "100", "100", nil},
+		{"should parse a satoshi", "0.00000001", "0.00000001", nil},
+		{"should parse a negative number", "-12.50", "-12.5", nil},
+		{"should parse an exponent", "1e-8", "0.00000001", nil},
+		{"should parse a positive exponent", "1.5E3", "1500", nil},
+		{"should ignore trailing zeros beyond precision", "1.0000000000", "1", nil},
+		{"should reject more fractional digits than Places", "0.000000001", "", ErrPrecision},
+		{"should reject garbage", "1.2.3", "", ErrInvalidFormat},
+		{"should reject an empty string", "", "", ErrInvalidFormat},
+		{"should reject too large numbers", "100000000000000", "", ErrOutOfRange},
+		{"should reject a huge exponent", "1e9223372036854775807", "", ErrOutOfRange},
+		{"should reject a large exponent without padding", "1e300000000", "", ErrOutOfRange},
+		{"should reject a huge negative exponent", "1e-9223372036854775808", "", ErrPrecision},
+		{"should parse zero with a huge exponent", "0e300000000", "0", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewFromString(tt.s)
+			if err != tt.wantErr {
+				t.Errorf("NewFromString() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("NewFromString() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecimal_Arithmetic(t *testing.T) {
+	a := RequireFromString("0.1")
+	b := RequireFromString("0.2")
+
+	if got := a.Add(b); got.String() != "0.3" {
+		t.Errorf("Decimal.Add() = %v, want 0.3", got)
+	}
+	if got := a.Sub(b); got.String() != "-0.1" {
+		t.Errorf("Decimal.Sub() = %v, want -0.1", got)
+	}
+	if got := RequireFromString("100.25").Mul(RequireFromString("0.00000003")); got.String() != "0.000003" {
+		t.Errorf("Decimal.Mul() = %v, want 0.000003", got)
+	}
+	if got := RequireFromString("1").Div(RequireFromString("3")); got.String() != "0.33333333" {
+		t.Errorf("Decimal.Div() = %v, want 0.33333333", got)
+	}
+	if got := RequireFromString("1.23456789").Truncate(2); got.String() != "1.23" {
+		t.Errorf("Decimal.Truncate() = %v, want 1.23", got)
+	}
+	if !RequireFromString("1.25").IsMultipleOf(RequireFromString("0.05")) {
+		t.Errorf("Decimal.IsMultipleOf() = false, want true")
+	}
+}
+
+func TestDecimal_Overflow(t *testing.T) {
+	max := RequireFromString("92233720368.54775807")
+	one := NewFromInt(1)
+
+	if _, err := max.CheckedAdd(one); err != ErrOutOfRange {
+		t.Errorf("Decimal.CheckedAdd() error = %v, want %v", err, ErrOutOfRange)
+	}
+	if _, err := max.Neg().Sub(one).CheckedSub(one); err != ErrOutOfRange {
+		t.Errorf("Decimal.CheckedSub() error = %v, want %v", err, ErrOutOfRange)
+	}
+	if _, err := max.CheckedMul(NewFromInt(2)); err != ErrOutOfRange {
+		t.Errorf("Decimal.CheckedMul() error = %v, want %v", err, ErrOutOfRange)
+	}
+	if _, err := max.CheckedDiv(RequireFromString("0.5")); err != ErrOutOfRange {
+		t.Errorf("Decimal.CheckedDiv() error = %v, want %v", err, ErrOutOfRange)
+	}
+	if _, err := one.CheckedDiv(Zero); err != ErrDivisionByZero {
+		t.Errorf("Decimal.CheckedDiv() error = %v, want %v", err, ErrDivisionByZero)
+	}
+	if got, err := one.CheckedAdd(one.Neg()); err != nil || !got.IsZero() {
+		t.Errorf("Decimal.CheckedAdd() = %v, %v, want 0", got, err)
+	}
+	if got := max.Add(one); !got.Equal(max) {
+		t.Errorf("Decimal.Add() = %v, want %v saturated instead of wrapped", got, max)
+	}
+	if got := max.Neg().Sub(NewFromInt(2)); got.Sign() >= 0 {
+		t.Errorf("Decimal.Sub() = %v, want a saturated negative", got)
+	}
+}
+
+func TestDecimal_JSON(t *testing.T) {
+	var v struct {
+		Size  Decimal
+		Price Decimal
+	}
+	if err := json.Unmarshal([]byte(`{"Size":"0.00000001","Price":1.34}`), &v); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if string(b) != `{"Size":"0.00000001","Price":"1.34"}` {
+		t.Errorf("json.Marshal() = %s", b)
+	}
+}
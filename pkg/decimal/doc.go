@@ -0,0 +1,4 @@
+// Package decimal provides a fixed-point decimal number for prices and sizes.
+// Crypto quantities like 0.00000001 BTC can not be represented by floating point numbers,
+// parsing rejects rather than rounds numbers which do not fit so an event replay always yields the same values.
+package decimal
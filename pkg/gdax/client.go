@@ -75,7 +75,7 @@ func (c *client) Subscribe(p []ProductID) (<-chan OrderEvent, error) {
 
 	c.productIDs = p
 	oc := make(chan OrderEvent, 2048)
-	c.conn.WriteJSON(subscribe{Type: "subscribe", ProductIds: []ProductID{EthUsd}})
+	c.conn.WriteJSON(subscribe{Type: "subscribe", ProductIds: p})
 
 	var s subscription
 	err := c.conn.ReadJSON(&s)
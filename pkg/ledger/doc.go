@@ -0,0 +1,7 @@
+// Package ledger represents the double-entry bookkeeping of settled trades.
+// Every settled fill is posted as a journal entry whose postings move funds between the accounts
+// of the customers, the clearing account of the exchange as central counterparty and the fee account.
+// The postings of an entry balance to zero per currency, so the trial balance of all accounts does too.
+//
+// double-entry bookkeeping - https://martinfowler.com/eaaDev/AccountingNarrative.html
+package ledger
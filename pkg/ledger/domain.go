@@ -0,0 +1,169 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/altairsix/eventsource"
+)
+
+var (
+	// ErrUnbalanced is returned when the postings of an entry do not sum to zero in every currency
+	ErrUnbalanced = errors.New("unbalanced journal entry")
+	// ErrEmptyEntry is returned when an entry has no postings
+	ErrEmptyEntry = errors.New("empty journal entry")
+	// ErrUnknownCommand is returned when a command is not supported by the entry
+	ErrUnknownCommand = errors.New("unknown command")
+	// ErrUnknownEvent is returned when an event is not supported by the entry
+	ErrUnknownEvent = errors.New("unknown event")
+)
+
+// Accounts of the exchange, customer accounts are named by their account id
+const (
+	// ClearingAccount is the central counterparty of every fill, it is balanced once both orders of a trade are settled
+	ClearingAccount = "exchange:clearing"
+	// FeeAccount collects the maker and taker fees
+	FeeAccount = "exchange:fees"
+)
+
+// Posting changes the balance of an account in a currency, debits are positive and credits negative
+type Posting struct {
+	Account  string
+	Currency string
+	Amount   decimal.Decimal
+}
+
+// Events --------------
+
+// EntryPosted Event - the postings of a settled fill were booked
+type EntryPosted struct {
+	TradeID  string
+	OrderID  string
+	Postings []Posting
+	Metadata orderbook.Metadata
+	eventsource.Model
+}
+
+// Commands --------------
+
+// PostEntry Command - books the postings of a fill, an entry is posted once per aggregate id
+type PostEntry struct {
+	TradeID  string
+	OrderID  string
+	Postings []Posting
+
+	Metadata orderbook.Metadata
+	eventsource.CommandModel
+}
+
+// Aggregates --------------
+
+// Entry is a journal entry and an Aggregate which apply Events
+type Entry struct {
+	TradeID  string
+	OrderID  string
+	Postings []Posting
+
+	id       string
+	version  int
+	postedAt time.Time
+}
+
+// ID returns the aggregate id of the entry
+func (e Entry) ID() string {
+	return e.id
+}
+
+// Version returns the version of the last event applied to the entry
+func (e Entry) Version() int {
+	return e.version
+}
+
+// PostedAt returns the time the entry was posted
+func (e Entry) PostedAt() time.Time {
+	return e.postedAt
+}
+
+// On applies events
+func (e *Entry) On(event eventsource.Event) error {
+	switch v := event.(type) {
+	case *EntryPosted:
+		e.TradeID = v.TradeID
+		e.OrderID = v.OrderID
+		e.Postings = v.Postings
+		e.postedAt = v.At
+	default:
+		return ErrUnknownEvent
+	}
+
+	e.id = event.AggregateID()
+	e.version = event.EventVersion()
+	return nil
+}
+
+// Apply generates events from a command
+func (e *Entry) Apply(ctx context.Context, command eventsource.Command) ([]eventsource.Event, error) {
+	switch v := command.(type) {
+	case *PostEntry:
+		// entries are immutable, posting an entry again is ignored
+		if e.version > 0 {
+			return []eventsource.Event{}, nil
+		}
+		if err := Validate(v.Postings); err != nil {
+			return nil, err
+		}
+		entryPosted := &EntryPosted{
+			TradeID:  v.TradeID,
+			OrderID:  v.OrderID,
+			Postings: v.Postings,
+			Metadata: v.Metadata,
+			Model:    eventsource.Model{ID: command.AggregateID(), Version: e.version + 1, At: time.Now()},
+		}
+		return []eventsource.Event{entryPosted}, nil
+	default:
+		return nil, ErrUnknownCommand
+	}
+}
+
+// Validate returns an error unless the postings sum to zero in every currency
+func Validate(postings []Posting) error {
+	if len(postings) == 0 {
+		return ErrEmptyEntry
+	}
+	sums := map[string]decimal.Decimal{}
+	for _, p := range postings {
+		sums[p.Currency] = sums[p.Currency].Add(p.Amount)
+	}
+	for _, sum := range sums {
+		if !sum.IsZero() {
+			return ErrUnbalanced
+		}
+	}
+	return nil
+}
+
+// FillPostings returns the postings of a fill of an order of account in the product base/quote:
+// the seller delivers size to and receives value less fee from the clearing account,
+// the buyer pays value plus fee and receives size. The fee is credited to the fee account.
+func FillPostings(account string, side orderbook.OrderSide, base, quote string, size, price, fee decimal.Decimal) []Posting {
+	value := size.Mul(price)
+	if side == orderbook.Sell {
+		return []Posting{
+			{Account: account, Currency: base, Amount: size.Neg()},
+			{Account: ClearingAccount, Currency: base, Amount: size},
+			{Account: ClearingAccount, Currency: quote, Amount: value.Neg()},
+			{Account: account, Currency: quote, Amount: value.Sub(fee)},
+			{Account: FeeAccount, Currency: quote, Amount: fee},
+		}
+	}
+	return []Posting{
+		{Account: account, Currency: quote, Amount: value.Add(fee).Neg()},
+		{Account: ClearingAccount, Currency: quote, Amount: value},
+		{Account: FeeAccount, Currency: quote, Amount: fee},
+		{Account: ClearingAccount, Currency: base, Amount: size.Neg()},
+		{Account: account, Currency: base, Amount: size},
+	}
+}
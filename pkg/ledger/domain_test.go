@@ -0,0 +1,72 @@
+package ledger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/altairsix/eventsource"
+)
+
+func TestEntry_Apply(t *testing.T) {
+	fill := FillPostings("alice", orderbook.Sell, "BTC", "USD", decimal.NewFromInt(2), decimal.NewFromInt(100), decimal.NewFromInt(1))
+	posted := &EntryPosted{Postings: fill, Model: eventsource.Model{ID: "t1/o1", Version: 1}}
+
+	tests := []struct {
+		name       string
+		events     []eventsource.Event
+		command    eventsource.Command
+		wantErr    error
+		wantEvents int
+	}{
+		{"should post a balanced entry", nil, &PostEntry{Postings: fill}, nil, 1},
+		{"should ignore an entry posted before", []eventsource.Event{posted}, &PostEntry{Postings: fill}, nil, 0},
+		{"should reject an unbalanced entry", nil,
+			&PostEntry{Postings: []Posting{{Account: "alice", Currency: "USD", Amount: decimal.NewFromInt(1)}}}, ErrUnbalanced, 0},
+		{"should reject an empty entry", nil, &PostEntry{}, ErrEmptyEntry, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Entry{}
+			for _, event := range tt.events {
+				if err := e.On(event); err != nil {
+					t.Fatalf("Entry.On() error = %v", err)
+				}
+			}
+			events, err := e.Apply(context.Background(), tt.command)
+			if err != tt.wantErr {
+				t.Fatalf("Entry.Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(events) != tt.wantEvents {
+				t.Errorf("Entry.Apply() = %v, want %v events", events, tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestFillPostings(t *testing.T) {
+	size, price, fee := decimal.NewFromInt(2), decimal.NewFromInt(100), decimal.NewFromInt(1)
+	l := NewLedger()
+	l.Post(FillPostings("alice", orderbook.Sell, "BTC", "USD", size, price, fee))
+	l.Post(FillPostings("bob", orderbook.Buy, "BTC", "USD", size, price, fee))
+
+	want := map[string]int64{
+		"alice/BTC": -2, "alice/USD": 199,
+		"bob/BTC": 2, "bob/USD": -201,
+		FeeAccount + "/USD":      2,
+		ClearingAccount + "/BTC": 0, ClearingAccount + "/USD": 0,
+	}
+	tb := l.TrialBalance()
+	if len(tb.Accounts) != len(want) {
+		t.Fatalf("Ledger.TrialBalance() = %+v, want %v accounts", tb.Accounts, len(want))
+	}
+	for _, b := range tb.Accounts {
+		if w, ok := want[b.Account+"/"+b.Currency]; !ok || !b.Balance().Equal(decimal.NewFromInt(w)) {
+			t.Errorf("Ledger.TrialBalance() %v %v = %v, want %v", b.Account, b.Currency, b.Balance(), w)
+		}
+	}
+	if !tb.Balanced() {
+		t.Errorf("TrialBalance.Balanced() = false, debits %v credits %v", tb.Debits, tb.Credits)
+	}
+}
@@ -0,0 +1,89 @@
+package ledger
+
+import (
+	"sort"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+)
+
+// AccountBalance is the sum of the debits and credits of an account in a currency
+type AccountBalance struct {
+	Account  string
+	Currency string
+	Debit    decimal.Decimal
+	Credit   decimal.Decimal
+}
+
+// Balance returns the debits less the credits
+func (b AccountBalance) Balance() decimal.Decimal {
+	return b.Debit.Sub(b.Credit)
+}
+
+// TrialBalance lists the balance of every account and currency,
+// it is balanced when the debits equal the credits in every currency
+type TrialBalance struct {
+	Accounts []AccountBalance
+	Debits   map[string]decimal.Decimal
+	Credits  map[string]decimal.Decimal
+}
+
+// Balanced reports whether the debits equal the credits in every currency
+func (t TrialBalance) Balanced() bool {
+	for currency, debit := range t.Debits {
+		if !debit.Equal(t.Credits[currency]) {
+			return false
+		}
+	}
+	return true
+}
+
+type accountCurrency struct {
+	account  string
+	currency string
+}
+
+// Ledger sums up the postings of journal entries per account and currency, it is not safe for concurrent use
+type Ledger struct {
+	balances map[accountCurrency]AccountBalance
+}
+
+// NewLedger returns an empty Ledger
+func NewLedger() *Ledger {
+	return &Ledger{balances: map[accountCurrency]AccountBalance{}}
+}
+
+// Post adds the postings of an entry to the balances
+func (l *Ledger) Post(postings []Posting) {
+	for _, p := range postings {
+		key := accountCurrency{account: p.Account, currency: p.Currency}
+		b := l.balances[key]
+		b.Account, b.Currency = p.Account, p.Currency
+		if p.Amount.Sign() < 0 {
+			b.Credit = b.Credit.Sub(p.Amount)
+		} else {
+			b.Debit = b.Debit.Add(p.Amount)
+		}
+		l.balances[key] = b
+	}
+}
+
+// TrialBalance returns the balances sorted by account and currency with the totals per currency
+func (l *Ledger) TrialBalance() TrialBalance {
+	t := TrialBalance{
+		Accounts: []AccountBalance{},
+		Debits:   map[string]decimal.Decimal{},
+		Credits:  map[string]decimal.Decimal{},
+	}
+	for _, b := range l.balances {
+		t.Accounts = append(t.Accounts, b)
+		t.Debits[b.Currency] = t.Debits[b.Currency].Add(b.Debit)
+		t.Credits[b.Currency] = t.Credits[b.Currency].Add(b.Credit)
+	}
+	sort.Slice(t.Accounts, func(i, j int) bool {
+		if t.Accounts[i].Account != t.Accounts[j].Account {
+			return t.Accounts[i].Account < t.Accounts[j].Account
+		}
+		return t.Accounts[i].Currency < t.Accounts[j].Currency
+	})
+	return t
+}
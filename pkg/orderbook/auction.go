@@ -0,0 +1,298 @@
+package orderbook
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+)
+
+// Uncross is the price at which an auction executes, the volume executed at the price
+// and the imbalance left, positive if buys and negative if sells are left over.
+type Uncross struct {
+	Price     decimal.Decimal
+	Volume    decimal.Decimal
+	Imbalance decimal.Decimal
+}
+
+// auctionOrder is an order collected by an auction with its remaining size, seq is its arrival
+type auctionOrder struct {
+	id       string
+	account  string
+	side     OrderSide
+	market   bool
+	price    decimal.Decimal
+	size     decimal.Decimal
+	seq      int
+	excluded bool // post only and fill or kill orders do not take part in the uncross
+}
+
+// Auction is a call auction of a single product. It collects orders without matching and
+// executes all crossing orders at a single price when it is uncrossed.
+// The uncross price maximizes the executable volume, ties are broken by the smallest imbalance,
+// then by the side of the imbalance (the highest price for a buy surplus, the lowest for a sell surplus)
+// and finally by the lowest price.
+type Auction struct {
+	productID ProductID
+
+	mux    sync.Mutex
+	orders []*auctionOrder // in arrival order
+	seq    int
+}
+
+// NewAuction returns an auction without orders for a product.
+func NewAuction(productID ProductID) *Auction {
+	return &Auction{productID: productID}
+}
+
+// ProductID returns the product of the auction.
+func (a *Auction) ProductID() ProductID {
+	return a.productID
+}
+
+// Add an order at the end of the auction.
+func (a *Auction) Add(o Order) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	a.add(o.id, o.AccountID, o.OrderSide, !o.OrderType.IsLimit(), o.Price, o.RemainingSize,
+		o.PostOnly || o.TimeInForce == FillOrKill)
+}
+
+// Collect moves the resting orders of the engine into the auction, the best price first and
+// within the same price in arrival order.
+func (a *Auction) Collect(e *Engine) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	for _, side := range []OrderSide{Buy, Sell} {
+		levels := e.bids
+		if side == Sell {
+			levels = e.asks
+		}
+		for _, level := range levels {
+			for _, entry := range level.entries {
+				a.add(entry.id, entry.account, side, false, level.price, entry.size, false)
+			}
+		}
+	}
+	e.bids, e.asks = nil, nil
+}
+
+// Remove an order from the auction and returns true if it was found.
+func (a *Auction) Remove(id string) bool {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	for i, b := range a.orders {
+		if b.id == id {
+			a.orders = append(a.orders[:i], a.orders[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Amend updates an order after it was amended, it keeps its arrival if it retains its priority
+// and is moved to the end otherwise. It returns false if the order was not found.
+func (a *Auction) Amend(o Order, retainsPriority bool) bool {
+	if !retainsPriority {
+		if !a.Remove(o.id) {
+			return false
+		}
+		a.Add(o)
+		return true
+	}
+
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	for _, b := range a.orders {
+		if b.id == o.id {
+			b.price = o.Price
+			b.size = o.RemainingSize
+			return true
+		}
+	}
+	return false
+}
+
+// Indicative returns the uncross of the orders collected so far, false if no order crosses.
+func (a *Auction) Indicative() (Uncross, bool) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	return a.uncross()
+}
+
+// Uncross returns the uncross and the matches which execute all crossing orders at the uncross price
+// without changing the auction, each match is removed from the auction by Fill once it is applied.
+// The order which arrived first is the maker of a match, orders of the same account never match each other.
+func (a *Auction) Uncross() (Uncross, []Match) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	u, ok := a.uncross()
+	if !ok {
+		return u, []Match{}
+	}
+	return u, a.allocate(u.Price)
+}
+
+// Fill removes the size of an applied match from its maker and taker, orders filled completely leave the auction.
+func (a *Auction) Fill(m Match) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	orders := []*auctionOrder{}
+	for _, b := range a.orders {
+		if b.id == m.MakerOrderID || b.id == m.TakerOrderID {
+			b.size = b.size.Sub(m.Size)
+		}
+		if b.size.IsPositive() {
+			orders = append(orders, b)
+		}
+	}
+	a.orders = orders
+}
+
+// Release empties the auction without executing it and returns the ids of its orders in arrival order.
+func (a *Auction) Release() []string {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	ids := []string{}
+	for _, b := range a.orders {
+		ids = append(ids, b.id)
+	}
+	a.orders = nil
+	return ids
+}
+
+func (a *Auction) add(id, account string, side OrderSide, market bool, price, size decimal.Decimal, excluded bool) {
+	a.seq++
+	a.orders = append(a.orders, &auctionOrder{id: id, account: account, side: side, market: market, price: price,
+		size: size, seq: a.seq, excluded: excluded})
+}
+
+// uncross returns the uncross price, volume and imbalance, the limit prices of the orders are the candidates.
+// The volume is the size the matches would execute, orders of the same account do not add to it.
+func (a *Auction) uncross() (Uncross, bool) {
+	best := Uncross{}
+	found := false
+	for _, b := range a.orders {
+		if b.market || b.excluded {
+			continue
+		}
+		demand, supply := a.executable(Buy, b.price), a.executable(Sell, b.price)
+		if !demand.Min(supply).IsPositive() {
+			continue
+		}
+		u := Uncross{Price: b.price, Volume: volume(a.allocate(b.price)), Imbalance: demand.Sub(supply)}
+		if u.Volume.IsPositive() && (!found || better(u, best)) {
+			best = u
+			found = true
+		}
+	}
+	return best, found
+}
+
+// allocate matches the orders which execute at a price in priority, orders of the same account never match each other
+func (a *Auction) allocate(price decimal.Decimal) []Match {
+	buys, sells := a.eligible(Buy, price), a.eligible(Sell, price)
+	remaining := map[*auctionOrder]decimal.Decimal{}
+	for _, orders := range [][]*auctionOrder{buys, sells} {
+		for _, o := range orders {
+			remaining[o] = o.size
+		}
+	}
+
+	matches := []Match{}
+	for _, buy := range buys {
+		for _, sell := range sells {
+			if !remaining[buy].IsPositive() {
+				break
+			}
+			if !remaining[sell].IsPositive() || buy.account != "" && buy.account == sell.account {
+				continue
+			}
+			size := remaining[buy].Min(remaining[sell])
+			maker, taker := buy, sell
+			if sell.seq < buy.seq {
+				maker, taker = sell, buy
+			}
+			matches = append(matches, Match{MakerOrderID: maker.id, TakerOrderID: taker.id, Price: price, Size: size})
+			remaining[buy] = remaining[buy].Sub(size)
+			remaining[sell] = remaining[sell].Sub(size)
+		}
+	}
+	return matches
+}
+
+// volume returns the size executed by matches
+func volume(matches []Match) decimal.Decimal {
+	v := decimal.Zero
+	for _, m := range matches {
+		v = v.Add(m.Size)
+	}
+	return v
+}
+
+// better returns true if the uncross u is preferred over v
+func better(u, v Uncross) bool {
+	if !u.Volume.Equal(v.Volume) {
+		return u.Volume.GreaterThan(v.Volume)
+	}
+	if !u.Imbalance.Abs().Equal(v.Imbalance.Abs()) {
+		return u.Imbalance.Abs().LessThan(v.Imbalance.Abs())
+	}
+	switch {
+	case u.Imbalance.IsPositive():
+		return u.Price.GreaterThan(v.Price)
+	case u.Imbalance.Sign() < 0:
+		return u.Price.LessThan(v.Price)
+	}
+	return u.Price.LessThan(v.Price)
+}
+
+// executable returns the size of one side which would execute at a price
+func (a *Auction) executable(side OrderSide, price decimal.Decimal) decimal.Decimal {
+	size := decimal.Zero
+	for _, b := range a.orders {
+		if b.side == side && b.accepts(price) {
+			size = size.Add(b.size)
+		}
+	}
+	return size
+}
+
+// eligible returns the orders of one side which execute at a price in priority:
+// market orders first, then the best price and within the same price in arrival order
+func (a *Auction) eligible(side OrderSide, price decimal.Decimal) []*auctionOrder {
+	orders := []*auctionOrder{}
+	for _, o := range a.orders {
+		if o.side == side && o.accepts(price) {
+			orders = append(orders, o)
+		}
+	}
+	sort.SliceStable(orders, func(i, j int) bool {
+		if orders[i].market != orders[j].market {
+			return orders[i].market
+		}
+		if side == Buy {
+			return orders[i].price.GreaterThan(orders[j].price)
+		}
+		return orders[i].price.LessThan(orders[j].price)
+	})
+	return orders
+}
+
+// accepts returns true if the order takes part in the uncross and executes at a price
+func (o *auctionOrder) accepts(price decimal.Decimal) bool {
+	if o.excluded {
+		return false
+	}
+	return o.market || crosses(o.side, o.price, price)
+}
@@ -0,0 +1,152 @@
+package orderbook
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+)
+
+func TestAuction_Uncross(t *testing.T) {
+	tests := []struct {
+		name          string
+		orders        []Order
+		wantPrice     int64
+		wantVolume    int64
+		wantImbalance int64
+		want          []Match
+		wantRemaining []string
+	}{
+		{"should not uncross if prices do not cross",
+			[]Order{newTestOrder("b1", Buy, Limit, 1, 99), newTestOrder("s1", Sell, Limit, 1, 100)},
+			0, 0, 0, []Match{}, []string{"b1", "s1"}},
+		{"should maximize the executable volume and prefer the lowest price for a sell surplus",
+			[]Order{
+				newTestOrder("b1", Buy, Limit, 5, 102), newTestOrder("b2", Buy, Limit, 3, 100),
+				newTestOrder("s1", Sell, Limit, 4, 99), newTestOrder("s2", Sell, Limit, 4, 101),
+			},
+			101, 5, -3,
+			[]Match{newTestMatch("b1", "s1", 101, 4), newTestMatch("b1", "s2", 101, 1)},
+			[]string{"b2", "s2"}},
+		{"should minimize the imbalance",
+			[]Order{newTestOrder("b1", Buy, Limit, 1, 101), newTestOrder("s1", Sell, Limit, 1, 99), newTestOrder("b2", Buy, Limit, 1, 99)},
+			101, 1, 0,
+			[]Match{newTestMatch("b1", "s1", 101, 1)},
+			[]string{"b2"}},
+		{"should execute market orders first",
+			[]Order{newTestOrder("b1", Buy, Limit, 1, 100), newTestOrder("s1", Sell, Limit, 2, 100), newTestOrder("b2", Buy, Market, 1, 0)},
+			100, 2, 0,
+			[]Match{newTestMatch("s1", "b2", 100, 1), newTestMatch("b1", "s1", 100, 1)},
+			[]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAuction(BtcUsd)
+			for _, o := range tt.orders {
+				a.Add(o)
+			}
+
+			indicative, _ := a.Indicative()
+			u, matches := a.Uncross()
+			remaining := uncross(a, matches)
+			want := Uncross{Price: decimal.NewFromInt(tt.wantPrice), Volume: decimal.NewFromInt(tt.wantVolume), Imbalance: decimal.NewFromInt(tt.wantImbalance)}
+			if u != want || indicative != want {
+				t.Errorf("Auction.Uncross() = %v, Auction.Indicative() = %v, want %v", u, indicative, want)
+			}
+			if !reflect.DeepEqual(matches, tt.want) {
+				t.Errorf("Auction.Uncross() matches = %v, want %v", matches, tt.want)
+			}
+			if !reflect.DeepEqual(remaining, tt.wantRemaining) {
+				t.Errorf("Auction.Uncross() remaining = %v, want %v", remaining, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestAuction_UncrossSameAccount(t *testing.T) {
+	b1, s1 := newTestOrder("b1", Buy, Limit, 1, 100), newTestOrder("s1", Sell, Limit, 1, 100)
+	b1.AccountID, s1.AccountID = "alice", "alice"
+
+	a := NewAuction(BtcUsd)
+	a.Add(b1)
+	a.Add(s1)
+	if u, ok := a.Indicative(); ok {
+		t.Errorf("Auction.Indicative() = %v, want no uncross of the same account", u)
+	}
+	if _, matches := a.Uncross(); len(matches) != 0 || !reflect.DeepEqual(a.Release(), []string{"b1", "s1"}) {
+		t.Errorf("Auction.Uncross() = %v, want no match of the same account", matches)
+	}
+}
+
+func TestAuction_IndicativeSameAccount(t *testing.T) {
+	b1, s1, s2 := newTestOrder("b1", Buy, Limit, 2, 101), newTestOrder("s1", Sell, Limit, 2, 100), newTestOrder("s2", Sell, Limit, 1, 101)
+	b1.AccountID, s1.AccountID, s2.AccountID = "alice", "alice", "bob"
+
+	a := NewAuction(BtcUsd)
+	a.Add(b1)
+	a.Add(s1)
+	a.Add(s2)
+	indicative, _ := a.Indicative()
+	u, matches := a.Uncross()
+	if !indicative.Volume.Equal(volume(matches)) || !u.Volume.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("Auction.Indicative() = %v, Auction.Uncross() = %v, want the volume of %v", indicative, u, matches)
+	}
+}
+
+func TestAuction_Amend(t *testing.T) {
+	b1, s1 := newTestOrder("b1", Buy, Limit, 3, 100), newTestOrder("s1", Sell, Limit, 3, 100)
+
+	a := NewAuction(BtcUsd)
+	a.Add(b1)
+	a.Add(s1)
+	b1.RemainingSize = decimal.NewFromInt(1)
+	a.Amend(b1, true)
+
+	u, matches := a.Uncross()
+	if !u.Volume.Equal(decimal.NewFromInt(1)) || !reflect.DeepEqual(matches, []Match{newTestMatch("b1", "s1", 100, 1)}) {
+		t.Errorf("Auction.Uncross() = %v, %v, want the amended size of b1", u, matches)
+	}
+	if remaining := uncross(a, matches); !reflect.DeepEqual(remaining, []string{"s1"}) {
+		t.Errorf("Auction.Release() = %v, want s1", remaining)
+	}
+}
+
+func TestAuction_Fill(t *testing.T) {
+	a := NewAuction(BtcUsd)
+	a.Add(newTestOrder("b1", Buy, Limit, 2, 100))
+	a.Add(newTestOrder("s1", Sell, Limit, 1, 100))
+	a.Add(newTestOrder("s2", Sell, Limit, 1, 100))
+
+	_, matches := a.Uncross()
+	a.Fill(matches[0])
+	if _, again := a.Uncross(); !reflect.DeepEqual(again, []Match{newTestMatch("b1", "s2", 100, 1)}) {
+		t.Errorf("Auction.Uncross() = %v, want only the match which was not filled", again)
+	}
+}
+
+// uncross fills all matches and releases the orders left in the auction
+func uncross(a *Auction, matches []Match) []string {
+	for _, m := range matches {
+		a.Fill(m)
+	}
+	return a.Release()
+}
+
+func TestAuction_Collect(t *testing.T) {
+	e := NewEngine(BtcUsd)
+	e.Submit(newTestOrder("b1", Buy, Limit, 1, 99))
+	e.Submit(newTestOrder("s1", Sell, Limit, 1, 101))
+
+	a := NewAuction(BtcUsd)
+	a.Collect(e)
+	a.Add(newTestOrder("b2", Buy, Limit, 1, 101))
+
+	if x := e.Submit(newTestOrder("b3", Buy, Market, 1, 0)); len(x.Matches) != 0 {
+		t.Errorf("Engine.Submit() = %v, want the resting orders collected by the auction", x.Matches)
+	}
+	_, matches := a.Uncross()
+	remaining := uncross(a, matches)
+	if !reflect.DeepEqual(matches, []Match{newTestMatch("s1", "b2", 101, 1)}) || !reflect.DeepEqual(remaining, []string{"b1"}) {
+		t.Errorf("Auction.Uncross() = %v, %v, want s1 to match b2", matches, remaining)
+	}
+}
@@ -0,0 +1,137 @@
+package orderbook
+
+import (
+	"github.com/LAtanassov/godax/pkg/decimal"
+)
+
+// BookEntry is either a price level of an aggregated book with the number of its orders,
+// or a single resting order with its OrderID
+type BookEntry struct {
+	Price     decimal.Decimal
+	Size      decimal.Decimal
+	NumOrders int
+	OrderID   string
+}
+
+// BookSnapshot is the state of a Book at its Sequence,
+// bids are sorted by price descending and asks by price ascending
+type BookSnapshot struct {
+	ProductID ProductID
+	Sequence  int64
+	Bids      []BookEntry
+	Asks      []BookEntry
+}
+
+// Book holds the orders resting on the book of one product in price-time priority.
+// Unlike the Engine it does not match, it is a projection of the order events and
+// the Sequence increases with every change. A Book is not safe for concurrent use.
+type Book struct {
+	productID ProductID
+	sequence  int64
+
+	bids []*priceLevel // sorted by price descending
+	asks []*priceLevel // sorted by price ascending
+}
+
+// NewBook returns an empty book of a product.
+func NewBook(productID ProductID) *Book {
+	return &Book{productID: productID}
+}
+
+// Add appends an order at the end of the queue of its price level
+func (b *Book) Add(id string, side OrderSide, price, size decimal.Decimal) {
+	insert(b.levels(side), side, price, &entry{id: id, size: size})
+	b.sequence++
+}
+
+// Reduce removes size of a resting order, e.g. after a fill, the order is removed once no size remains
+func (b *Book) Reduce(id string, side OrderSide, size decimal.Decimal) {
+	e := find(*b.levels(side), id)
+	if e == nil {
+		return
+	}
+	e.size = e.size.Sub(size)
+	if !e.size.IsPositive() {
+		remove(b.levels(side), id)
+	}
+	b.sequence++
+}
+
+// Amend replaces price and remaining size of a resting order,
+// the order moves to the end of the queue of its price level unless it retains its priority
+func (b *Book) Amend(id string, side OrderSide, price, size decimal.Decimal, priorityRetained bool) {
+	e := find(*b.levels(side), id)
+	if e == nil {
+		return
+	}
+	if priorityRetained {
+		e.size = size
+		b.sequence++
+		return
+	}
+	remove(b.levels(side), id)
+	b.Add(id, side, price, size)
+}
+
+// Remove takes an order off the book
+func (b *Book) Remove(id string, side OrderSide) {
+	if remove(b.levels(side), id) {
+		b.sequence++
+	}
+}
+
+// Level1 returns the best bid and the best ask (BBO) aggregated over their orders
+func (b *Book) Level1() BookSnapshot {
+	return b.Level2(1)
+}
+
+// Level2 returns the top depth price levels of each side aggregated over their orders, all levels if depth is 0
+func (b *Book) Level2(depth int) BookSnapshot {
+	aggregate := func(levels []*priceLevel) []BookEntry {
+		entries := []BookEntry{}
+		for _, level := range levels {
+			if depth > 0 && len(entries) == depth {
+				break
+			}
+			size := decimal.Zero
+			for _, e := range level.entries {
+				size = size.Add(e.size)
+			}
+			entries = append(entries, BookEntry{Price: level.price, Size: size, NumOrders: len(level.entries)})
+		}
+		return entries
+	}
+	return BookSnapshot{ProductID: b.productID, Sequence: b.sequence, Bids: aggregate(b.bids), Asks: aggregate(b.asks)}
+}
+
+// Level3 returns every resting order in price-time priority
+func (b *Book) Level3() BookSnapshot {
+	orders := func(levels []*priceLevel) []BookEntry {
+		entries := []BookEntry{}
+		for _, level := range levels {
+			for _, e := range level.entries {
+				entries = append(entries, BookEntry{Price: level.price, Size: e.size, NumOrders: 1, OrderID: e.id})
+			}
+		}
+		return entries
+	}
+	return BookSnapshot{ProductID: b.productID, Sequence: b.sequence, Bids: orders(b.bids), Asks: orders(b.asks)}
+}
+
+func (b *Book) levels(side OrderSide) *[]*priceLevel {
+	if side == Buy {
+		return &b.bids
+	}
+	return &b.asks
+}
+
+func find(levels []*priceLevel, id string) *entry {
+	for _, level := range levels {
+		for _, e := range level.entries {
+			if e.id == id {
+				return e
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package orderbook
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+)
+
+func newTestLevel(price, size int64, orders int) BookEntry {
+	return BookEntry{Price: decimal.NewFromInt(price), Size: decimal.NewFromInt(size), NumOrders: orders}
+}
+
+func newTestBookOrder(id string, price, size int64) BookEntry {
+	return BookEntry{Price: decimal.NewFromInt(price), Size: decimal.NewFromInt(size), NumOrders: 1, OrderID: id}
+}
+
+func newTestBook() *Book {
+	b := NewBook(BtcUsd)
+	b.Add("b1", Buy, decimal.NewFromInt(99), decimal.NewFromInt(1))
+	b.Add("b2", Buy, decimal.NewFromInt(100), decimal.NewFromInt(2))
+	b.Add("b3", Buy, decimal.NewFromInt(100), decimal.NewFromInt(3))
+	b.Add("s1", Sell, decimal.NewFromInt(102), decimal.NewFromInt(1))
+	b.Add("s2", Sell, decimal.NewFromInt(101), decimal.NewFromInt(4))
+	return b
+}
+
+func TestBook_Levels(t *testing.T) {
+	b := newTestBook()
+	tests := []struct {
+		name string
+		got  BookSnapshot
+		want BookSnapshot
+	}{
+		{"should return the best bid and ask at level 1", b.Level1(),
+			BookSnapshot{ProductID: BtcUsd, Sequence: 5,
+				Bids: []BookEntry{newTestLevel(100, 5, 2)},
+				Asks: []BookEntry{newTestLevel(101, 4, 1)}}},
+		{"should aggregate the top price levels at level 2", b.Level2(2),
+			BookSnapshot{ProductID: BtcUsd, Sequence: 5,
+				Bids: []BookEntry{newTestLevel(100, 5, 2), newTestLevel(99, 1, 1)},
+				Asks: []BookEntry{newTestLevel(101, 4, 1), newTestLevel(102, 1, 1)}}},
+		{"should return every order in price-time priority at level 3", b.Level3(),
+			BookSnapshot{ProductID: BtcUsd, Sequence: 5,
+				Bids: []BookEntry{newTestBookOrder("b2", 100, 2), newTestBookOrder("b3", 100, 3), newTestBookOrder("b1", 99, 1)},
+				Asks: []BookEntry{newTestBookOrder("s2", 101, 4), newTestBookOrder("s1", 102, 1)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("Book = %+v, want %+v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBook_Changes(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(b *Book)
+		want   []BookEntry
+	}{
+		{"should reduce the size of a filled order", func(b *Book) { b.Reduce("b2", Buy, decimal.NewFromInt(1)) },
+			[]BookEntry{newTestBookOrder("b2", 100, 1), newTestBookOrder("b3", 100, 3), newTestBookOrder("b1", 99, 1)}},
+		{"should remove an order without remaining size", func(b *Book) { b.Reduce("b2", Buy, decimal.NewFromInt(2)) },
+			[]BookEntry{newTestBookOrder("b3", 100, 3), newTestBookOrder("b1", 99, 1)}},
+		{"should remove a canceled order", func(b *Book) { b.Remove("b1", Buy) },
+			[]BookEntry{newTestBookOrder("b2", 100, 2), newTestBookOrder("b3", 100, 3)}},
+		{"should keep the position of an order which retains priority", func(b *Book) {
+			b.Amend("b2", Buy, decimal.NewFromInt(100), decimal.NewFromInt(1), true)
+		}, []BookEntry{newTestBookOrder("b2", 100, 1), newTestBookOrder("b3", 100, 3), newTestBookOrder("b1", 99, 1)}},
+		{"should move an amended order to the end of its new price level", func(b *Book) {
+			b.Amend("b2", Buy, decimal.NewFromInt(99), decimal.NewFromInt(2), false)
+		}, []BookEntry{newTestBookOrder("b3", 100, 3), newTestBookOrder("b1", 99, 1), newTestBookOrder("b2", 99, 2)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBook()
+			tt.change(b)
+			got := b.Level3()
+			if !reflect.DeepEqual(got.Bids, tt.want) {
+				t.Errorf("Book.Level3().Bids = %+v, want %+v", got.Bids, tt.want)
+			}
+			if got.Sequence <= 5 {
+				t.Errorf("Book.Level3().Sequence = %v, want > 5", got.Sequence)
+			}
+		})
+	}
+}
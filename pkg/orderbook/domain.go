@@ -5,6 +5,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/LAtanassov/godax/pkg/decimal"
 	"github.com/altairsix/eventsource"
 )
 
@@ -16,6 +17,10 @@ const (
 	Limit OrderType = iota
 	// Market order type will be executed immediately at the current market price
 	Market
+	// Stop order type becomes a market order once the last trade price reaches the stop price
+	Stop
+	// StopLimit order type becomes a limit order once the last trade price reaches the stop price
+	StopLimit
 )
 
 func (o OrderType) String() string {
@@ -24,11 +29,25 @@ func (o OrderType) String() string {
 		return "limit"
 	case Market:
 		return "market"
+	case Stop:
+		return "stop"
+	case StopLimit:
+		return "stop_limit"
 	}
 
 	return ""
 }
 
+// IsStop returns true for order types which wait for their stop price before they are matched
+func (o OrderType) IsStop() bool {
+	return o == Stop || o == StopLimit
+}
+
+// IsLimit returns true for order types with a limit price, their remaining size rests on the book
+func (o OrderType) IsLimit() bool {
+	return o == Limit || o == StopLimit
+}
+
 // OrderSide represents an enum of order sides
 type OrderSide int
 
@@ -50,32 +69,131 @@ func (o OrderSide) String() string {
 	return ""
 }
 
-// ProductID represents an enum of product ids
-type ProductID int
+// TimeInForce represents an enum of policies how long an order stays on the book
+type TimeInForce int
 
 const (
-	// BtcUsd product id represents the market of Bitcoin and US dollar
-	BtcUsd ProductID = iota
+	// GoodTilCanceled orders rest on the book until they are filled or canceled
+	GoodTilCanceled TimeInForce = iota
+	// GoodTilTime orders rest on the book until they are filled, canceled or expired
+	GoodTilTime
+	// ImmediateOrCancel orders are matched immediately, their remaining size is canceled
+	ImmediateOrCancel
+	// FillOrKill orders are either filled immediately and completely or canceled, also if a self-trade prevention
+	// other than cancel oldest would decrease or cancel them
+	FillOrKill
 )
 
-func (p ProductID) String() string {
-	switch p {
-	case BtcUsd:
-		return "BTC-USD"
+func (t TimeInForce) String() string {
+	switch t {
+	case GoodTilCanceled:
+		return "GTC"
+	case GoodTilTime:
+		return "GTT"
+	case ImmediateOrCancel:
+		return "IOC"
+	case FillOrKill:
+		return "FOK"
+	}
+
+	return ""
+}
+
+// Rests returns true if the remaining size of an order stays on the book after matching
+func (t TimeInForce) Rests() bool {
+	return t == GoodTilCanceled || t == GoodTilTime
+}
+
+// SelfTradePrevention represents an enum of policies applied when an incoming order
+// would match a resting order of the same account
+type SelfTradePrevention int
+
+const (
+	// DecreaseAndCancel cancels the smaller order and decreases the larger order by the smaller size
+	DecreaseAndCancel SelfTradePrevention = iota
+	// CancelOldest cancels the resting order
+	CancelOldest
+	// CancelNewest cancels the remaining size of the incoming order
+	CancelNewest
+	// CancelBoth cancels the resting order and the remaining size of the incoming order
+	CancelBoth
+)
+
+func (s SelfTradePrevention) String() string {
+	switch s {
+	case DecreaseAndCancel:
+		return "dc"
+	case CancelOldest:
+		return "co"
+	case CancelNewest:
+		return "cn"
+	case CancelBoth:
+		return "cb"
 	}
 
 	return ""
 }
 
+// CancelReason represents why an order was canceled
+type CancelReason string
+
 const (
-	stateCreated   = "created"
-	stateAccepted  = "accepted"
-	statePublished = "published"
-	stateCanceled  = "canceled"
-	stateMatched   = "matched"
-	stateConfirmed = "confirmed"
-	stateCleared   = "cleared"
-	stateSettled   = "settled"
+	// CanceledByUser the owner of the order canceled it
+	CanceledByUser CancelReason = "user"
+	// CanceledByTimeInForce the order could not be filled as required by its time in force
+	CanceledByTimeInForce CancelReason = "time_in_force"
+	// CanceledByPostOnly the post only order would have taken liquidity
+	CanceledByPostOnly CancelReason = "post_only"
+)
+
+// RejectReason represents the reason code why an order was rejected
+type RejectReason string
+
+const (
+	// RejectedByRiskLimit the order exceeds a risk limit
+	RejectedByRiskLimit RejectReason = "risk_limit"
+	// RejectedByCompliance the order violates a compliance rule
+	RejectedByCompliance RejectReason = "compliance"
+	// RejectedAsInvalid the order is not plausible, e.g. a fat finger price
+	RejectedAsInvalid RejectReason = "invalid"
+	// RejectedByOther any other reason, explained by the message of the rejection
+	RejectedByOther RejectReason = "other"
+)
+
+// Liquidity classifies a fill, the maker provided liquidity with a resting order and the taker removed it
+type Liquidity string
+
+const (
+	// Maker the order was resting on the book
+	Maker Liquidity = "M"
+	// Taker the order matched against a resting order
+	Taker Liquidity = "T"
+)
+
+// ProductID identifies a product like "BTC-USD", the available products are configured in a product catalog
+type ProductID string
+
+const (
+	// BtcUsd product id represents the market of Bitcoin and US dollar
+	BtcUsd ProductID = "BTC-USD"
+)
+
+func (p ProductID) String() string {
+	return string(p)
+}
+
+const (
+	stateCreated   State = "created"
+	stateAccepted  State = "accepted"
+	statePublished State = "published"
+	statePending   State = "pending"
+	stateCanceled  State = "canceled"
+	stateRejected  State = "rejected"
+	stateExpired   State = "expired"
+	stateMatched   State = "matched"
+	stateConfirmed State = "confirmed"
+	stateCleared   State = "cleared"
+	stateSettled   State = "settled"
 )
 
 var (
@@ -86,52 +204,157 @@ var (
 	ErrUnknownCommand = errors.New("unknown command")
 	// ErrInvalidStateTransition is returned when preconditions are not met
 	ErrInvalidStateTransition = errors.New("invalid state transition")
+	// ErrFillExceedsRemainingSize is returned when a fill is larger than the remaining size of an order
+	ErrFillExceedsRemainingSize = errors.New("fill exceeds remaining size")
+	// ErrInvalidExpireTime is returned when a good til time order has no expire time in the future
+	ErrInvalidExpireTime = errors.New("invalid expire time")
+	// ErrNotExpired is returned when an order is expired before its expire time
+	ErrNotExpired = errors.New("order not expired")
+	// ErrInvalidPostOnly is returned when a post only order is not a resting limit order
+	ErrInvalidPostOnly = errors.New("invalid post only order")
+	// ErrInvalidAmendment is returned when an amendment changes nothing, reprices a market or stop order
+	// or does not leave any size to fill
+	ErrInvalidAmendment = errors.New("invalid amendment")
 )
 
 // Events --------------
 
 // OrderCreated Event - created by the system
 type OrderCreated struct {
-	Size      float32
-	Price     float32
+	Size      decimal.Decimal
+	Price     decimal.Decimal
+	StopPrice decimal.Decimal
 	OrderType OrderType
 	OrderSide OrderSide
 	ProductID ProductID
+
+	TimeInForce TimeInForce
+	ExpireTime  time.Time
+
+	AccountID           string
+	ClientOID           string
+	PostOnly            bool
+	SelfTradePrevention SelfTradePrevention
+	Metadata            Metadata
 	eventsource.Model
 }
 
 // OrderAccepted Event - accepted by a risk analyst
 type OrderAccepted struct {
+	Metadata Metadata
 	eventsource.Model
 }
 
-// OrderCanceled Event - an order was canceled
+// OrderRejected Event - rejected by a risk analyst or by automated risk rules
+type OrderRejected struct {
+	Reason   RejectReason
+	Message  string
+	Metadata Metadata
+	eventsource.Model
+}
+
+// OrderCanceled Event - an order was canceled, a partially filled order for its remaining size
 type OrderCanceled struct {
+	CanceledSize decimal.Decimal
+	Reason       CancelReason
+	Metadata     Metadata
+	eventsource.Model
+}
+
+// OrderExpired Event - a good til time order reached its expire time
+type OrderExpired struct {
+	ExpiredSize decimal.Decimal
+	Metadata    Metadata
 	eventsource.Model
 }
 
-// OrderPublished Event - published on the exchange
+// OrderAmended Event - price or size of an order was replaced,
+// the order keeps its position in the queue of its price level if PriorityRetained
+type OrderAmended struct {
+	Size             decimal.Decimal
+	Price            decimal.Decimal
+	RemainingSize    decimal.Decimal
+	PriorityRetained bool
+	Metadata         Metadata
+	eventsource.Model
+}
+
+// OrderPublished Event - published on the exchange, stop orders are pending until activated
 type OrderPublished struct {
+	Metadata Metadata
+	eventsource.Model
+}
+
+// OrderActivated Event - the stop price of a pending stop order was reached
+type OrderActivated struct {
+	TriggerPrice decimal.Decimal
+	Metadata     Metadata
 	eventsource.Model
 }
 
 // OrderMatched Event - sell and buy order was matched
+// Deprecated: replaced by OrderPartiallyFilled and OrderFilled, kept to replay existing event streams
 type OrderMatched struct {
+	CounterpartyID string
+	Price          decimal.Decimal
+	Size           decimal.Decimal
+	Metadata       Metadata
+	eventsource.Model
+}
+
+// OrderPartiallyFilled Event - a part of the order was executed, the remaining size stays on the book
+type OrderPartiallyFilled struct {
+	TradeID        string
+	CounterpartyID string
+	Price          decimal.Decimal
+	Size           decimal.Decimal
+	Metadata       Metadata
 	eventsource.Model
 }
 
-// OrderConfirmed - both clients confirmed the trade
+// OrderFilled Event - the remaining size of the order was executed
+type OrderFilled struct {
+	TradeID        string
+	CounterpartyID string
+	Price          decimal.Decimal
+	Size           decimal.Decimal
+	Metadata       Metadata
+	eventsource.Model
+}
+
+// OrderSelfTradePrevented Event - size of the order was removed instead of matching an order of the same account,
+// the order is canceled once no size remains
+type OrderSelfTradePrevented struct {
+	Size     decimal.Decimal
+	Metadata Metadata
+	eventsource.Model
+}
+
+// OrderFeeCharged Event - a fee was charged for a fill, the fee is in the quote currency of the product
+type OrderFeeCharged struct {
+	TradeID   string
+	Liquidity Liquidity
+	Rate      decimal.Decimal
+	Fee       decimal.Decimal
+	Metadata  Metadata
+	eventsource.Model
+}
+
+// OrderConfirmed - all trades of the order were confirmed by both parties, see package confirmation
 type OrderConfirmed struct {
+	Metadata Metadata
 	eventsource.Model
 }
 
 // OrderCleared - all calculations and obligations are fullfilled
 type OrderCleared struct {
+	Metadata Metadata
 	eventsource.Model
 }
 
 // OrderSettled - money and security settled
 type OrderSettled struct {
+	Metadata Metadata
 	eventsource.Model
 }
 
@@ -139,47 +362,137 @@ type OrderSettled struct {
 
 // CreateOrder Command
 type CreateOrder struct {
-	Size      float32
-	Price     float32
+	Size      decimal.Decimal
+	Price     decimal.Decimal
+	StopPrice decimal.Decimal
 	OrderType OrderType
 	OrderSide OrderSide
 	ProductID ProductID
 
+	TimeInForce TimeInForce
+	ExpireTime  time.Time
+
+	AccountID           string
+	ClientOID           string
+	PostOnly            bool
+	SelfTradePrevention SelfTradePrevention
+
+	Metadata Metadata
 	eventsource.CommandModel
 }
 
 // AcceptOrder Command
 type AcceptOrder struct {
+	Metadata Metadata
+	eventsource.CommandModel
+}
+
+// RejectOrder Command
+type RejectOrder struct {
+	Reason  RejectReason
+	Message string
+
+	Metadata Metadata
 	eventsource.CommandModel
 }
 
-// CancelOrder Command
+// CancelOrder Command - Reason defaults to CanceledByUser
 type CancelOrder struct {
+	Reason CancelReason
+
+	Metadata Metadata
 	eventsource.CommandModel
 }
 
+// ReplaceOrder Command - amends size and price of an order, a zero Size or Price keeps the current value.
+// Size is the new total size including the filled size.
+type ReplaceOrder struct {
+	Size  decimal.Decimal
+	Price decimal.Decimal
+
+	Metadata Metadata
+	eventsource.CommandModel
+}
+
+// RetainsPriority returns true if the amended order keeps its position in the queue of its price level,
+// which is only the case for an unchanged price and a decreased size
+func (c ReplaceOrder) RetainsPriority(o Order) bool {
+	return (c.Price.IsZero() || c.Price.Equal(o.Price)) && !c.Size.GreaterThan(o.Size)
+}
+
 // PublishOrder Command
 type PublishOrder struct {
+	Metadata Metadata
+	eventsource.CommandModel
+}
+
+// ActivateOrder Command - issued by the trigger when the last trade price reaches the stop price
+type ActivateOrder struct {
+	TriggerPrice decimal.Decimal
+
+	Metadata Metadata
 	eventsource.CommandModel
 }
 
-// MatchOrder Command
+// MatchOrder Command - issued by the matching engine for each execution
 type MatchOrder struct {
+	TradeID        string
+	CounterpartyID string
+	Price          decimal.Decimal
+	Size           decimal.Decimal
+
+	Metadata Metadata
+	eventsource.CommandModel
+}
+
+// PreventSelfTrade Command - issued by the matching engine to remove size from an order
+// instead of matching it against an order of the same account
+type PreventSelfTrade struct {
+	Size decimal.Decimal
+
+	Metadata Metadata
+	eventsource.CommandModel
+}
+
+// ChargeFee Command - issued after a fill to charge the maker or taker fee
+type ChargeFee struct {
+	TradeID   string
+	Liquidity Liquidity
+	Rate      decimal.Decimal
+	Fee       decimal.Decimal
+
+	Metadata Metadata
+	eventsource.CommandModel
+}
+
+// ApplyTimeInForce Command - issued after an order was matched against the book,
+// cancels the remaining size of immediate or cancel, fill or kill and market orders
+type ApplyTimeInForce struct {
+	Metadata Metadata
+	eventsource.CommandModel
+}
+
+// ExpireOrder Command - issued by the expiry scheduler for good til time orders
+type ExpireOrder struct {
+	Metadata Metadata
 	eventsource.CommandModel
 }
 
 // ConfirmOrder Command
 type ConfirmOrder struct {
+	Metadata Metadata
 	eventsource.CommandModel
 }
 
 // ClearOrder Command
 type ClearOrder struct {
+	Metadata Metadata
 	eventsource.CommandModel
 }
 
 // SettleOrder Command
 type SettleOrder struct {
+	Metadata Metadata
 	eventsource.CommandModel
 }
 
@@ -187,17 +500,52 @@ type SettleOrder struct {
 
 // Order is an Aggregate which apply Events
 type Order struct {
-	Size      float32
-	Price     float32
+	Size      decimal.Decimal
+	Price     decimal.Decimal
+	StopPrice decimal.Decimal
 	OrderType OrderType
 	OrderSide OrderSide
 	ProductID ProductID
 
-	id        string
-	version   int
-	createdAt time.Time
-	updatedAt time.Time
-	state     string
+	TimeInForce TimeInForce
+	ExpireTime  time.Time
+
+	AccountID           string
+	ClientOID           string
+	PostOnly            bool
+	SelfTradePrevention SelfTradePrevention
+
+	FilledSize       decimal.Decimal
+	RemainingSize    decimal.Decimal
+	AverageFillPrice decimal.Decimal
+	FillFees         decimal.Decimal
+
+	filledValue decimal.Decimal
+	id          string
+	version     int
+	createdAt   time.Time
+	updatedAt   time.Time
+	state       State
+}
+
+// ID returns the aggregate id of the order
+func (o Order) ID() string {
+	return o.id
+}
+
+// Version returns the version of the last event applied to the order
+func (o Order) Version() int {
+	return o.version
+}
+
+// State returns the lifecycle state of the order
+func (o Order) State() State {
+	return o.state
+}
+
+// FilledValue returns the value of all fills in the quote currency, excluding fees
+func (o Order) FilledValue() decimal.Decimal {
+	return o.filledValue
 }
 
 // On an incoming event apply updates to the order (aggregate).
@@ -207,20 +555,57 @@ func (o *Order) On(event eventsource.Event) error {
 	case *OrderCreated:
 		o.Size = v.Size
 		o.Price = v.Price
+		o.StopPrice = v.StopPrice
+		o.TimeInForce = v.TimeInForce
+		o.ExpireTime = v.ExpireTime
+		o.AccountID = v.AccountID
+		o.ClientOID = v.ClientOID
+		o.PostOnly = v.PostOnly
+		o.SelfTradePrevention = v.SelfTradePrevention
 		o.OrderType = v.OrderType
 		o.ProductID = v.ProductID
 		o.OrderSide = v.OrderSide
+		o.RemainingSize = v.Size
 
 		o.createdAt = v.At
 		o.state = stateCreated
 	case *OrderAccepted:
 		o.state = stateAccepted
+	case *OrderRejected:
+		o.RemainingSize = decimal.Zero
+		o.state = stateRejected
 	case *OrderCanceled:
+		o.RemainingSize = decimal.Zero
 		o.state = stateCanceled
+	case *OrderExpired:
+		o.RemainingSize = decimal.Zero
+		o.state = stateExpired
+	case *OrderAmended:
+		o.Size = v.Size
+		o.Price = v.Price
+		o.RemainingSize = v.RemainingSize
 	case *OrderPublished:
 		o.state = statePublished
+		if o.OrderType.IsStop() {
+			o.state = statePending
+		}
+	case *OrderActivated:
+		o.state = statePublished
 	case *OrderMatched:
+		o.fill(v.Price, v.Size)
+		o.state = stateMatched
+	case *OrderPartiallyFilled:
+		o.fill(v.Price, v.Size)
+	case *OrderFilled:
+		o.fill(v.Price, v.Size)
 		o.state = stateMatched
+	case *OrderSelfTradePrevented:
+		o.RemainingSize = o.RemainingSize.Sub(v.Size)
+		if !o.RemainingSize.IsPositive() {
+			o.state = stateCanceled
+		}
+	case *OrderFeeCharged:
+		o.FillFees = o.FillFees.Add(v.Fee)
 	case *OrderConfirmed:
 		o.state = stateConfirmed
 	case *OrderCleared:
@@ -238,68 +623,207 @@ func (o *Order) On(event eventsource.Event) error {
 	return nil
 }
 
+// fill updates filled size, remaining size and the volume weighted average fill price
+func (o *Order) fill(price, size decimal.Decimal) {
+	if !size.IsPositive() {
+		return
+	}
+	o.filledValue = o.filledValue.Add(price.Mul(size))
+	o.FilledSize = o.FilledSize.Add(size)
+	o.RemainingSize = o.RemainingSize.Sub(size)
+	o.AverageFillPrice = o.filledValue.Div(o.FilledSize)
+}
+
 // Apply generates events from a command
 func (o *Order) Apply(ctx context.Context, command eventsource.Command) ([]eventsource.Event, error) {
+	t, err := transition(command)
+	if err != nil {
+		return nil, err
+	}
+	if !o.permits(t) {
+		// time in force only applies to published orders, filled orders are not affected
+		if _, ok := command.(*ApplyTimeInForce); ok {
+			return []eventsource.Event{}, nil
+		}
+		return nil, ErrInvalidStateTransition
+	}
+
 	switch v := command.(type) {
 	case *CreateOrder:
+		if v.TimeInForce == GoodTilTime && !v.ExpireTime.After(time.Now()) {
+			return nil, ErrInvalidExpireTime
+		}
+		if v.PostOnly && (!v.OrderType.IsLimit() || !v.TimeInForce.Rests()) {
+			return nil, ErrInvalidPostOnly
+		}
 		orderCreated := &OrderCreated{
-			Model: eventsource.Model{ID: v.AggregateID(), Version: o.version + 1, At: time.Now()},
+			Size:      v.Size,
+			Price:     v.Price,
+			StopPrice: v.StopPrice,
+			OrderType: v.OrderType,
+			OrderSide: v.OrderSide,
+			ProductID: v.ProductID,
+
+			TimeInForce: v.TimeInForce,
+			ExpireTime:  v.ExpireTime,
+
+			AccountID:           v.AccountID,
+			ClientOID:           v.ClientOID,
+			PostOnly:            v.PostOnly,
+			SelfTradePrevention: v.SelfTradePrevention,
+			Metadata:            v.Metadata,
+			Model:               eventsource.Model{ID: v.AggregateID(), Version: o.version + 1, At: time.Now()},
 		}
 		return []eventsource.Event{orderCreated}, nil
 	case *AcceptOrder:
-		if o.state != stateCreated {
-			return nil, ErrInvalidStateTransition
-		}
 		orderAccepted := &OrderAccepted{
-			Model: eventsource.Model{ID: v.AggregateID(), Version: o.version + 1, At: time.Now()},
+			Metadata: v.Metadata,
+			Model:    eventsource.Model{ID: v.AggregateID(), Version: o.version + 1, At: time.Now()},
 		}
 		return []eventsource.Event{orderAccepted}, nil
+	case *RejectOrder:
+		orderRejected := &OrderRejected{
+			Reason:   v.Reason,
+			Message:  v.Message,
+			Metadata: v.Metadata,
+			Model:    eventsource.Model{ID: v.AggregateID(), Version: o.version + 1, At: time.Now()},
+		}
+		return []eventsource.Event{orderRejected}, nil
 	case *CancelOrder:
-		if o.state != stateCreated {
-			return nil, ErrInvalidStateTransition
+		// a published order might be partially filled and is canceled for its remaining size
+		reason := v.Reason
+		if reason == "" {
+			reason = CanceledByUser
 		}
 		orderCanceled := &OrderCanceled{
-			Model: eventsource.Model{ID: v.AggregateID(), Version: o.version + 1, At: time.Now()},
+			CanceledSize: o.RemainingSize,
+			Reason:       reason,
+			Metadata:     v.Metadata,
+			Model:        eventsource.Model{ID: v.AggregateID(), Version: o.version + 1, At: time.Now()},
 		}
 		return []eventsource.Event{orderCanceled}, nil
-	case *PublishOrder:
-		if o.state != stateAccepted {
-			return nil, ErrInvalidStateTransition
+	case *ReplaceOrder:
+		size, price := o.Size, o.Price
+		if !v.Size.IsZero() {
+			size = v.Size
+		}
+		if !v.Price.IsZero() {
+			price = v.Price
+		}
+		if size.Equal(o.Size) && price.Equal(o.Price) {
+			return nil, ErrInvalidAmendment
+		}
+		if !price.Equal(o.Price) && !o.OrderType.IsLimit() {
+			return nil, ErrInvalidAmendment
 		}
+		if !size.GreaterThan(o.FilledSize) {
+			return nil, ErrInvalidAmendment
+		}
+		orderAmended := &OrderAmended{
+			Size:             size,
+			Price:            price,
+			RemainingSize:    size.Sub(o.FilledSize),
+			PriorityRetained: v.RetainsPriority(*o),
+			Metadata:         v.Metadata,
+			Model:            eventsource.Model{ID: v.AggregateID(), Version: o.version + 1, At: time.Now()},
+		}
+		return []eventsource.Event{orderAmended}, nil
+	case *ApplyTimeInForce:
+		// resting orders are not affected
+		if o.TimeInForce.Rests() && o.OrderType.IsLimit() {
+			return []eventsource.Event{}, nil
+		}
+		orderCanceled := &OrderCanceled{
+			CanceledSize: o.RemainingSize,
+			Reason:       CanceledByTimeInForce,
+			Metadata:     v.Metadata,
+			Model:        eventsource.Model{ID: v.AggregateID(), Version: o.version + 1, At: time.Now()},
+		}
+		return []eventsource.Event{orderCanceled}, nil
+	case *ExpireOrder:
+		if o.TimeInForce != GoodTilTime || time.Now().Before(o.ExpireTime) {
+			return nil, ErrNotExpired
+		}
+		orderExpired := &OrderExpired{
+			ExpiredSize: o.RemainingSize,
+			Metadata:    v.Metadata,
+			Model:       eventsource.Model{ID: v.AggregateID(), Version: o.version + 1, At: time.Now()},
+		}
+		return []eventsource.Event{orderExpired}, nil
+	case *PublishOrder:
 		orderPublished := &OrderPublished{
-			Model: eventsource.Model{ID: v.AggregateID(), Version: o.version + 1, At: time.Now()},
+			Metadata: v.Metadata,
+			Model:    eventsource.Model{ID: v.AggregateID(), Version: o.version + 1, At: time.Now()},
 		}
 		return []eventsource.Event{orderPublished}, nil
+	case *ActivateOrder:
+		orderActivated := &OrderActivated{
+			TriggerPrice: v.TriggerPrice,
+			Metadata:     v.Metadata,
+			Model:        eventsource.Model{ID: v.AggregateID(), Version: o.version + 1, At: time.Now()},
+		}
+		return []eventsource.Event{orderActivated}, nil
 	case *MatchOrder:
-		if o.state != statePublished {
-			return nil, ErrInvalidStateTransition
+		if v.Size.GreaterThan(o.RemainingSize) {
+			return nil, ErrFillExceedsRemainingSize
 		}
-		orderMatched := &OrderMatched{
-			Model: eventsource.Model{ID: v.AggregateID(), Version: o.version + 1, At: time.Now()},
+		model := eventsource.Model{ID: v.AggregateID(), Version: o.version + 1, At: time.Now()}
+		if v.Size.LessThan(o.RemainingSize) {
+			orderPartiallyFilled := &OrderPartiallyFilled{
+				TradeID:        v.TradeID,
+				CounterpartyID: v.CounterpartyID,
+				Price:          v.Price,
+				Size:           v.Size,
+				Metadata:       v.Metadata,
+				Model:          model,
+			}
+			return []eventsource.Event{orderPartiallyFilled}, nil
 		}
-		return []eventsource.Event{orderMatched}, nil
-	case *ConfirmOrder:
-		if o.state != stateMatched {
-			return nil, ErrInvalidStateTransition
+		orderFilled := &OrderFilled{
+			TradeID:        v.TradeID,
+			CounterpartyID: v.CounterpartyID,
+			Price:          v.Price,
+			Size:           v.Size,
+			Metadata:       v.Metadata,
+			Model:          model,
+		}
+		return []eventsource.Event{orderFilled}, nil
+	case *PreventSelfTrade:
+		if v.Size.GreaterThan(o.RemainingSize) {
+			return nil, ErrFillExceedsRemainingSize
+		}
+		orderSelfTradePrevented := &OrderSelfTradePrevented{
+			Size:     v.Size,
+			Metadata: v.Metadata,
+			Model:    eventsource.Model{ID: v.AggregateID(), Version: o.version + 1, At: time.Now()},
 		}
+		return []eventsource.Event{orderSelfTradePrevented}, nil
+	case *ChargeFee:
+		orderFeeCharged := &OrderFeeCharged{
+			TradeID:   v.TradeID,
+			Liquidity: v.Liquidity,
+			Rate:      v.Rate,
+			Fee:       v.Fee,
+			Metadata:  v.Metadata,
+			Model:     eventsource.Model{ID: v.AggregateID(), Version: o.version + 1, At: time.Now()},
+		}
+		return []eventsource.Event{orderFeeCharged}, nil
+	case *ConfirmOrder:
 		orderConfirmed := &OrderConfirmed{
-			Model: eventsource.Model{ID: v.AggregateID(), Version: o.version + 1, At: time.Now()},
+			Metadata: v.Metadata,
+			Model:    eventsource.Model{ID: v.AggregateID(), Version: o.version + 1, At: time.Now()},
 		}
 		return []eventsource.Event{orderConfirmed}, nil
 	case *ClearOrder:
-		if o.state != stateConfirmed {
-			return nil, ErrInvalidStateTransition
-		}
 		orderCleared := &OrderCleared{
-			Model: eventsource.Model{ID: v.AggregateID(), Version: o.version + 1, At: time.Now()},
+			Metadata: v.Metadata,
+			Model:    eventsource.Model{ID: v.AggregateID(), Version: o.version + 1, At: time.Now()},
 		}
 		return []eventsource.Event{orderCleared}, nil
 	case *SettleOrder:
-		if o.state != stateCleared {
-			return nil, ErrInvalidStateTransition
-		}
 		orderSettled := &OrderSettled{
-			Model: eventsource.Model{ID: v.AggregateID(), Version: o.version + 1, At: time.Now()},
+			Metadata: v.Metadata,
+			Model:    eventsource.Model{ID: v.AggregateID(), Version: o.version + 1, At: time.Now()},
 		}
 		return []eventsource.Event{orderSettled}, nil
 
@@ -2,9 +2,11 @@ package orderbook
 
 import (
 	"context"
+	"reflect"
 	"testing"
 	"time"
 
+	"github.com/LAtanassov/godax/pkg/decimal"
 	"github.com/altairsix/eventsource"
 )
 
@@ -13,7 +15,7 @@ func TestOrder_On(t *testing.T) {
 		name      string
 		order     Order
 		event     eventsource.Event
-		wantState string
+		wantState State
 		wantErr   bool
 	}{
 		{"should set stateCreated", Order{}, &OrderCreated{
@@ -25,12 +27,31 @@ func TestOrder_On(t *testing.T) {
 		{"should set stateCanceled", Order{}, &OrderCanceled{
 			Model: eventsource.Model{ID: "", Version: 1, At: time.Now()},
 		}, stateCanceled, false},
+		{"should set stateRejected", Order{}, &OrderRejected{
+			Model: eventsource.Model{ID: "", Version: 1, At: time.Now()},
+		}, stateRejected, false},
 		{"should set statePublished", Order{}, &OrderPublished{
 			Model: eventsource.Model{ID: "", Version: 1, At: time.Now()},
 		}, statePublished, false},
+		{"should set statePending for a published stop order", Order{OrderType: Stop}, &OrderPublished{
+			Model: eventsource.Model{ID: "", Version: 1, At: time.Now()},
+		}, statePending, false},
+		{"should set statePublished for an activated stop order", Order{OrderType: Stop, state: statePending}, &OrderActivated{
+			Model: eventsource.Model{ID: "", Version: 1, At: time.Now()},
+		}, statePublished, false},
 		{"should set stateMatched", Order{}, &OrderMatched{
 			Model: eventsource.Model{ID: "", Version: 1, At: time.Now()},
 		}, stateMatched, false},
+		{"should set stateMatched on OrderFilled", Order{}, &OrderFilled{
+			Model: eventsource.Model{ID: "", Version: 1, At: time.Now()},
+		}, stateMatched, false},
+		{"should keep statePublished on OrderPartiallyFilled", Order{state: statePublished}, &OrderPartiallyFilled{
+			Model: eventsource.Model{ID: "", Version: 1, At: time.Now()},
+		}, statePublished, false},
+		{"should set stateCanceled once OrderSelfTradePrevented removed the remaining size", Order{state: statePublished, RemainingSize: decimal.NewFromInt(1)}, &OrderSelfTradePrevented{
+			Size:  decimal.NewFromInt(1),
+			Model: eventsource.Model{ID: "", Version: 1, At: time.Now()},
+		}, stateCanceled, false},
 		{"should set stateConfirmed", Order{}, &OrderConfirmed{
 			Model: eventsource.Model{ID: "", Version: 1, At: time.Now()},
 		}, stateConfirmed, false},
@@ -108,12 +129,25 @@ func TestOrder_Apply(t *testing.T) {
 			[]eventsource.Event{OrderCanceled{
 				Model: eventsource.Model{ID: "1", Version: 1, At: time.Now()},
 			}}, false, nil},
-		{"should return ErrInvalidStateTransition for CancelOrder command with an Order with not stateCreated", Order{version: 0, state: stateAccepted},
+		{"should return ErrInvalidStateTransition for CancelOrder command with a closed Order", Order{version: 0, state: stateRejected},
 			args{context.Background(), &CancelOrder{
 				CommandModel: eventsource.CommandModel{ID: "1"},
 			}},
 			[]eventsource.Event{}, true, ErrInvalidStateTransition},
 
+		{"should return OrderRejected Event for RejectOrder command", Order{version: 0, state: stateCreated},
+			args{context.Background(), &RejectOrder{
+				Reason:       RejectedByRiskLimit,
+				CommandModel: eventsource.CommandModel{ID: "1"},
+			}},
+			[]eventsource.Event{OrderRejected{
+				Model: eventsource.Model{ID: "1", Version: 1, At: time.Now()},
+			}}, false, nil},
+		{"should return ErrInvalidStateTransition for RejectOrder command with an Order with not stateCreated", Order{version: 0, state: stateAccepted},
+			args{context.Background(), &RejectOrder{
+				CommandModel: eventsource.CommandModel{ID: "1"},
+			}},
+			[]eventsource.Event{}, true, ErrInvalidStateTransition},
 		{"should return OrderPublished Event for PublishOrder command", Order{version: 0, state: stateAccepted},
 			args{context.Background(), &PublishOrder{
 				CommandModel: eventsource.CommandModel{ID: "1"},
@@ -127,6 +161,92 @@ func TestOrder_Apply(t *testing.T) {
 			}},
 			[]eventsource.Event{}, true, ErrInvalidStateTransition},
 
+		{"should return ErrInvalidExpireTime for a good til time CreateOrder command without expire time", Order{version: 0},
+			args{context.Background(), &CreateOrder{
+				TimeInForce:  GoodTilTime,
+				CommandModel: eventsource.CommandModel{ID: "1"},
+			}},
+			[]eventsource.Event{}, true, ErrInvalidExpireTime},
+		{"should return ErrInvalidPostOnly for a post only market CreateOrder command", Order{version: 0},
+			args{context.Background(), &CreateOrder{
+				OrderType:    Market,
+				PostOnly:     true,
+				CommandModel: eventsource.CommandModel{ID: "1"},
+			}},
+			[]eventsource.Event{}, true, ErrInvalidPostOnly},
+		{"should return OrderSelfTradePrevented Event for PreventSelfTrade command", Order{version: 0, state: statePublished, RemainingSize: decimal.NewFromInt(2)},
+			args{context.Background(), &PreventSelfTrade{
+				Size:         decimal.NewFromInt(1),
+				CommandModel: eventsource.CommandModel{ID: "1"},
+			}},
+			[]eventsource.Event{OrderSelfTradePrevented{
+				Model: eventsource.Model{ID: "1", Version: 1, At: time.Now()},
+			}}, false, nil},
+		{"should return ErrFillExceedsRemainingSize for PreventSelfTrade command larger than the remaining size", Order{version: 0, state: statePublished, RemainingSize: decimal.NewFromInt(1)},
+			args{context.Background(), &PreventSelfTrade{
+				Size:         decimal.NewFromInt(2),
+				CommandModel: eventsource.CommandModel{ID: "1"},
+			}},
+			[]eventsource.Event{}, true, ErrFillExceedsRemainingSize},
+		{"should return OrderAmended Event for ReplaceOrder command", Order{version: 0, state: statePublished, Size: decimal.NewFromInt(2), Price: decimal.NewFromInt(100)},
+			args{context.Background(), &ReplaceOrder{
+				Size:         decimal.NewFromInt(1),
+				CommandModel: eventsource.CommandModel{ID: "1"},
+			}},
+			[]eventsource.Event{OrderAmended{
+				Model: eventsource.Model{ID: "1", Version: 1, At: time.Now()},
+			}}, false, nil},
+		{"should return ErrInvalidAmendment for ReplaceOrder command not leaving any size to fill", Order{version: 0, state: statePublished, Size: decimal.NewFromInt(2), FilledSize: decimal.NewFromInt(1)},
+			args{context.Background(), &ReplaceOrder{
+				Size:         decimal.NewFromInt(1),
+				CommandModel: eventsource.CommandModel{ID: "1"},
+			}},
+			[]eventsource.Event{}, true, ErrInvalidAmendment},
+		{"should return ErrInvalidAmendment for ReplaceOrder command repricing a market Order", Order{version: 0, state: statePublished, OrderType: Market, Size: decimal.NewFromInt(1)},
+			args{context.Background(), &ReplaceOrder{
+				Price:        decimal.NewFromInt(100),
+				CommandModel: eventsource.CommandModel{ID: "1"},
+			}},
+			[]eventsource.Event{}, true, ErrInvalidAmendment},
+		{"should return ErrInvalidStateTransition for ReplaceOrder command with a matched Order", Order{version: 0, state: stateMatched, Size: decimal.NewFromInt(1)},
+			args{context.Background(), &ReplaceOrder{
+				Size:         decimal.NewFromInt(2),
+				CommandModel: eventsource.CommandModel{ID: "1"},
+			}},
+			[]eventsource.Event{}, true, ErrInvalidStateTransition},
+		{"should return OrderCanceled Event for ApplyTimeInForce command with an immediate or cancel Order", Order{version: 0, state: statePublished, TimeInForce: ImmediateOrCancel},
+			args{context.Background(), &ApplyTimeInForce{
+				CommandModel: eventsource.CommandModel{ID: "1"},
+			}},
+			[]eventsource.Event{OrderCanceled{
+				Model: eventsource.Model{ID: "1", Version: 1, At: time.Now()},
+			}}, false, nil},
+		{"should return OrderExpired Event for ExpireOrder command with an expired Order", Order{version: 0, state: statePublished, TimeInForce: GoodTilTime, ExpireTime: time.Now()},
+			args{context.Background(), &ExpireOrder{
+				CommandModel: eventsource.CommandModel{ID: "1"},
+			}},
+			[]eventsource.Event{OrderExpired{
+				Model: eventsource.Model{ID: "1", Version: 1, At: time.Now()},
+			}}, false, nil},
+		{"should return ErrNotExpired for ExpireOrder command before the expire time", Order{version: 0, state: statePublished, TimeInForce: GoodTilTime, ExpireTime: time.Now().Add(time.Hour)},
+			args{context.Background(), &ExpireOrder{
+				CommandModel: eventsource.CommandModel{ID: "1"},
+			}},
+			[]eventsource.Event{}, true, ErrNotExpired},
+
+		{"should return OrderActivated Event for ActivateOrder command", Order{version: 0, state: statePending},
+			args{context.Background(), &ActivateOrder{
+				CommandModel: eventsource.CommandModel{ID: "1"},
+			}},
+			[]eventsource.Event{OrderActivated{
+				Model: eventsource.Model{ID: "1", Version: 1, At: time.Now()},
+			}}, false, nil},
+		{"should return ErrInvalidStateTransition for ActivateOrder command with an Order with not statePending", Order{version: 0, state: statePublished},
+			args{context.Background(), &ActivateOrder{
+				CommandModel: eventsource.CommandModel{ID: "1"},
+			}},
+			[]eventsource.Event{}, true, ErrInvalidStateTransition},
+
 		{"should return OrderMatched Event for MatchOrder command", Order{version: 0, state: statePublished},
 			args{context.Background(), &MatchOrder{
 				CommandModel: eventsource.CommandModel{ID: "1"},
@@ -140,6 +260,28 @@ func TestOrder_Apply(t *testing.T) {
 			}},
 			[]eventsource.Event{}, true, ErrInvalidStateTransition},
 
+		{"should return OrderPartiallyFilled Event for MatchOrder command smaller than the remaining size", Order{version: 0, state: statePublished, RemainingSize: decimal.NewFromInt(2)},
+			args{context.Background(), &MatchOrder{
+				Size:         decimal.NewFromInt(1),
+				CommandModel: eventsource.CommandModel{ID: "1"},
+			}},
+			[]eventsource.Event{OrderPartiallyFilled{
+				Model: eventsource.Model{ID: "1", Version: 1, At: time.Now()},
+			}}, false, nil},
+		{"should return ErrFillExceedsRemainingSize for MatchOrder command larger than the remaining size", Order{version: 0, state: statePublished, RemainingSize: decimal.NewFromInt(1)},
+			args{context.Background(), &MatchOrder{
+				Size:         decimal.NewFromInt(2),
+				CommandModel: eventsource.CommandModel{ID: "1"},
+			}},
+			[]eventsource.Event{}, true, ErrFillExceedsRemainingSize},
+		{"should return OrderCanceled Event for CancelOrder command with a published Order", Order{version: 0, state: statePublished, RemainingSize: decimal.NewFromInt(1)},
+			args{context.Background(), &CancelOrder{
+				CommandModel: eventsource.CommandModel{ID: "1"},
+			}},
+			[]eventsource.Event{OrderCanceled{
+				Model: eventsource.Model{ID: "1", Version: 1, At: time.Now()},
+			}}, false, nil},
+
 		{"should return OrderConfirmed Event for ConfirmOrder command", Order{version: 0, state: stateMatched},
 			args{context.Background(), &ConfirmOrder{
 				CommandModel: eventsource.CommandModel{ID: "1"},
@@ -201,3 +343,53 @@ func TestOrder_Apply(t *testing.T) {
 		})
 	}
 }
+
+func TestReplaceOrder_RetainsPriority(t *testing.T) {
+	o := Order{Size: decimal.NewFromInt(2), Price: decimal.NewFromInt(100)}
+	tests := []struct {
+		name    string
+		command ReplaceOrder
+		want    bool
+	}{
+		{"should retain priority on a size decrease", ReplaceOrder{Size: decimal.NewFromInt(1)}, true},
+		{"should lose priority on a size increase", ReplaceOrder{Size: decimal.NewFromInt(3)}, false},
+		{"should lose priority on a price change", ReplaceOrder{Price: decimal.NewFromInt(101)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.command.RetainsPriority(o); got != tt.want {
+				t.Errorf("ReplaceOrder.RetainsPriority() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrder_ApplyCopiesMetadata(t *testing.T) {
+	md := Metadata{Actor: "alice", Reason: "changed my mind", CorrelationID: "req-1", Source: "api"}
+	tests := []struct {
+		name    string
+		order   Order
+		command eventsource.Command
+	}{
+		{"should record metadata on OrderCreated", Order{},
+			&CreateOrder{Metadata: md, CommandModel: eventsource.CommandModel{ID: "1"}}},
+		{"should record metadata on OrderCanceled", Order{state: stateCreated},
+			&CancelOrder{Metadata: md, CommandModel: eventsource.CommandModel{ID: "1"}}},
+		{"should record metadata on OrderFilled", Order{state: statePublished, RemainingSize: decimal.NewFromInt(1)},
+			&MatchOrder{Size: decimal.NewFromInt(1), Metadata: md, CommandModel: eventsource.CommandModel{ID: "1"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.order.Apply(context.Background(), tt.command)
+			if err != nil {
+				t.Fatalf("Order.Apply() error = %v", err)
+			}
+			if len(got) != 1 {
+				t.Fatalf("Order.Apply() = %v, want one event", got)
+			}
+			if m := reflect.ValueOf(got[0]).Elem().FieldByName("Metadata").Interface(); m != md {
+				t.Errorf("Order.Apply() metadata = %+v, want %+v", m, md)
+			}
+		})
+	}
+}
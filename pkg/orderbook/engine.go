@@ -0,0 +1,281 @@
+package orderbook
+
+import (
+	"sync"
+	"time"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+)
+
+// Match represents an execution between a resting (maker) and an incoming (taker) order.
+type Match struct {
+	MakerOrderID string
+	TakerOrderID string
+	Price        decimal.Decimal
+	Size         decimal.Decimal
+}
+
+// Trade is the record of an executed Match, Side is the side of the taker which is the aggressor
+type Trade struct {
+	TradeID      string
+	ProductID    ProductID
+	Price        decimal.Decimal
+	Size         decimal.Decimal
+	MakerOrderID string
+	TakerOrderID string
+	Side         OrderSide
+	Time         time.Time
+}
+
+// Prevention represents size removed from an order instead of matching it against an order of the same account.
+type Prevention struct {
+	OrderID string
+	Size    decimal.Decimal
+}
+
+// Execution is the outcome of submitting an order to the engine.
+type Execution struct {
+	Matches   []Match
+	Prevented []Prevention
+	// Rejected is true if a post only order was not added because it would have taken liquidity
+	Rejected bool
+}
+
+// entry is an order resting on the book with its remaining size
+type entry struct {
+	id      string
+	account string
+	size    decimal.Decimal
+}
+
+// priceLevel holds all resting orders of one price in arrival order (FIFO)
+type priceLevel struct {
+	price   decimal.Decimal
+	entries []*entry
+}
+
+// Engine is a continuous matching engine of a single product.
+// Orders are executed in price-time priority: the best price first and
+// within the same price the order which arrived first.
+type Engine struct {
+	productID ProductID
+
+	mux  sync.Mutex
+	bids []*priceLevel // sorted by price descending
+	asks []*priceLevel // sorted by price ascending
+}
+
+// NewEngine returns an empty matching engine for a product.
+func NewEngine(productID ProductID) *Engine {
+	return &Engine{productID: productID}
+}
+
+// ProductID returns the product the engine matches.
+func (e *Engine) ProductID() ProductID {
+	return e.productID
+}
+
+// Submit matches an incoming order against the opposite side of the book.
+// The remaining size of a limit order rests on the book, the remaining size of a market order is discarded.
+// Activated stop orders are matched like market orders and stop limit orders like limit orders.
+// Only good til canceled and good til time orders rest on the book,
+// fill or kill orders are not matched at all if they can not be filled completely.
+// Post only orders which would cross the book are rejected.
+// Orders of the same account never match, the self trade prevention of the incoming order decides which size is removed.
+func (e *Engine) Submit(o Order) Execution {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	remaining := o.RemainingSize
+	prevented := decimal.Zero
+	x := Execution{Matches: []Match{}, Prevented: []Prevention{}}
+
+	levels := &e.asks
+	if o.OrderSide == Sell {
+		levels = &e.bids
+	}
+
+	if o.PostOnly && len(*levels) > 0 && crosses(o.OrderSide, o.Price, (*levels)[0].price) {
+		x.Rejected = true
+		return x
+	}
+	if o.TimeInForce == FillOrKill && !fills(*levels, o, remaining) {
+		return x
+	}
+
+	for remaining.IsPositive() && len(*levels) > 0 {
+		level := (*levels)[0]
+		if o.OrderType.IsLimit() && !crosses(o.OrderSide, o.Price, level.price) {
+			break
+		}
+
+		for remaining.IsPositive() && len(level.entries) > 0 {
+			maker := level.entries[0]
+			if o.AccountID != "" && maker.account == o.AccountID {
+				makerSize, takerSize := preventSelfTrade(o.SelfTradePrevention, maker.size, remaining)
+				if makerSize.IsPositive() {
+					x.Prevented = append(x.Prevented, Prevention{OrderID: maker.id, Size: makerSize})
+				}
+				prevented = prevented.Add(takerSize)
+				remaining = remaining.Sub(takerSize)
+				maker.size = maker.size.Sub(makerSize)
+			} else {
+				size := remaining.Min(maker.size)
+				x.Matches = append(x.Matches, Match{
+					MakerOrderID: maker.id,
+					TakerOrderID: o.id,
+					Price:        level.price,
+					Size:         size,
+				})
+				remaining = remaining.Sub(size)
+				maker.size = maker.size.Sub(size)
+			}
+
+			if !maker.size.IsPositive() {
+				level.entries = level.entries[1:]
+			}
+		}
+
+		if len(level.entries) == 0 {
+			*levels = (*levels)[1:]
+		}
+	}
+
+	if prevented.IsPositive() {
+		x.Prevented = append(x.Prevented, Prevention{OrderID: o.id, Size: prevented})
+	}
+	if remaining.IsPositive() && o.OrderType.IsLimit() && o.TimeInForce.Rests() {
+		e.rest(o.id, o.AccountID, o.OrderSide, o.Price, remaining)
+	}
+
+	return x
+}
+
+// preventSelfTrade returns the sizes removed from the resting (maker) and the incoming (taker) order
+func preventSelfTrade(stp SelfTradePrevention, maker, taker decimal.Decimal) (decimal.Decimal, decimal.Decimal) {
+	switch stp {
+	case CancelOldest:
+		return maker, decimal.Zero
+	case CancelNewest:
+		return decimal.Zero, taker
+	case CancelBoth:
+		return maker, taker
+	}
+	size := maker.Min(taker)
+	return size, size
+}
+
+// Cancel removes a resting order from the book and returns true if it was found.
+func (e *Engine) Cancel(id string) bool {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	return remove(&e.bids, id) || remove(&e.asks, id)
+}
+
+// Amend decreases the size of a resting order in place so it keeps its position in the queue,
+// it returns false if the order was not found.
+func (e *Engine) Amend(id string, size decimal.Decimal) bool {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	for _, levels := range [][]*priceLevel{e.bids, e.asks} {
+		for _, level := range levels {
+			for _, entry := range level.entries {
+				if entry.id == id {
+					entry.size = size
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Restore puts a resting order back at the end of the queue of its price level without matching it,
+// e.g. when the engine is rebuilt from the order events after a restart.
+func (e *Engine) Restore(o Order) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	e.rest(o.id, o.AccountID, o.OrderSide, o.Price, o.RemainingSize)
+}
+
+// rest appends an order at the end of the queue of its price level
+func (e *Engine) rest(id, account string, side OrderSide, price, size decimal.Decimal) {
+	levels := &e.asks
+	if side == Buy {
+		levels = &e.bids
+	}
+	insert(levels, side, price, &entry{id: id, account: account, size: size})
+}
+
+// insert appends the entry at the end of the queue of its price level, the level is created if missing
+func insert(levels *[]*priceLevel, side OrderSide, price decimal.Decimal, e *entry) {
+	better := func(a, b decimal.Decimal) bool { return a.LessThan(b) }
+	if side == Buy {
+		better = func(a, b decimal.Decimal) bool { return a.GreaterThan(b) }
+	}
+
+	i := 0
+	for i < len(*levels) && better((*levels)[i].price, price) {
+		i++
+	}
+
+	if i < len(*levels) && (*levels)[i].price.Equal(price) {
+		(*levels)[i].entries = append((*levels)[i].entries, e)
+		return
+	}
+
+	level := &priceLevel{price: price, entries: []*entry{e}}
+	*levels = append(*levels, nil)
+	copy((*levels)[i+1:], (*levels)[i:])
+	(*levels)[i] = level
+}
+
+// fills returns true if an order executes its whole size against the levels. Resting orders of the same account
+// are skipped if the order cancels the oldest, any other self-trade prevention would decrease or cancel the order.
+func fills(levels []*priceLevel, o Order, size decimal.Decimal) bool {
+	for _, level := range levels {
+		if o.OrderType.IsLimit() && !crosses(o.OrderSide, o.Price, level.price) {
+			break
+		}
+		for _, entry := range level.entries {
+			if !size.IsPositive() {
+				return true
+			}
+			if o.AccountID != "" && entry.account == o.AccountID {
+				if o.SelfTradePrevention == CancelOldest {
+					continue
+				}
+				return false
+			}
+			size = size.Sub(size.Min(entry.size))
+		}
+	}
+	return !size.IsPositive()
+}
+
+func remove(levels *[]*priceLevel, id string) bool {
+	for i, level := range *levels {
+		for j, entry := range level.entries {
+			if entry.id != id {
+				continue
+			}
+			level.entries = append(level.entries[:j], level.entries[j+1:]...)
+			if len(level.entries) == 0 {
+				*levels = append((*levels)[:i], (*levels)[i+1:]...)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// crosses returns true if an incoming order at price can trade with a resting order at makerPrice
+func crosses(side OrderSide, price, makerPrice decimal.Decimal) bool {
+	if side == Buy {
+		return !price.LessThan(makerPrice)
+	}
+	return !price.GreaterThan(makerPrice)
+}
@@ -0,0 +1,278 @@
+package orderbook
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+)
+
+func newTestOrder(id string, side OrderSide, orderType OrderType, size, price int64) Order {
+	return Order{id: id, OrderSide: side, OrderType: orderType,
+		Size: decimal.NewFromInt(size), RemainingSize: decimal.NewFromInt(size), Price: decimal.NewFromInt(price), ProductID: BtcUsd}
+}
+
+func newTestMatch(maker, taker string, price, size int64) Match {
+	return Match{MakerOrderID: maker, TakerOrderID: taker, Price: decimal.NewFromInt(price), Size: decimal.NewFromInt(size)}
+}
+
+func TestEngine_Submit(t *testing.T) {
+	tests := []struct {
+		name    string
+		resting []Order
+		order   Order
+		want    []Match
+	}{
+		{"should not match an empty book", nil,
+			newTestOrder("b1", Buy, Limit, 1, 100),
+			[]Match{}},
+		{"should not match if prices do not cross",
+			[]Order{newTestOrder("s1", Sell, Limit, 1, 101)},
+			newTestOrder("b1", Buy, Limit, 1, 100),
+			[]Match{}},
+		{"should execute at the price of the resting order",
+			[]Order{newTestOrder("b1", Buy, Limit, 1, 100)},
+			newTestOrder("s1", Sell, Limit, 1, 80),
+			[]Match{newTestMatch("b1", "s1", 100, 1)}},
+		{"should prefer the better price",
+			[]Order{newTestOrder("s1", Sell, Limit, 1, 101), newTestOrder("s2", Sell, Limit, 1, 100)},
+			newTestOrder("b1", Buy, Limit, 1, 101),
+			[]Match{newTestMatch("s2", "b1", 100, 1)}},
+		{"should prefer the earlier order within a price level",
+			[]Order{newTestOrder("s1", Sell, Limit, 1, 100), newTestOrder("s2", Sell, Limit, 1, 100)},
+			newTestOrder("b1", Buy, Limit, 1, 100),
+			[]Match{newTestMatch("s1", "b1", 100, 1)}},
+		{"should walk the book until the order is filled",
+			[]Order{newTestOrder("s1", Sell, Limit, 5, 100), newTestOrder("s2", Sell, Limit, 5, 101)},
+			newTestOrder("b1", Buy, Limit, 7, 101),
+			[]Match{
+				newTestMatch("s1", "b1", 100, 5),
+				newTestMatch("s2", "b1", 101, 2),
+			}},
+		{"should match a market order regardless of price",
+			[]Order{newTestOrder("b1", Buy, Limit, 2, 50)},
+			newTestOrder("s1", Sell, Market, 1, 0),
+			[]Match{newTestMatch("b1", "s1", 50, 1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine(BtcUsd)
+			for _, o := range tt.resting {
+				e.Submit(o)
+			}
+
+			if got := e.Submit(tt.order).Matches; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Engine.Submit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngine_SubmitRestsRemainder(t *testing.T) {
+	e := NewEngine(BtcUsd)
+	e.Submit(newTestOrder("s1", Sell, Limit, 5, 100))
+	e.Submit(newTestOrder("b1", Buy, Limit, 7, 100))
+
+	got := e.Submit(newTestOrder("s2", Sell, Limit, 2, 100)).Matches
+	want := []Match{newTestMatch("b1", "s2", 100, 2)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Engine.Submit() = %v, want %v", got, want)
+	}
+}
+
+func TestEngine_Cancel(t *testing.T) {
+	e := NewEngine(BtcUsd)
+	e.Submit(newTestOrder("s1", Sell, Limit, 1, 100))
+
+	if !e.Cancel("s1") {
+		t.Errorf("Engine.Cancel() = false, want true")
+	}
+	if e.Cancel("s1") {
+		t.Errorf("Engine.Cancel() = true, want false")
+	}
+	if got := e.Submit(newTestOrder("b1", Buy, Limit, 1, 100)).Matches; len(got) != 0 {
+		t.Errorf("Engine.Submit() = %v, want no matches", got)
+	}
+}
+
+func TestEngine_Restore(t *testing.T) {
+	e := NewEngine(BtcUsd)
+	partial := newTestOrder("s1", Sell, Limit, 5, 100)
+	partial.RemainingSize = decimal.NewFromInt(2)
+	e.Restore(partial)
+	e.Restore(newTestOrder("s2", Sell, Limit, 1, 100))
+
+	got := e.Submit(newTestOrder("b1", Buy, Limit, 3, 100)).Matches
+	want := []Match{newTestMatch("s1", "b1", 100, 2), newTestMatch("s2", "b1", 100, 1)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Engine.Submit() = %v, want the remaining sizes in the restored order %v", got, want)
+	}
+}
+
+func TestEngine_SubmitTimeInForce(t *testing.T) {
+	tests := []struct {
+		name        string
+		timeInForce TimeInForce
+		size        int64
+		wantMatches int
+		wantRests   bool
+	}{
+		{"should rest the remaining size of a good til canceled order", GoodTilCanceled, 3, 1, true},
+		{"should not rest the remaining size of an immediate or cancel order", ImmediateOrCancel, 3, 1, false},
+		{"should not match a fill or kill order which can not be filled", FillOrKill, 3, 0, false},
+		{"should match a fill or kill order which can be filled", FillOrKill, 2, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine(BtcUsd)
+			e.Submit(newTestOrder("s1", Sell, Limit, 2, 100))
+
+			o := newTestOrder("b1", Buy, Limit, tt.size, 100)
+			o.TimeInForce = tt.timeInForce
+			if got := e.Submit(o).Matches; len(got) != tt.wantMatches {
+				t.Errorf("Engine.Submit() = %v, want %v matches", got, tt.wantMatches)
+			}
+			if got := e.Cancel("b1"); got != tt.wantRests {
+				t.Errorf("Engine.Cancel() = %v, want %v", got, tt.wantRests)
+			}
+		})
+	}
+}
+
+func TestEngine_SubmitPostOnly(t *testing.T) {
+	e := NewEngine(BtcUsd)
+	e.Submit(newTestOrder("s1", Sell, Limit, 1, 100))
+
+	o := newTestOrder("b1", Buy, Limit, 1, 100)
+	o.PostOnly = true
+	if got := e.Submit(o); !got.Rejected || len(got.Matches) != 0 {
+		t.Errorf("Engine.Submit() = %v, want rejected", got)
+	}
+	if e.Cancel("b1") {
+		t.Errorf("Engine.Cancel() = true, want a rejected order not to rest")
+	}
+
+	o = newTestOrder("b2", Buy, Limit, 1, 99)
+	o.PostOnly = true
+	if got := e.Submit(o); got.Rejected {
+		t.Errorf("Engine.Submit() = %v, want not rejected", got)
+	}
+	if !e.Cancel("b2") {
+		t.Errorf("Engine.Cancel() = false, want a post only order to rest")
+	}
+}
+
+func TestEngine_SubmitSelfTradePrevention(t *testing.T) {
+	newPrevention := func(id string, size int64) Prevention {
+		return Prevention{OrderID: id, Size: decimal.NewFromInt(size)}
+	}
+
+	tests := []struct {
+		name          string
+		stp           SelfTradePrevention
+		size          int64
+		wantMatches   []Match
+		wantPrevented []Prevention
+		wantRests     bool
+	}{
+		{"should decrease the larger order and cancel the smaller order", DecreaseAndCancel, 1,
+			[]Match{},
+			[]Prevention{newPrevention("s1", 1), newPrevention("b1", 1)},
+			false},
+		{"should decrease and continue matching other accounts", DecreaseAndCancel, 4,
+			[]Match{newTestMatch("s2", "b1", 100, 1)},
+			[]Prevention{newPrevention("s1", 2), newPrevention("b1", 2)},
+			true},
+		{"should cancel the oldest order and continue matching", CancelOldest, 2,
+			[]Match{newTestMatch("s2", "b1", 100, 1)},
+			[]Prevention{newPrevention("s1", 2)},
+			true},
+		{"should cancel the newest order", CancelNewest, 2,
+			[]Match{},
+			[]Prevention{newPrevention("b1", 2)},
+			false},
+		{"should cancel both orders", CancelBoth, 2,
+			[]Match{},
+			[]Prevention{newPrevention("s1", 2), newPrevention("b1", 2)},
+			false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine(BtcUsd)
+			own := newTestOrder("s1", Sell, Limit, 2, 100)
+			own.AccountID = "desk"
+			e.Submit(own)
+			e.Submit(newTestOrder("s2", Sell, Limit, 1, 100))
+
+			o := newTestOrder("b1", Buy, Limit, tt.size, 100)
+			o.AccountID = "desk"
+			o.SelfTradePrevention = tt.stp
+
+			got := e.Submit(o)
+			if !reflect.DeepEqual(got.Matches, tt.wantMatches) {
+				t.Errorf("Engine.Submit() matches = %v, want %v", got.Matches, tt.wantMatches)
+			}
+			if !reflect.DeepEqual(got.Prevented, tt.wantPrevented) {
+				t.Errorf("Engine.Submit() prevented = %v, want %v", got.Prevented, tt.wantPrevented)
+			}
+			if got := e.Cancel("b1"); got != tt.wantRests {
+				t.Errorf("Engine.Cancel() = %v, want %v", got, tt.wantRests)
+			}
+		})
+	}
+}
+
+func TestEngine_SubmitFillOrKillSelfTradePrevention(t *testing.T) {
+	tests := []struct {
+		name          string
+		stp           SelfTradePrevention
+		size          int64
+		wantMatches   int
+		wantPrevented int
+	}{
+		{"should fill before the order of the same account is reached", DecreaseAndCancel, 1, 1, 0},
+		{"should kill an order which would be decreased", DecreaseAndCancel, 3, 0, 0},
+		{"should kill an order which would be canceled", CancelNewest, 3, 0, 0},
+		{"should kill an order which would be canceled with the resting order", CancelBoth, 3, 0, 0},
+		{"should fill an order which cancels the resting order of the same account", CancelOldest, 3, 2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine(BtcUsd)
+			e.Submit(newTestOrder("s1", Sell, Limit, 1, 100))
+			own := newTestOrder("s2", Sell, Limit, 1, 100)
+			own.AccountID = "desk"
+			e.Submit(own)
+			e.Submit(newTestOrder("s3", Sell, Limit, 2, 100))
+
+			o := newTestOrder("b1", Buy, Limit, tt.size, 100)
+			o.AccountID = "desk"
+			o.TimeInForce = FillOrKill
+			o.SelfTradePrevention = tt.stp
+
+			got := e.Submit(o)
+			if len(got.Matches) != tt.wantMatches || len(got.Prevented) != tt.wantPrevented {
+				t.Errorf("Engine.Submit() = %v, want %v matches and %v preventions", got, tt.wantMatches, tt.wantPrevented)
+			}
+		})
+	}
+}
+
+func TestEngine_Amend(t *testing.T) {
+	e := NewEngine(BtcUsd)
+	e.Submit(newTestOrder("s1", Sell, Limit, 5, 100))
+	e.Submit(newTestOrder("s2", Sell, Limit, 5, 100))
+
+	if !e.Amend("s1", decimal.NewFromInt(2)) {
+		t.Errorf("Engine.Amend() = false, want true")
+	}
+	if e.Amend("s3", decimal.NewFromInt(2)) {
+		t.Errorf("Engine.Amend() = true, want false")
+	}
+
+	got := e.Submit(newTestOrder("b1", Buy, Limit, 3, 100)).Matches
+	want := []Match{newTestMatch("s1", "b1", 100, 2), newTestMatch("s2", "b1", 100, 1)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Engine.Submit() = %v, want %v", got, want)
+	}
+}
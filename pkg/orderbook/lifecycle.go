@@ -0,0 +1,175 @@
+package orderbook
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/altairsix/eventsource"
+)
+
+// State represents a state in the lifecycle of an order
+type State string
+
+// Transition is a command which is allowed in the From states and leads to one of the To states,
+// a transition without To states keeps the order in its state. Internal commands are issued by the exchange itself, e.g. by the matching engine, and not by clients.
+// Filled states allow the command only for orders with fills, e.g. an order canceled after a partial fill.
+type Transition struct {
+	Command  string
+	From     []State
+	Filled   []State
+	To       []State
+	Internal bool
+}
+
+// stateNone is the state of an order which was not created yet
+const stateNone State = ""
+
+// open are the states in which an order can still be amended, canceled or expire
+var open = []State{stateCreated, stateAccepted, statePending, statePublished}
+
+// lifecycle is the declarative state machine of an order, Order.Apply accepts a command only
+// in the From states of its transition
+var lifecycle = []Transition{
+	{Command: "CreateOrder", From: []State{stateNone}, To: []State{stateCreated}},
+	{Command: "AcceptOrder", From: []State{stateCreated}, To: []State{stateAccepted}},
+	{Command: "RejectOrder", From: []State{stateCreated}, To: []State{stateRejected}},
+	{Command: "ReplaceOrder", From: open},
+	{Command: "CancelOrder", From: open, To: []State{stateCanceled}},
+	{Command: "PublishOrder", From: []State{stateAccepted}, To: []State{statePublished, statePending}},
+	{Command: "ActivateOrder", From: []State{statePending}, To: []State{statePublished}, Internal: true},
+	{Command: "MatchOrder", From: []State{statePublished}, To: []State{statePublished, stateMatched}, Internal: true},
+	{Command: "ChargeFee", From: []State{statePublished, stateMatched}, Internal: true},
+	{Command: "PreventSelfTrade", From: []State{statePublished}, To: []State{statePublished, stateCanceled}, Internal: true},
+	{Command: "ApplyTimeInForce", From: []State{statePublished}, To: []State{stateCanceled}, Internal: true},
+	{Command: "ExpireOrder", From: open, To: []State{stateExpired}, Internal: true},
+	{Command: "ConfirmOrder", From: []State{stateMatched}, Filled: []State{stateCanceled, stateExpired}, To: []State{stateConfirmed}, Internal: true},
+	{Command: "ClearOrder", From: []State{stateConfirmed}, To: []State{stateCleared}},
+	{Command: "SettleOrder", From: []State{stateCleared}, To: []State{stateSettled}},
+}
+
+// Lifecycle returns all transitions of the order lifecycle
+func Lifecycle() []Transition {
+	return append([]Transition{}, lifecycle...)
+}
+
+// AllowedCommands returns the transitions which are allowed in the current state of the order
+func (o Order) AllowedCommands() []Transition {
+	allowed := []Transition{}
+	for _, t := range lifecycle {
+		if o.permits(t) {
+			allowed = append(allowed, t)
+		}
+	}
+	return allowed
+}
+
+// Allows returns true if the command is allowed in the current state of the order
+func (o Order) Allows(command string) bool {
+	for _, t := range o.AllowedCommands() {
+		if t.Command == command {
+			return true
+		}
+	}
+	return false
+}
+
+// Closed returns true if the order reached the end of its lifecycle, no command is allowed anymore
+func (o Order) Closed() bool {
+	return len(o.AllowedCommands()) == 0
+}
+
+// transition returns the transition of a command or ErrUnknownCommand
+func transition(command eventsource.Command) (Transition, error) {
+	t := reflect.TypeOf(command)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for _, tr := range lifecycle {
+		if tr.Command == t.Name() {
+			return tr, nil
+		}
+	}
+	return Transition{}, ErrUnknownCommand
+}
+
+// permits returns true if the transition is allowed in the current state of the order
+func (o Order) permits(t Transition) bool {
+	if contains(t.From, o.state) {
+		return true
+	}
+	return o.FilledSize.IsPositive() && contains(t.Filled, o.state)
+}
+
+func contains(states []State, state State) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+func (t Transition) targets(from State) []State {
+	if len(t.To) == 0 {
+		return []State{from}
+	}
+	return t.To
+}
+
+// Mermaid returns the order lifecycle as Mermaid state diagram
+func Mermaid() string {
+	var b bytes.Buffer
+	b.WriteString("stateDiagram-v2\n")
+	b.WriteString("    [*] --> created: CreateOrder\n")
+	for _, t := range lifecycle[1:] {
+		for _, from := range t.From {
+			for _, to := range t.targets(from) {
+				fmt.Fprintf(&b, "    %s --> %s: %s\n", from, to, label(t))
+			}
+		}
+		for _, from := range t.Filled {
+			for _, to := range t.targets(from) {
+				fmt.Fprintf(&b, "    %s --> %s: %s if filled\n", from, to, label(t))
+			}
+		}
+	}
+	for _, s := range []State{stateRejected, stateCanceled, stateExpired, stateSettled} {
+		fmt.Fprintf(&b, "    %s --> [*]\n", s)
+	}
+	return b.String()
+}
+
+// Graphviz returns the order lifecycle as Graphviz dot graph
+func Graphviz() string {
+	var b bytes.Buffer
+	b.WriteString("digraph order {\n")
+	b.WriteString("    start [shape=point];\n")
+	b.WriteString("    start -> created [label=\"CreateOrder\"];\n")
+	for _, t := range lifecycle[1:] {
+		style := ""
+		if t.Internal {
+			style = ", style=dashed"
+		}
+		for _, from := range t.From {
+			for _, to := range t.targets(from) {
+				fmt.Fprintf(&b, "    %s -> %s [label=\"%s\"%s];\n", from, to, t.Command, style)
+			}
+		}
+		for _, from := range t.Filled {
+			for _, to := range t.targets(from) {
+				fmt.Fprintf(&b, "    %s -> %s [label=\"%s if filled\"%s];\n", from, to, t.Command, style)
+			}
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func label(t Transition) string {
+	if t.Internal {
+		return strings.Join([]string{t.Command, "(internal)"}, " ")
+	}
+	return t.Command
+}
@@ -0,0 +1,124 @@
+package orderbook
+
+import (
+	"context"
+	"flag"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/altairsix/eventsource"
+)
+
+var update = flag.Bool("update", false, "update the lifecycle diagrams in doc/")
+
+const (
+	mermaidFile  = "../../doc/order-lifecycle.md"
+	graphvizFile = "../../doc/order-lifecycle.dot"
+)
+
+func commands(transitions []Transition) []string {
+	names := []string{}
+	for _, t := range transitions {
+		names = append(names, t.Command)
+	}
+	return names
+}
+
+func TestOrder_AllowedCommands(t *testing.T) {
+	tests := []struct {
+		name  string
+		order Order
+		want  []string
+	}{
+		{"should allow to create a new order", Order{}, []string{"CreateOrder"}},
+		{"should allow to accept, reject, amend, cancel and expire a created order", Order{state: stateCreated},
+			[]string{"AcceptOrder", "RejectOrder", "ReplaceOrder", "CancelOrder", "ExpireOrder"}},
+		{"should allow to publish, amend, cancel and expire an accepted order", Order{state: stateAccepted},
+			[]string{"ReplaceOrder", "CancelOrder", "PublishOrder", "ExpireOrder"}},
+		{"should allow to amend, cancel, activate and expire a pending order", Order{state: statePending},
+			[]string{"ReplaceOrder", "CancelOrder", "ActivateOrder", "ExpireOrder"}},
+		{"should allow to charge the fee and confirm a matched order", Order{state: stateMatched}, []string{"ChargeFee", "ConfirmOrder"}},
+		{"should allow to confirm a canceled order with fills", Order{state: stateCanceled, FilledSize: decimal.NewFromInt(1)}, []string{"ConfirmOrder"}},
+		{"should allow nothing for a canceled order without fills", Order{state: stateCanceled}, []string{}},
+		{"should allow nothing for a settled order", Order{state: stateSettled}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commands(tt.order.AllowedCommands()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Order.AllowedCommands() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrder_Closed(t *testing.T) {
+	for _, state := range []State{stateNone, stateCreated, stateAccepted, statePending, statePublished,
+		stateMatched, stateConfirmed, stateCleared} {
+		if (Order{state: state}).Closed() {
+			t.Errorf("Order{state: %q}.Closed() = true, want false", state)
+		}
+	}
+	for _, state := range []State{stateRejected, stateCanceled, stateExpired, stateSettled} {
+		if !(Order{state: state}).Closed() {
+			t.Errorf("Order{state: %q}.Closed() = false, want true", state)
+		}
+	}
+	for _, state := range []State{stateCanceled, stateExpired} {
+		if (Order{state: state, FilledSize: decimal.NewFromInt(1)}).Closed() {
+			t.Errorf("Order{state: %q} with fills.Closed() = true, want false until it is settled", state)
+		}
+	}
+}
+
+func TestOrder_ApplyRejectsCommandsOutsideLifecycle(t *testing.T) {
+	commands := []eventsource.Command{
+		&CreateOrder{}, &AcceptOrder{}, &RejectOrder{}, &ReplaceOrder{}, &CancelOrder{}, &PublishOrder{},
+		&ActivateOrder{}, &MatchOrder{}, &PreventSelfTrade{}, &ExpireOrder{},
+		&ConfirmOrder{}, &ClearOrder{}, &SettleOrder{},
+	}
+	states := []State{stateNone, stateCreated, stateAccepted, stateRejected, statePending, statePublished,
+		stateCanceled, stateExpired, stateMatched, stateConfirmed, stateCleared, stateSettled}
+
+	for _, command := range commands {
+		tr, err := transition(command)
+		if err != nil {
+			t.Fatalf("transition(%T) error = %v", command, err)
+		}
+		for _, s := range states {
+			o := Order{state: s}
+			if o.permits(tr) {
+				continue
+			}
+			if _, err := o.Apply(context.Background(), command); err != ErrInvalidStateTransition {
+				t.Errorf("Order.Apply(%T) in state %q error = %v, want %v", command, s, err, ErrInvalidStateTransition)
+			}
+		}
+	}
+}
+
+func TestLifecycle_Diagrams(t *testing.T) {
+	diagrams := []struct {
+		file string
+		want string
+	}{
+		{mermaidFile, "# Order Lifecycle\n\nGenerated from `orderbook.Lifecycle()` by `go test ./pkg/orderbook -update`, do not edit.\n" +
+			"Internal transitions, dashed in `order-lifecycle.dot`, are issued by the exchange itself.\n\n```mermaid\n" + Mermaid() + "```\n"},
+		{graphvizFile, Graphviz()},
+	}
+	for _, d := range diagrams {
+		if *update {
+			if err := ioutil.WriteFile(d.file, []byte(d.want), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		got, err := ioutil.ReadFile(d.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != d.want {
+			t.Errorf("%s is out of date, run go test ./pkg/orderbook -update", d.file)
+		}
+	}
+}
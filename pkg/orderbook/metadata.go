@@ -0,0 +1,29 @@
+package orderbook
+
+import "context"
+
+// Metadata describes who issued a command, why and as part of which request,
+// Order.Apply copies it onto the resulting events so the event log serves as audit trail
+type Metadata struct {
+	// Actor is the authenticated account, or the component of the exchange, which issued the command
+	Actor string
+	// Reason is a free text given by the actor
+	Reason string
+	// CorrelationID is the id of the request which issued the command, all commands of one request share it
+	CorrelationID string
+	// Source is the service which issued the request, e.g. riskmonitor, or the exchange itself
+	Source string
+}
+
+type metadataContextKey struct{}
+
+// NewContext returns a context carrying the metadata md
+func NewContext(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, metadataContextKey{}, md)
+}
+
+// MetadataFromContext returns the metadata carried by ctx, or empty metadata
+func MetadataFromContext(ctx context.Context) Metadata {
+	md, _ := ctx.Value(metadataContextKey{}).(Metadata)
+	return md
+}
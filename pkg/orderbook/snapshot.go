@@ -0,0 +1,55 @@
+package orderbook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+)
+
+// OrderSnapshotVersion is the version of the serialized Order state.
+// Increase it whenever the fields of Order change, existing snapshots are ignored then
+// and the Order is rebuilt from its event stream.
+const OrderSnapshotVersion = 4
+
+// orderSnapshot contains the exported and unexported fields of an Order
+type orderSnapshot struct {
+	Order
+
+	FilledValue decimal.Decimal
+	ID          string
+	Version     int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	State       State
+}
+
+// MarshalSnapshot serializes the complete state of the Order
+func (o Order) MarshalSnapshot() ([]byte, error) {
+	return json.Marshal(orderSnapshot{
+		Order:       o,
+		FilledValue: o.filledValue,
+		ID:          o.id,
+		Version:     o.version,
+		CreatedAt:   o.createdAt,
+		UpdatedAt:   o.updatedAt,
+		State:       o.state,
+	})
+}
+
+// UnmarshalSnapshot restores the complete state of the Order from a snapshot
+func (o *Order) UnmarshalSnapshot(b []byte) error {
+	var s orderSnapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	*o = s.Order
+	o.filledValue = s.FilledValue
+	o.id = s.ID
+	o.version = s.Version
+	o.createdAt = s.CreatedAt
+	o.updatedAt = s.UpdatedAt
+	o.state = s.State
+	return nil
+}
@@ -0,0 +1,40 @@
+package orderbook
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/altairsix/eventsource"
+)
+
+func TestOrder_Snapshot(t *testing.T) {
+	at := time.Date(2018, 5, 1, 12, 0, 0, 0, time.UTC)
+	o := Order{}
+	events := []eventsource.Event{
+		&OrderCreated{Size: decimal.NewFromInt(3), Price: decimal.NewFromInt(100), ProductID: BtcUsd,
+			Model: eventsource.Model{ID: "1", Version: 1, At: at}},
+		&OrderAccepted{Model: eventsource.Model{ID: "1", Version: 2, At: at}},
+		&OrderPublished{Model: eventsource.Model{ID: "1", Version: 3, At: at}},
+		&OrderPartiallyFilled{Price: decimal.NewFromInt(100), Size: decimal.NewFromInt(1),
+			Model: eventsource.Model{ID: "1", Version: 4, At: at}},
+	}
+	for _, e := range events {
+		if err := o.On(e); err != nil {
+			t.Fatalf("Order.On() error = %v", err)
+		}
+	}
+
+	b, err := o.MarshalSnapshot()
+	if err != nil {
+		t.Fatalf("Order.MarshalSnapshot() error = %v", err)
+	}
+	var got Order
+	if err := got.UnmarshalSnapshot(b); err != nil {
+		t.Fatalf("Order.UnmarshalSnapshot() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, o) {
+		t.Errorf("Order.UnmarshalSnapshot() = %+v, want %+v", got, o)
+	}
+}
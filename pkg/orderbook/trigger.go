@@ -0,0 +1,74 @@
+package orderbook
+
+import (
+	"sync"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+)
+
+// stop is a pending stop order waiting for its stop price
+type stop struct {
+	id        string
+	side      OrderSide
+	stopPrice decimal.Decimal
+}
+
+// Trigger watches the last trade prices of a single product and activates pending stop orders.
+// A buy stop is activated when the last trade price rises to or above its stop price,
+// a sell stop when the last trade price falls to or below its stop price.
+type Trigger struct {
+	productID ProductID
+
+	mux   sync.Mutex
+	stops []stop // in arrival order
+}
+
+// NewTrigger returns a trigger without pending stop orders for a product.
+func NewTrigger(productID ProductID) *Trigger {
+	return &Trigger{productID: productID}
+}
+
+// ProductID returns the product the trigger watches.
+func (t *Trigger) ProductID() ProductID {
+	return t.productID
+}
+
+// Add a pending stop order.
+func (t *Trigger) Add(o Order) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	t.stops = append(t.stops, stop{id: o.id, side: o.OrderSide, stopPrice: o.StopPrice})
+}
+
+// Remove a pending stop order and returns true if it was found.
+func (t *Trigger) Remove(id string) bool {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	for i, s := range t.stops {
+		if s.id == id {
+			t.stops = append(t.stops[:i], t.stops[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Fire removes and returns the ids of all stop orders crossed by the last trade price in arrival order.
+func (t *Trigger) Fire(lastPrice decimal.Decimal) []string {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	ids := []string{}
+	pending := t.stops[:0]
+	for _, s := range t.stops {
+		if (s.side == Buy && !lastPrice.LessThan(s.stopPrice)) || (s.side == Sell && !lastPrice.GreaterThan(s.stopPrice)) {
+			ids = append(ids, s.id)
+			continue
+		}
+		pending = append(pending, s)
+	}
+	t.stops = pending
+	return ids
+}
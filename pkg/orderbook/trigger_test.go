@@ -0,0 +1,55 @@
+package orderbook
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+)
+
+func newTestStop(id string, side OrderSide, stopPrice int64) Order {
+	return Order{id: id, OrderSide: side, OrderType: Stop, StopPrice: decimal.NewFromInt(stopPrice), ProductID: BtcUsd}
+}
+
+func TestTrigger_Fire(t *testing.T) {
+	tests := []struct {
+		name      string
+		stops     []Order
+		lastPrice int64
+		want      []string
+	}{
+		{"should not fire without stop orders", nil, 100, []string{}},
+		{"should fire a buy stop when the price rises to the stop price",
+			[]Order{newTestStop("b1", Buy, 100), newTestStop("b2", Buy, 101)}, 100, []string{"b1"}},
+		{"should fire a sell stop when the price falls to the stop price",
+			[]Order{newTestStop("s1", Sell, 90), newTestStop("s2", Sell, 100)}, 95, []string{"s2"}},
+		{"should fire in arrival order",
+			[]Order{newTestStop("s1", Sell, 90), newTestStop("s2", Sell, 100)}, 80, []string{"s1", "s2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := NewTrigger(BtcUsd)
+			for _, o := range tt.stops {
+				tr.Add(o)
+			}
+
+			if got := tr.Fire(decimal.NewFromInt(tt.lastPrice)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Trigger.Fire() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrigger_FireOnce(t *testing.T) {
+	tr := NewTrigger(BtcUsd)
+	tr.Add(newTestStop("b1", Buy, 100))
+	tr.Add(newTestStop("b2", Buy, 100))
+	tr.Remove("b2")
+
+	if got := tr.Fire(decimal.NewFromInt(100)); !reflect.DeepEqual(got, []string{"b1"}) {
+		t.Errorf("Trigger.Fire() = %v, want [b1]", got)
+	}
+	if got := tr.Fire(decimal.NewFromInt(100)); len(got) != 0 {
+		t.Errorf("Trigger.Fire() = %v, want no stop orders", got)
+	}
+}
@@ -0,0 +1,157 @@
+package orders
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/altairsix/eventsource"
+	"github.com/go-kit/kit/endpoint"
+)
+
+// APIKeyHeader is the request header which carries the API key of the caller
+const APIKeyHeader = "X-Api-Key"
+
+var (
+	// ErrUnauthenticated is returned when a request has no or an unknown API key
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrForbidden is returned when a Principal which is not an operator calls an operator endpoint
+	ErrForbidden = errors.New("forbidden")
+	// ErrInvalidAPIKeys is returned when an API key configuration has a key without account
+	ErrInvalidAPIKeys = errors.New("invalid api keys")
+)
+
+// Principal is the authenticated caller of the API
+type Principal struct {
+	AccountID string `json:"account_id"`
+	// Operator principals run the order lifecycle, e.g. the risk monitor, and act on the orders of all accounts
+	Operator bool `json:"operator"`
+}
+
+// Authenticator resolves an API key to its Principal
+type Authenticator interface {
+	Authenticate(ctx context.Context, apiKey string) (Principal, error)
+}
+
+type apiKeyAuthenticator struct {
+	keys map[string]Principal
+}
+
+// NewAPIKeyAuthenticator returns an Authenticator of a fixed set of API keys
+func NewAPIKeyAuthenticator(keys map[string]Principal) Authenticator {
+	return &apiKeyAuthenticator{keys: keys}
+}
+
+func (a *apiKeyAuthenticator) Authenticate(ctx context.Context, apiKey string) (Principal, error) {
+	p, ok := a.keys[apiKey]
+	if !ok || apiKey == "" {
+		return Principal{}, ErrUnauthenticated
+	}
+	return p, nil
+}
+
+// LoadAPIKeys reads a JSON object of API keys and their principals, e.g. from a configuration file
+func LoadAPIKeys(r io.Reader) (map[string]Principal, error) {
+	keys := map[string]Principal{}
+	if err := json.NewDecoder(r).Decode(&keys); err != nil {
+		return nil, err
+	}
+	for _, p := range keys {
+		if p.AccountID == "" {
+			return nil, ErrInvalidAPIKeys
+		}
+	}
+	return keys, nil
+}
+
+type contextKey int
+
+const (
+	apiKeyContextKey contextKey = iota
+	principalContextKey
+)
+
+// NewContext returns a context which carries the Principal
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey, p)
+}
+
+// PrincipalFromContext returns the Principal of an authenticated request
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalContextKey).(Principal)
+	return p, ok
+}
+
+// populateAPIKey moves the API key of the request into the context
+func populateAPIKey(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey, r.Header.Get(APIKeyHeader))
+}
+
+// newAuthenticationMiddleware resolves the API key of the request to its Principal
+func newAuthenticationMiddleware(a Authenticator) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			apiKey, _ := ctx.Value(apiKeyContextKey).(string)
+			p, err := a.Authenticate(ctx, apiKey)
+			if err != nil {
+				return nil, err
+			}
+			return next(withActor(NewContext(ctx, p), p), request)
+		}
+	}
+}
+
+// OperatorsOnly returns a middleware which lets only operators pass, e.g. to the admin endpoints of other services.
+// Requests without or with an unknown API key are unauthorized, those of accounts are forbidden.
+func OperatorsOnly(a Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, err := a.Authenticate(r.Context(), r.Header.Get(APIKeyHeader))
+			if err == nil && !p.Operator {
+				err = ErrForbidden
+			}
+			if err != nil {
+				encodeError(r.Context(), err, w)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), p)))
+		})
+	}
+}
+
+// orderRequest is a request on an existing order
+type orderRequest interface {
+	orderID() string
+}
+
+// newOwnershipMiddleware lets a Principal act only on the orders of its account, operators act on all orders
+// and operator only endpoints are forbidden for everybody else.
+// Orders of other accounts are reported as not found, so their ids can not be probed.
+func newOwnershipMiddleware(s Service, operatorOnly bool) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			p, ok := PrincipalFromContext(ctx)
+			if !ok {
+				return nil, ErrUnauthenticated
+			}
+			if operatorOnly && !p.Operator {
+				return nil, ErrForbidden
+			}
+			r, ok := request.(orderRequest)
+			if !ok || p.Operator {
+				return next(ctx, request)
+			}
+
+			o, err := s.GetOrder(ctx, r.orderID())
+			if err != nil {
+				return nil, err
+			}
+			if o.AccountID != p.AccountID {
+				return nil, eventsource.NewError(nil, eventsource.ErrAggregateNotFound, "unable to load order, %v", r.orderID())
+			}
+			return next(ctx, request)
+		}
+	}
+}
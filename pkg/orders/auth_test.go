@@ -0,0 +1,94 @@
+package orders
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	kitlog "github.com/go-kit/kit/log"
+)
+
+func TestMakeHandler_authorization(t *testing.T) {
+	s := newTestService(Dependencies{})
+	a := NewAPIKeyAuthenticator(map[string]Principal{
+		"alice-key": {AccountID: "alice"},
+		"bob-key":   {AccountID: "bob"},
+		"risk-key":  {AccountID: "risk", Operator: true},
+	})
+	h := MakeHandler(s, a, kitlog.NewNopLogger())
+
+	spec := newLimitOrder(orderbook.Sell, 1, 100)
+	spec.AccountID = "alice"
+	id, _ := s.CreateOrder(context.Background(), spec)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		apiKey string
+		want   int
+	}{
+		{"should reject a request without API key", "GET", "/godax/v1/orders/" + id, "", "", http.StatusUnauthorized},
+		{"should reject an unknown API key", "GET", "/godax/v1/orders/" + id, "", "mallory-key", http.StatusUnauthorized},
+		{"should return the order of the own account", "GET", "/godax/v1/orders/" + id, "", "alice-key", http.StatusOK},
+		{"should not find the order of another account", "GET", "/godax/v1/orders/" + id, "", "bob-key", http.StatusNotFound},
+		{"should not cancel the order of another account", "DELETE", "/godax/v1/orders/" + id, "", "bob-key", http.StatusNotFound},
+		{"should forbid an account to accept its own order", "PUT", "/godax/v1/orders/" + id + "/accept", "", "alice-key", http.StatusForbidden},
+		{"should let an operator accept any order", "PUT", "/godax/v1/orders/" + id + "/accept", "", "risk-key", http.StatusOK},
+		{"should create an order for the account of the caller", "POST", "/godax/v1/orders",
+			`{"size": "1", "price": "100", "type": "limit", "side": "buy", "product_id": "BTC-USD"}`, "bob-key", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			r.Header.Set(APIKeyHeader, tt.apiKey)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("MakeHandler() status = %v, want %v: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+
+	o, _ := s.GetOrder(context.Background(), "2")
+	if o.AccountID != "bob" || o.OrderSide != orderbook.Buy {
+		t.Errorf("service.GetOrder() = %+v, want the order created for bob", o)
+	}
+}
+
+func TestOperatorsOnly(t *testing.T) {
+	a := NewAPIKeyAuthenticator(map[string]Principal{
+		"alice-key": {AccountID: "alice"},
+		"risk-key":  {AccountID: "risk", Operator: true},
+	})
+	h := OperatorsOnly(a)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p, _ := PrincipalFromContext(r.Context()); !p.Operator {
+			t.Errorf("PrincipalFromContext() = %+v, want the operator", p)
+		}
+	}))
+
+	tests := []struct {
+		name   string
+		apiKey string
+		want   int
+	}{
+		{"should reject a request without API key", "", http.StatusUnauthorized},
+		{"should forbid accounts", "alice-key", http.StatusForbidden},
+		{"should let operators pass", "risk-key", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PUT", "/godax/v1/products/BTC-USD", strings.NewReader(`{"id": "BTC-USD"}`))
+			r.Header.Set(APIKeyHeader, tt.apiKey)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("OperatorsOnly() status = %v, want %v: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package orders
+
+import (
+	"sync"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/altairsix/eventsource"
+)
+
+// BookProjection maintains the book of every product from the order events of the Repository
+type BookProjection interface {
+	// On applies an order event, it is registered as observer of the Repository
+	On(event eventsource.Event)
+	// Snapshot returns the book of a product at level 1 (best bid and ask), 2 (top depth price levels) or 3 (all orders)
+	Snapshot(productID orderbook.ProductID, level, depth int) orderbook.BookSnapshot
+}
+
+// bookOrder is what the projection needs to know about an order to place it on the book
+type bookOrder struct {
+	productID orderbook.ProductID
+	side      orderbook.OrderSide
+	orderType orderbook.OrderType
+	price     decimal.Decimal
+	remaining decimal.Decimal
+	resting   bool
+}
+
+// bookProjection keeps the books in memory, the events applied before it was created are replayed by Restore
+type bookProjection struct {
+	mux    sync.Mutex
+	books  map[orderbook.ProductID]*orderbook.Book
+	orders map[string]*bookOrder
+}
+
+// NewBookProjection returns an empty in-memory BookProjection
+func NewBookProjection() BookProjection {
+	return &bookProjection{
+		books:  map[orderbook.ProductID]*orderbook.Book{},
+		orders: map[string]*bookOrder{},
+	}
+}
+
+// On places published limit orders on the book and keeps their remaining size up to date.
+// Orders are on the book from their publication, an incoming order is listed until the fills of its matching are applied.
+func (p *bookProjection) On(event eventsource.Event) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	id := event.AggregateID()
+	if v, ok := event.(*orderbook.OrderCreated); ok {
+		p.orders[id] = &bookOrder{productID: v.ProductID, side: v.OrderSide, orderType: v.OrderType, price: v.Price, remaining: v.Size}
+		return
+	}
+
+	o, ok := p.orders[id]
+	if !ok {
+		return
+	}
+	book := p.book(o.productID)
+
+	switch v := event.(type) {
+	case *orderbook.OrderPublished:
+		if !o.orderType.IsStop() {
+			p.rest(book, id, o)
+		}
+	case *orderbook.OrderActivated:
+		p.rest(book, id, o)
+	case *orderbook.OrderAmended:
+		o.price, o.remaining = v.Price, v.RemainingSize
+		if o.resting {
+			book.Amend(id, o.side, v.Price, v.RemainingSize, v.PriorityRetained)
+		}
+	case *orderbook.OrderMatched:
+		p.reduce(book, id, o, v.Size)
+	case *orderbook.OrderPartiallyFilled:
+		p.reduce(book, id, o, v.Size)
+	case *orderbook.OrderFilled:
+		p.reduce(book, id, o, v.Size)
+	case *orderbook.OrderSelfTradePrevented:
+		p.reduce(book, id, o, v.Size)
+	case *orderbook.OrderCanceled, *orderbook.OrderExpired, *orderbook.OrderRejected:
+		if o.resting {
+			book.Remove(id, o.side)
+		}
+		delete(p.orders, id)
+	}
+}
+
+func (p *bookProjection) Snapshot(productID orderbook.ProductID, level, depth int) orderbook.BookSnapshot {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	book := p.book(productID)
+	switch level {
+	case 1:
+		return book.Level1()
+	case 2:
+		return book.Level2(depth)
+	default:
+		return book.Level3()
+	}
+}
+
+// rest adds the remaining size of limit orders to the book, market orders never rest
+func (p *bookProjection) rest(book *orderbook.Book, id string, o *bookOrder) {
+	if !o.orderType.IsLimit() || !o.remaining.IsPositive() {
+		return
+	}
+	book.Add(id, o.side, o.price, o.remaining)
+	o.resting = true
+}
+
+func (p *bookProjection) reduce(book *orderbook.Book, id string, o *bookOrder, size decimal.Decimal) {
+	o.remaining = o.remaining.Sub(size)
+	if o.resting {
+		book.Reduce(id, o.side, size)
+	}
+	if !o.remaining.IsPositive() {
+		delete(p.orders, id)
+	}
+}
+
+func (p *bookProjection) book(productID orderbook.ProductID) *orderbook.Book {
+	book, ok := p.books[productID]
+	if !ok {
+		book = orderbook.NewBook(productID)
+		p.books[productID] = book
+	}
+	return book
+}
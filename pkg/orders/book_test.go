@@ -0,0 +1,80 @@
+package orders
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/gdax"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	kitlog "github.com/go-kit/kit/log"
+)
+
+func TestMakeHandler_book(t *testing.T) {
+	ctx := context.Background()
+	books := NewBookProjection()
+	s := newTestService(Dependencies{Repository: newInMemRepository(books.On), Books: books})
+	h := MakeHandler(s, NewAPIKeyAuthenticator(map[string]Principal{}), kitlog.NewNopLogger())
+
+	place := func(spec OrderSpec) string {
+		id, _ := s.CreateOrder(ctx, spec)
+		s.AcceptOrder(ctx, id)
+		s.PublishOrder(ctx, id)
+		return id
+	}
+	place(newLimitOrder(orderbook.Sell, 2, 101))             // 1
+	place(newLimitOrder(orderbook.Sell, 1, 102))             // 2
+	place(newLimitOrder(orderbook.Buy, 3, 99))               // 3
+	place(newLimitOrder(orderbook.Buy, 1, 100))              // 4
+	place(newLimitOrder(orderbook.Buy, 2, 100))              // 5
+	place(newLimitOrder(orderbook.Buy, 1, 101))              // 6, fills 1 of order 1
+	canceled := place(newLimitOrder(orderbook.Sell, 1, 103)) // 7
+	s.CancelOrder(ctx, canceled)
+	place(OrderSpec{Size: decimal.NewFromInt(1), OrderType: orderbook.Market, OrderSide: orderbook.Buy,
+		ProductID: orderbook.BtcUsd}) // 8, fills the rest of order 1 and never rests
+
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+		want     gdax.BookSnapshot
+	}{
+		{"should return the best bid and ask by default", "/godax/v1/books/BTC-USD", http.StatusOK,
+			gdax.BookSnapshot{Bids: [][]string{{"100", "3", "2"}}, Asks: [][]string{{"102", "1", "1"}}}},
+		{"should aggregate the top price levels at level 2", "/godax/v1/books/BTC-USD?level=2&depth=2", http.StatusOK,
+			gdax.BookSnapshot{Bids: [][]string{{"100", "3", "2"}, {"99", "3", "1"}}, Asks: [][]string{{"102", "1", "1"}}}},
+		{"should return every resting order at level 3", "/godax/v1/books/BTC-USD?level=3", http.StatusOK,
+			gdax.BookSnapshot{Bids: [][]string{{"100", "1", "4"}, {"100", "2", "5"}, {"99", "3", "3"}}, Asks: [][]string{{"102", "1", "2"}}}},
+		{"should reject an unknown level", "/godax/v1/books/BTC-USD?level=4", http.StatusBadRequest, gdax.BookSnapshot{}},
+		{"should reject a depth outside of level 2", "/godax/v1/books/BTC-USD?level=3&depth=2", http.StatusBadRequest, gdax.BookSnapshot{}},
+		{"should reject an unknown product", "/godax/v1/books/XYZ-USD", http.StatusBadRequest, gdax.BookSnapshot{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+			if w.Code != tt.wantCode {
+				t.Fatalf("MakeHandler() status = %v, want %v: %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var got gdax.BookSnapshot
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Sequence == 0 {
+				t.Errorf("BookSnapshot.Sequence = 0, want the sequence of the last change")
+			}
+			got.Sequence = 0
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BookSnapshot = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package orders
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/LAtanassov/godax/pkg/accessor"
+	"github.com/LAtanassov/godax/pkg/clearing"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/LAtanassov/godax/pkg/products"
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/mysqlstore"
+	"github.com/go-kit/kit/log"
+)
+
+// clearingSerializer binds the clearing events with their current schema version, see serializer
+var clearingSerializer = newSchemaSerializer().
+	Bind(1,
+		clearing.CycleCleared{},
+	)
+
+// clearingMetadata names the clearing house as actor of the ClearOrder commands of a cycle
+var clearingMetadata = orderbook.Metadata{Actor: "clearing-house", Source: ServiceName}
+
+// Clearing returns the cleared cycles and their net instructions
+type Clearing interface {
+	// Cycle returns a cleared cycle
+	Cycle(ctx context.Context, id string) (clearing.Cycle, error)
+	// Cycles returns the cleared cycles, newest first
+	Cycles(ctx context.Context) ([]clearing.Cycle, error)
+}
+
+// ClearingHouse collects the orders confirmed within a window, nets their obligations per account
+// and currency into a clearing cycle and issues a ClearOrder command for each order of the cycle.
+// Cycles are kept in their own event store and the list of cycles is rebuilt from it at startup,
+// the confirmed orders without a cycle are collected again when the order events are replayed by Restore.
+type ClearingHouse struct {
+	repository  *eventsource.Repository
+	catalog     products.Catalog
+	idGenerator Generator
+	logger      log.Logger
+
+	mux         sync.Mutex
+	pending     []string
+	windowStart time.Time
+	cycles      []string
+	cycled      map[string]bool
+}
+
+// NewClearingHouse returns a ClearingHouse depending on driver, On has to observe the order events
+// and Run has to be called to clear cycles
+func NewClearingHouse(dbDriver, dbURL, tableName string, catalog products.Catalog, logger log.Logger) (*ClearingHouse, error) {
+	switch dbDriver {
+	case inmem:
+		return newClearingHouse(newMemoryStore(), catalog, logger), nil
+	case mysql:
+		a, err := accessor.New(dbDriver, dbURL, tableName)
+		if err != nil {
+			return nil, err
+		}
+		store, err := mysqlstore.New(accessor.ClearingTableName(tableName), a)
+		if err != nil {
+			return nil, err
+		}
+		c := newClearingHouse(store, catalog, logger)
+		if err := c.rebuild(context.Background()); err != nil {
+			return nil, err
+		}
+		return c, nil
+	default:
+		return nil, ErrUnsupportedDriver
+	}
+}
+
+func newClearingHouse(store eventsource.Store, catalog products.Catalog, logger log.Logger) *ClearingHouse {
+	c := &ClearingHouse{
+		catalog:     catalog,
+		idGenerator: NewIDGenerator(),
+		logger:      logger,
+		windowStart: time.Now(),
+		cycled:      map[string]bool{},
+	}
+	c.repository = eventsource.New(&clearing.Cycle{},
+		eventsource.WithStore(store),
+		eventsource.WithSerializer(clearingSerializer),
+		eventsource.WithObservers(c.onCycle),
+	)
+	return c
+}
+
+// rebuild lists the cycles cleared before startup
+func (c *ClearingHouse) rebuild(ctx context.Context) error {
+	return replayStore(ctx, c.repository.Store(), clearingSerializer, c.onCycle)
+}
+
+// onCycle lists a cleared cycle and remembers its orders, a replayed cycle is listed once
+func (c *ClearingHouse) onCycle(event eventsource.Event) {
+	v, ok := event.(*clearing.CycleCleared)
+	if !ok {
+		return
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	for _, id := range c.cycles {
+		if id == v.AggregateID() {
+			return
+		}
+	}
+	c.cycles = append(c.cycles, v.AggregateID())
+	for _, id := range v.OrderIDs {
+		c.cycled[id] = true
+	}
+}
+
+// On collects the confirmed orders for the next cycle, orders which were netted in a cycle before are ignored
+// so the order events can be replayed
+func (c *ClearingHouse) On(event eventsource.Event) {
+	if _, ok := event.(*orderbook.OrderConfirmed); !ok {
+		return
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if c.cycled[event.AggregateID()] {
+		return
+	}
+	c.pending = append(c.pending, event.AggregateID())
+}
+
+// Run clears a cycle every window until the context is done
+func (c *ClearingHouse) Run(ctx context.Context, s Service, window time.Duration) {
+	t := time.NewTicker(window)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			if _, err := c.Clear(ctx, s, now); err != nil {
+				c.logger.Log("method", "Clear", "err", err)
+			}
+		}
+	}
+}
+
+// Clear nets the orders confirmed since the last cycle, persists the cycle and clears its orders through the Service.
+// It returns the id of the cycle, an empty id if no order was confirmed. If the cycle can not be persisted
+// its orders are cleared with the next cycle, orders which can not be cleared are logged and stay confirmed.
+func (c *ClearingHouse) Clear(ctx context.Context, s Service, now time.Time) (string, error) {
+	ids, windowStart := c.take(now)
+	if len(ids) == 0 {
+		return "", nil
+	}
+
+	obligations := []clearing.Instruction{}
+	for _, id := range ids {
+		o, err := s.GetOrder(ctx, id)
+		if err != nil {
+			c.requeue(ids, windowStart)
+			return "", err
+		}
+		product, err := c.catalog.Get(o.ProductID)
+		if err != nil {
+			c.requeue(ids, windowStart)
+			return "", err
+		}
+		obligations = append(obligations, clearing.Obligations(o.AccountID, o.OrderSide,
+			product.BaseCurrency, product.QuoteCurrency, o.FilledSize, o.FilledValue(), o.FillFees)...)
+	}
+
+	ctx = orderbook.NewContext(ctx, clearingMetadata)
+	id := c.idGenerator.Generate()
+	clearCycle := &clearing.ClearCycle{
+		OrderIDs:     ids,
+		Obligations:  obligations,
+		WindowStart:  windowStart,
+		WindowEnd:    now,
+		Metadata:     clearingMetadata,
+		CommandModel: eventsource.CommandModel{ID: id},
+	}
+	if _, err := c.repository.Apply(ctx, clearCycle); err != nil {
+		c.requeue(ids, windowStart)
+		return "", err
+	}
+
+	for _, orderID := range ids {
+		if err := s.ClearOrder(ctx, orderID); err != nil {
+			c.logger.Log("method", "Clear", "cycle", id, "id", orderID, "err", err)
+		}
+	}
+	return id, nil
+}
+
+// take removes the pending orders and starts the next window
+func (c *ClearingHouse) take(now time.Time) ([]string, time.Time) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	ids, windowStart := c.pending, c.windowStart
+	c.pending, c.windowStart = nil, now
+	return ids, windowStart
+}
+
+// requeue puts the orders of a failed cycle back in front of the pending orders and restores the start of their window
+func (c *ClearingHouse) requeue(ids []string, windowStart time.Time) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.pending, c.windowStart = append(ids, c.pending...), windowStart
+}
+
+// Cycle loads a cleared cycle
+func (c *ClearingHouse) Cycle(ctx context.Context, id string) (clearing.Cycle, error) {
+	v, err := c.repository.Load(ctx, id)
+	if err != nil {
+		return clearing.Cycle{}, err
+	}
+	cycle, ok := v.(*clearing.Cycle)
+	if !ok {
+		return clearing.Cycle{}, ErrTypeCast
+	}
+	return *cycle, nil
+}
+
+// Cycles loads the cleared cycles, newest first
+func (c *ClearingHouse) Cycles(ctx context.Context) ([]clearing.Cycle, error) {
+	c.mux.Lock()
+	ids := append([]string{}, c.cycles...)
+	c.mux.Unlock()
+
+	cycles := []clearing.Cycle{}
+	for i := len(ids) - 1; i >= 0; i-- {
+		cycle, err := c.Cycle(ctx, ids[i])
+		if err != nil {
+			return nil, err
+		}
+		cycles = append(cycles, cycle)
+	}
+	return cycles, nil
+}
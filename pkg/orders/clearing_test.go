@@ -0,0 +1,232 @@
+package orders
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/ledger"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/altairsix/eventsource"
+	kitlog "github.com/go-kit/kit/log"
+)
+
+func TestClearingHouse_Clear(t *testing.T) {
+	ctx := context.Background()
+	c := newClearingHouse(newMemoryStore(), testCatalog, kitlog.NewNopLogger())
+	s := newTestService(Dependencies{Repository: newInMemRepository(c.On), Clearing: c})
+	h := MakeHandler(s, NewAPIKeyAuthenticator(map[string]Principal{"risk-key": {AccountID: "risk", Operator: true}}), kitlog.NewNopLogger())
+
+	place := func(account string, spec OrderSpec) string {
+		spec.AccountID = account
+		id, _ := s.CreateOrder(ctx, spec)
+		s.AcceptOrder(ctx, id)
+		s.PublishOrder(ctx, id)
+		return id
+	}
+	maker := place("alice", newLimitOrder(orderbook.Sell, 2, 100))
+	taker := place("bob", newLimitOrder(orderbook.Buy, 2, 100))
+	s.ConfirmOrder(ctx, maker)
+
+	t.Run("should not clear an order before the cycle", func(t *testing.T) {
+		o, _ := s.GetOrder(ctx, maker)
+		if o.State() == "cleared" {
+			t.Errorf("service.GetOrder() state = %v before the cycle", o.State())
+		}
+	})
+
+	var first string
+	t.Run("should net the confirmed orders and clear them", func(t *testing.T) {
+		id, err := c.Clear(ctx, s, time.Now())
+		if err != nil || id == "" {
+			t.Fatalf("ClearingHouse.Clear() = %v, %v", id, err)
+		}
+		first = id
+		o, _ := s.GetOrder(ctx, maker)
+		if o.State() != "cleared" {
+			t.Errorf("service.GetOrder() state = %v, want the order cleared", o.State())
+		}
+		if o, _ := s.GetOrder(ctx, taker); o.State() == "cleared" {
+			t.Errorf("service.GetOrder() state = %v, want the unconfirmed order not cleared", o.State())
+		}
+
+		cycle, err := s.GetClearingCycle(ctx, id)
+		if err != nil {
+			t.Fatalf("service.GetClearingCycle() error = %v", err)
+		}
+		// the buyer is cleared in a later cycle, until then the clearing account takes the other side
+		want := map[string]decimal.Decimal{
+			"alice/BTC":                     decimal.NewFromInt(-2),
+			ledger.ClearingAccount + "/BTC": decimal.NewFromInt(2),
+		}
+		found := 0
+		for _, i := range cycle.Instructions {
+			if w, ok := want[i.Account+"/"+i.Currency]; ok {
+				found++
+				if !i.Amount.Equal(w) {
+					t.Errorf("service.GetClearingCycle() %v %v = %v, want %v", i.Account, i.Currency, i.Amount, w)
+				}
+			}
+		}
+		if found != len(want) {
+			t.Errorf("service.GetClearingCycle() = %+v, want instructions %v", cycle.Instructions, want)
+		}
+	})
+
+	t.Run("should skip a window without confirmed orders", func(t *testing.T) {
+		if id, err := c.Clear(ctx, s, time.Now()); id != "" || err != nil {
+			t.Errorf("ClearingHouse.Clear() = %v, %v, want no cycle", id, err)
+		}
+	})
+
+	t.Run("should return the instructions of an account to operators", func(t *testing.T) {
+		s.ConfirmOrder(ctx, taker)
+		if _, err := c.Clear(ctx, s, time.Now()); err != nil {
+			t.Fatalf("ClearingHouse.Clear() error = %v", err)
+		}
+
+		r := httptest.NewRequest("GET", "/godax/v1/clearing/cycles?account_id=bob", nil)
+		r.Header.Set(APIKeyHeader, "risk-key")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("MakeHandler() status = %v: %s", w.Code, w.Body.String())
+		}
+		var got getClearingCyclesResponse
+		json.NewDecoder(w.Body).Decode(&got)
+		if len(got.Cycles) != 2 || got.Cycles[1].ID != first || len(got.Cycles[1].Instructions) != 0 {
+			t.Fatalf("MakeHandler() = %+v, want the newest cycle first and no instruction of bob in %v", got, first)
+		}
+		for _, i := range got.Cycles[0].Instructions {
+			if i.Account != "bob" {
+				t.Errorf("MakeHandler() instruction = %+v, want only bob", i)
+			}
+		}
+	})
+}
+
+func TestClearingHouse_Clear_failures(t *testing.T) {
+	ctx := context.Background()
+	store := &unavailableStore{Store: newMemoryStore()}
+	c := newClearingHouse(store, testCatalog, kitlog.NewNopLogger())
+	s := newTestService(Dependencies{Repository: newInMemRepository(c.On), Clearing: c})
+
+	place := func(account string, spec OrderSpec) string {
+		spec.AccountID = account
+		id, _ := s.CreateOrder(ctx, spec)
+		s.AcceptOrder(ctx, id)
+		s.PublishOrder(ctx, id)
+		return id
+	}
+	maker := place("alice", newLimitOrder(orderbook.Sell, 2, 100))
+	taker := place("bob", newLimitOrder(orderbook.Buy, 1, 100))
+	if err := s.CancelOrder(ctx, maker); err != nil {
+		t.Fatalf("service.CancelOrder() error = %v", err)
+	}
+	for _, id := range []string{maker, taker} {
+		if err := s.ConfirmOrder(ctx, id); err != nil {
+			t.Fatalf("service.ConfirmOrder() error = %v", err)
+		}
+	}
+
+	t.Run("should clear the orders of a failed cycle with the next cycle", func(t *testing.T) {
+		store.unavailable = true
+		if _, err := c.Clear(ctx, s, time.Now()); err != errStoreUnavailable {
+			t.Errorf("ClearingHouse.Clear() error = %v, want %v", err, errStoreUnavailable)
+		}
+		store.unavailable = false
+		id, err := c.Clear(ctx, s, time.Now())
+		if err != nil || id == "" {
+			t.Fatalf("ClearingHouse.Clear() = %v, %v, want the requeued orders cleared", id, err)
+		}
+	})
+
+	t.Run("should balance the clearing account with an order canceled after a partial fill", func(t *testing.T) {
+		cycles, _ := c.Cycles(ctx)
+		if len(cycles) != 1 || len(cycles[0].OrderIDs) != 2 {
+			t.Fatalf("ClearingHouse.Cycles() = %+v, want one cycle of both orders", cycles)
+		}
+		for _, i := range cycles[0].Instructions {
+			if i.Account == ledger.ClearingAccount && !i.Amount.IsZero() {
+				t.Errorf("ClearingHouse.Cycles() instruction = %+v, want the clearing account balanced", i)
+			}
+		}
+		for _, id := range []string{maker, taker} {
+			if o, _ := s.GetOrder(ctx, id); o.State() != "cleared" {
+				t.Errorf("service.GetOrder() state = %v, want cleared", o.State())
+			}
+		}
+	})
+
+	t.Run("should rebuild the cycles from the store at startup", func(t *testing.T) {
+		restarted := newClearingHouse(store, testCatalog, kitlog.NewNopLogger())
+		if err := restarted.rebuild(ctx); err != nil {
+			t.Fatalf("ClearingHouse.rebuild() error = %v", err)
+		}
+		want, _ := c.Cycles(ctx)
+		got, err := restarted.Cycles(ctx)
+		if err != nil || len(got) != len(want) || got[0].ID() != want[0].ID() {
+			t.Errorf("ClearingHouse.Cycles() = %+v, %v, want %+v", got, err, want)
+		}
+
+		// the replayed confirmation of an order of a cycle is not cleared again
+		restarted.On(&orderbook.OrderConfirmed{Model: eventsource.Model{ID: maker, Version: 1}})
+		if id, err := restarted.Clear(ctx, s, time.Now()); id != "" || err != nil {
+			t.Errorf("ClearingHouse.Clear() = %v, %v, want no cycle", id, err)
+		}
+	})
+}
+
+func TestClearingHouse_restart(t *testing.T) {
+	ctx := context.Background()
+	orders, cycles := newMemoryStore(), newMemoryStore()
+	idg := &sequenceIDGenerator{}
+
+	// start returns a service and a clearing house on the stores, restored like after a restart
+	start := func(t *testing.T) (Service, *ClearingHouse) {
+		c := newClearingHouse(cycles, testCatalog, kitlog.NewNopLogger())
+		if err := c.rebuild(ctx); err != nil {
+			t.Fatalf("ClearingHouse.rebuild() error = %v", err)
+		}
+		d := Dependencies{IDGenerator: idg, Repository: newRepository(orders, NewMemoryDedupeIndex(), c.On),
+			Matcher: NewMatcher(), Trades: NewTradeHistory(), Markets: newInMemMarkets(), Clearing: c}
+		if err := Restore(ctx, d); err != nil {
+			t.Fatalf("Restore() error = %v", err)
+		}
+		return newTestService(d), c
+	}
+
+	s, c := start(t)
+	place := func(account string, spec OrderSpec) string {
+		spec.AccountID = account
+		id, _ := s.CreateOrder(ctx, spec)
+		s.AcceptOrder(ctx, id)
+		s.PublishOrder(ctx, id)
+		return id
+	}
+	maker := place("alice", newLimitOrder(orderbook.Sell, 1, 100))
+	taker := place("bob", newLimitOrder(orderbook.Buy, 1, 100))
+	s.ConfirmOrder(ctx, maker)
+	if _, err := c.Clear(ctx, s, time.Now()); err != nil {
+		t.Fatalf("ClearingHouse.Clear() error = %v", err)
+	}
+	s.ConfirmOrder(ctx, taker)
+
+	restarted, rc := start(t)
+	id, err := rc.Clear(ctx, restarted, time.Now())
+	if err != nil {
+		t.Fatalf("ClearingHouse.Clear() error = %v", err)
+	}
+	cycle, _ := rc.Cycle(ctx, id)
+	if !reflect.DeepEqual(cycle.OrderIDs, []string{taker}) {
+		t.Errorf("ClearingHouse.Clear() orders = %v, want the confirmed order without a cycle %v only", cycle.OrderIDs, taker)
+	}
+	if got, _ := rc.Cycles(ctx); len(got) != 2 {
+		t.Errorf("ClearingHouse.Cycles() = %+v, want both cycles", got)
+	}
+}
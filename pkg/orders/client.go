@@ -6,22 +6,24 @@ import (
 	"net/http"
 	"net/url"
 
+	"github.com/LAtanassov/godax/pkg/orderbook"
 	"github.com/LAtanassov/godax/pkg/rest"
 )
 
 // Client apply action on orders api
 type Client interface {
 	AcceptOrder(ctx context.Context, id string) error
-	RejectOrder(ctx context.Context, id string) error
+	RejectOrder(ctx context.Context, id string, reason orderbook.RejectReason, message string) error
 }
 
 type client struct {
 	restClient rest.Client
+	apiKey     string
 }
 
-// NewClient return an orders api client
-func NewClient(h *http.Client, u *url.URL) Client {
-	return &client{restClient: rest.NewClient(h, u)}
+// NewClient return an orders api client which authenticates with the API key of an operator
+func NewClient(h *http.Client, u *url.URL, apiKey string) Client {
+	return &client{restClient: rest.NewClient(h, u), apiKey: apiKey}
 }
 
 func (c *client) AcceptOrder(ctx context.Context, id string) error {
@@ -30,16 +32,21 @@ func (c *client) AcceptOrder(ctx context.Context, id string) error {
 	if err != nil {
 		return err
 	}
+	r.Header.Set(APIKeyHeader, c.apiKey)
+	setMetadataHeaders(ctx, r)
 	_, err = c.restClient.Do(r, nil)
 	return err
 }
 
-func (c *client) RejectOrder(ctx context.Context, id string) error {
+func (c *client) RejectOrder(ctx context.Context, id string, reason orderbook.RejectReason, message string) error {
 	p := fmt.Sprintf("/godax/v1/orders/%s/reject", id)
-	r, err := c.restClient.NewRequest("PUT", &url.URL{Path: p}, nil)
+	body := map[string]string{"reason": string(reason), "message": message}
+	r, err := c.restClient.NewRequest("PUT", &url.URL{Path: p}, body)
 	if err != nil {
 		return err
 	}
+	r.Header.Set(APIKeyHeader, c.apiKey)
+	setMetadataHeaders(ctx, r)
 	_, err = c.restClient.Do(r, nil)
 	return err
 }
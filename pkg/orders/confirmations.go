@@ -0,0 +1,78 @@
+package orders
+
+import (
+	"context"
+
+	"github.com/LAtanassov/godax/pkg/accessor"
+	"github.com/LAtanassov/godax/pkg/confirmation"
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/mysqlstore"
+)
+
+// confirmationSerializer binds the confirmation events with their current schema version, see serializer
+var confirmationSerializer = newSchemaSerializer().
+	Bind(1,
+		confirmation.ConfirmationOpened{},
+		confirmation.PartyConfirmed{},
+		confirmation.TradeDisputed{},
+		confirmation.DisputeResolved{},
+		confirmation.TradeConfirmed{},
+	)
+
+// Confirmations keeps the two-party confirmation of every trade, the id of a confirmation is the trade id
+type Confirmations interface {
+	// Apply executes a command of the confirmation package
+	Apply(ctx context.Context, command eventsource.Command) error
+	// Get returns the confirmation of a trade
+	Get(ctx context.Context, tradeID string) (confirmation.Confirmation, error)
+}
+
+// eventConfirmations keeps the confirmations as aggregates in their own event store
+type eventConfirmations struct {
+	repository *eventsource.Repository
+}
+
+// NewConfirmations returns Confirmations depending on driver
+func NewConfirmations(dbDriver, dbURL, tableName string) (Confirmations, error) {
+	switch dbDriver {
+	case inmem:
+		return newInMemConfirmations(), nil
+	case mysql:
+		a, err := accessor.New(dbDriver, dbURL, tableName)
+		if err != nil {
+			return nil, err
+		}
+		store, err := mysqlstore.New(accessor.ConfirmationsTableName(tableName), a)
+		if err != nil {
+			return nil, err
+		}
+		return &eventConfirmations{
+			repository: eventsource.New(&confirmation.Confirmation{}, eventsource.WithStore(store), eventsource.WithSerializer(confirmationSerializer)),
+		}, nil
+	default:
+		return nil, ErrUnsupportedDriver
+	}
+}
+
+func newInMemConfirmations() Confirmations {
+	return &eventConfirmations{
+		repository: eventsource.New(&confirmation.Confirmation{}, eventsource.WithStore(newMemoryStore()), eventsource.WithSerializer(confirmationSerializer)),
+	}
+}
+
+func (c *eventConfirmations) Apply(ctx context.Context, command eventsource.Command) error {
+	_, err := c.repository.Apply(ctx, command)
+	return err
+}
+
+func (c *eventConfirmations) Get(ctx context.Context, tradeID string) (confirmation.Confirmation, error) {
+	v, err := c.repository.Load(ctx, tradeID)
+	if err != nil {
+		return confirmation.Confirmation{}, err
+	}
+	cf, ok := v.(*confirmation.Confirmation)
+	if !ok {
+		return confirmation.Confirmation{}, ErrTypeCast
+	}
+	return *cf, nil
+}
@@ -0,0 +1,148 @@
+package orders
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/LAtanassov/godax/pkg/confirmation"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	kitlog "github.com/go-kit/kit/log"
+)
+
+func TestMakeHandler_confirmations(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(Dependencies{})
+	h := MakeHandler(s, NewAPIKeyAuthenticator(map[string]Principal{
+		"alice-key": {AccountID: "alice"},
+		"bob-key":   {AccountID: "bob"},
+		"carol-key": {AccountID: "carol"},
+		"risk-key":  {AccountID: "risk", Operator: true},
+	}), kitlog.NewNopLogger())
+
+	place := func(account string, spec OrderSpec) string {
+		spec.AccountID = account
+		id, _ := s.CreateOrder(ctx, spec)
+		s.AcceptOrder(ctx, id)
+		s.PublishOrder(ctx, id)
+		return id
+	}
+	trade := func(size, price int64) (string, string, string) {
+		maker := place("alice", newLimitOrder(orderbook.Sell, size, price))
+		taker := place("bob", newLimitOrder(orderbook.Buy, size, price))
+		p, err := s.GetFills(ctx, taker, Page{})
+		if err != nil || len(p.Trades) != 1 {
+			t.Fatalf("service.GetFills() = %+v, %v, want one trade", p, err)
+		}
+		return p.Trades[0].TradeID, maker, taker
+	}
+	serve := func(method, path, key, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set(APIKeyHeader, key)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	state := func(id string) orderbook.State {
+		o, _ := s.GetOrder(ctx, id)
+		return o.State()
+	}
+
+	t.Run("should confirm both orders once both parties confirmed the trade", func(t *testing.T) {
+		tradeID, maker, taker := trade(1, 100)
+		if w := serve("PUT", "/godax/v1/trades/"+tradeID+"/confirm", "alice-key", ""); w.Code != http.StatusOK {
+			t.Fatalf("MakeHandler() status = %v: %s", w.Code, w.Body.String())
+		}
+		if state(maker) != "matched" || state(taker) != "matched" {
+			t.Errorf("service.GetOrder() states = %v, %v, want matched until both parties confirmed", state(maker), state(taker))
+		}
+		if w := serve("PUT", "/godax/v1/trades/"+tradeID+"/confirm", "bob-key", ""); w.Code != http.StatusOK {
+			t.Fatalf("MakeHandler() status = %v: %s", w.Code, w.Body.String())
+		}
+		if state(maker) != "confirmed" || state(taker) != "confirmed" {
+			t.Errorf("service.GetOrder() states = %v, %v, want confirmed", state(maker), state(taker))
+		}
+	})
+
+	t.Run("should block a disputed trade until the dispute is resolved", func(t *testing.T) {
+		tradeID, maker, taker := trade(1, 101)
+		if w := serve("PUT", "/godax/v1/trades/"+tradeID+"/dispute", "alice-key", `{"message": "wrong price"}`); w.Code != http.StatusOK {
+			t.Fatalf("MakeHandler() status = %v: %s", w.Code, w.Body.String())
+		}
+		if w := serve("PUT", "/godax/v1/trades/"+tradeID+"/confirm", "bob-key", ""); w.Code != http.StatusConflict {
+			t.Errorf("MakeHandler() status = %v, want %v", w.Code, http.StatusConflict)
+		}
+		if w := serve("PUT", "/godax/v1/trades/"+tradeID+"/resolve", "bob-key", `{"message": "price was correct"}`); w.Code != http.StatusForbidden {
+			t.Errorf("MakeHandler() status = %v, want %v", w.Code, http.StatusForbidden)
+		}
+		if w := serve("PUT", "/godax/v1/trades/"+tradeID+"/resolve", "risk-key", `{"message": "price was correct"}`); w.Code != http.StatusOK {
+			t.Fatalf("MakeHandler() status = %v: %s", w.Code, w.Body.String())
+		}
+		if state(maker) != "confirmed" || state(taker) != "confirmed" {
+			t.Errorf("service.GetOrder() states = %v, %v, want confirmed", state(maker), state(taker))
+		}
+
+		w := serve("GET", "/godax/v1/trades/"+tradeID, "bob-key", "")
+		var got getConfirmationResponse
+		json.NewDecoder(w.Body).Decode(&got)
+		if got.Status != confirmation.Confirmed || got.DisputedBy != "sell" || got.Dispute != "wrong price" || got.Resolution != "price was correct" {
+			t.Errorf("MakeHandler() = %+v, want the resolved dispute of the seller", got)
+		}
+	})
+
+	t.Run("should confirm an order canceled after a partial fill", func(t *testing.T) {
+		confirm := func(tradeID string) {
+			for _, key := range []string{"alice-key", "bob-key"} {
+				if w := serve("PUT", "/godax/v1/trades/"+tradeID+"/confirm", key, ""); w.Code != http.StatusOK {
+					t.Fatalf("MakeHandler() status = %v: %s", w.Code, w.Body.String())
+				}
+			}
+		}
+		fill := func(price int64) (string, string) {
+			maker := place("alice", newLimitOrder(orderbook.Sell, 2, price))
+			taker := place("bob", newLimitOrder(orderbook.Buy, 1, price))
+			p, err := s.GetFills(ctx, taker, Page{})
+			if err != nil || len(p.Trades) != 1 {
+				t.Fatalf("service.GetFills() = %+v, %v, want one trade", p, err)
+			}
+			return p.Trades[0].TradeID, maker
+		}
+
+		tradeID, maker := fill(103)
+		confirm(tradeID)
+		if state(maker) != "published" {
+			t.Errorf("service.GetOrder() state = %v, want published while it is open", state(maker))
+		}
+		if err := s.CancelOrder(ctx, maker); err != nil {
+			t.Fatalf("service.CancelOrder() error = %v", err)
+		}
+		if state(maker) != "confirmed" {
+			t.Errorf("service.GetOrder() state = %v, want confirmed once canceled", state(maker))
+		}
+
+		tradeID, maker = fill(104)
+		if err := s.CancelOrder(ctx, maker); err != nil {
+			t.Fatalf("service.CancelOrder() error = %v", err)
+		}
+		confirm(tradeID)
+		if state(maker) != "confirmed" {
+			t.Errorf("service.GetOrder() state = %v, want confirmed with its trade", state(maker))
+		}
+	})
+
+	t.Run("should not return the trade of other accounts", func(t *testing.T) {
+		tradeID, _, _ := trade(1, 102)
+		if w := serve("GET", "/godax/v1/trades/"+tradeID, "carol-key", ""); w.Code != http.StatusNotFound {
+			t.Errorf("MakeHandler() status = %v, want %v", w.Code, http.StatusNotFound)
+		}
+		if w := serve("PUT", "/godax/v1/trades/"+tradeID+"/confirm", "carol-key", ""); w.Code != http.StatusNotFound {
+			t.Errorf("MakeHandler() status = %v, want %v", w.Code, http.StatusNotFound)
+		}
+		if w := serve("GET", "/godax/v1/trades/"+tradeID, "risk-key", ""); w.Code != http.StatusOK {
+			t.Errorf("MakeHandler() status = %v, want %v", w.Code, http.StatusOK)
+		}
+	})
+}
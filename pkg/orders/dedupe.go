@@ -0,0 +1,104 @@
+package orders
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/LAtanassov/godax/pkg/accessor"
+	"github.com/altairsix/eventsource/mysqlstore"
+)
+
+// DedupeIndex maps idempotency keys to the id of the order created for them
+type DedupeIndex interface {
+	// Reserve records id for key unless key is already taken, it returns the id recorded for key
+	Reserve(ctx context.Context, key, id string) (string, error)
+	// Release removes key if it is recorded for id, e.g. when the order could not be created
+	Release(ctx context.Context, key, id string) error
+}
+
+// memoryDedupeIndex keeps idempotency keys in memory
+type memoryDedupeIndex struct {
+	mux  sync.Mutex
+	keys map[string]string
+}
+
+// NewMemoryDedupeIndex returns an in-memory DedupeIndex
+func NewMemoryDedupeIndex() DedupeIndex {
+	return &memoryDedupeIndex{keys: map[string]string{}}
+}
+
+func (m *memoryDedupeIndex) Reserve(ctx context.Context, key, id string) (string, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if existing, ok := m.keys[key]; ok {
+		return existing, nil
+	}
+	m.keys[key] = id
+	return id, nil
+}
+
+func (m *memoryDedupeIndex) Release(ctx context.Context, key, id string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if m.keys[key] == id {
+		delete(m.keys, key)
+	}
+	return nil
+}
+
+// mysqlDedupeIndex keeps idempotency keys in the dedupe table created by accessor.New
+type mysqlDedupeIndex struct {
+	tableName string
+	accessor  mysqlstore.Accessor
+}
+
+// NewMysqlDedupeIndex returns a DedupeIndex backed by the dedupe table of the event table
+func NewMysqlDedupeIndex(tableName string, a mysqlstore.Accessor) DedupeIndex {
+	return &mysqlDedupeIndex{
+		tableName: accessor.DedupeTableName(tableName),
+		accessor:  a,
+	}
+}
+
+func (m *mysqlDedupeIndex) Reserve(ctx context.Context, key, id string) (string, error) {
+	db, err := m.accessor.Open(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer m.accessor.Close(db)
+
+	// the primary key decides which of concurrent requests wins
+	_, err = db.Exec(fmt.Sprintf(`INSERT IGNORE INTO %s (idempotency_key, aggregate_id) VALUES (?, ?)`, m.tableName), key, id)
+	if err != nil {
+		return "", err
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`SELECT aggregate_id FROM %s WHERE idempotency_key = ?`, m.tableName), key)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return "", fmt.Errorf("idempotency key %v not recorded", key)
+	}
+	var existing string
+	if err := rows.Scan(&existing); err != nil {
+		return "", err
+	}
+	return existing, nil
+}
+
+func (m *mysqlDedupeIndex) Release(ctx context.Context, key, id string) error {
+	db, err := m.accessor.Open(ctx)
+	if err != nil {
+		return err
+	}
+	defer m.accessor.Close(db)
+
+	_, err = db.Exec(fmt.Sprintf(`DELETE FROM %s WHERE idempotency_key = ? AND aggregate_id = ?`, m.tableName), key, id)
+	return err
+}
@@ -2,18 +2,23 @@ package orders
 
 import (
 	"context"
+	"sort"
+	"strconv"
+	"time"
 
+	"github.com/LAtanassov/godax/pkg/clearing"
+	"github.com/LAtanassov/godax/pkg/confirmation"
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/gdax"
 	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/LAtanassov/godax/pkg/trading"
 
+	"github.com/altairsix/eventsource"
 	"github.com/go-kit/kit/endpoint"
 )
 
 type createOrderRequest struct {
-	Size      float32
-	Price     float32
-	OrderType orderbook.OrderType
-	OrderSide orderbook.OrderSide
-	ProductID orderbook.ProductID
+	Spec OrderSpec
 }
 
 type createOrderResponse struct {
@@ -29,18 +34,29 @@ func makeCreateOrderEndpoint(s Service) endpoint.Endpoint {
 		if !ok {
 			return nil, ErrTypeCast
 		}
-		id, err := s.CreateOrder(ctx, req.Size, req.Price, req.OrderType, req.OrderSide, req.ProductID)
+		// orders are always created for the account of the caller
+		p, _ := PrincipalFromContext(ctx)
+		req.Spec.AccountID = p.AccountID
+		id, err := s.CreateOrder(ctx, req.Spec)
 		return createOrderResponse{ID: id, Err: err}, nil
 	}
 }
 
+// getOrderRequest returns the latest state of an order unless AsOf or Version is set
 type getOrderRequest struct {
-	ID string `json:"id"`
+	ID      string    `json:"id"`
+	AsOf    time.Time `json:"as_of"`
+	Version int       `json:"version"`
 }
 
+func (r getOrderRequest) orderID() string { return r.ID }
+
 type getOrderResponse struct {
-	Order orderbook.Order `json:"order"`
-	Err   error           `json:"error,omitempty"`
+	Order           orderbook.Order `json:"order"`
+	Version         int             `json:"version"`
+	State           orderbook.State `json:"state"`
+	AllowedCommands []string        `json:"allowed_commands"`
+	Err             error           `json:"error,omitempty"`
 }
 
 func (r getOrderResponse) error() error { return r.Err }
@@ -51,15 +67,361 @@ func makeGetOrderEndpoint(s Service) endpoint.Endpoint {
 		if !ok {
 			return nil, ErrTypeCast
 		}
-		o, err := s.GetOrder(ctx, r.ID)
-		return getOrderResponse{Order: o, Err: err}, nil
+		var o orderbook.Order
+		var err error
+		switch {
+		case !r.AsOf.IsZero():
+			o, err = s.GetOrderAt(ctx, r.ID, r.AsOf)
+		case r.Version > 0:
+			o, err = s.GetOrderAtVersion(ctx, r.ID, r.Version)
+		default:
+			o, err = s.GetOrder(ctx, r.ID)
+		}
+		return getOrderResponse{Order: o, Version: o.Version(), State: o.State(), AllowedCommands: allowedCommands(o), Err: err}, nil
+	}
+}
+
+type getBookRequest struct {
+	ProductID orderbook.ProductID `json:"product_id"`
+	Level     int                 `json:"level"`
+	Depth     int                 `json:"depth"`
+}
+
+type getBookResponse struct {
+	gdax.BookSnapshot
+	Err error `json:"error,omitempty"`
+}
+
+func (r getBookResponse) error() error { return r.Err }
+
+func makeGetBookEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		r, ok := request.(getBookRequest)
+		if !ok {
+			return nil, ErrTypeCast
+		}
+		b, err := s.GetBook(ctx, r.ProductID, r.Level, r.Depth)
+		return getBookResponse{BookSnapshot: toGdaxBook(b, r.Level), Err: err}, nil
+	}
+}
+
+// toGdaxBook renders the book like the GDAX order book, entries are [price, size, num-orders]
+// at level 1 and 2 and [price, size, order_id] at level 3
+func toGdaxBook(b orderbook.BookSnapshot, level int) gdax.BookSnapshot {
+	rows := func(entries []orderbook.BookEntry) [][]string {
+		rs := [][]string{}
+		for _, e := range entries {
+			last := strconv.Itoa(e.NumOrders)
+			if level == 3 {
+				last = e.OrderID
+			}
+			rs = append(rs, []string{e.Price.String(), e.Size.String(), last})
+		}
+		return rs
+	}
+	return gdax.BookSnapshot{Sequence: int(b.Sequence), Bids: rows(b.Bids), Asks: rows(b.Asks)}
+}
+
+// publicTrade is a Trade in the shape of the GDAX trades, side is the side of the aggressor
+type publicTrade struct {
+	TradeID      string              `json:"trade_id"`
+	ProductID    orderbook.ProductID `json:"product_id"`
+	Price        decimal.Decimal     `json:"price"`
+	Size         decimal.Decimal     `json:"size"`
+	MakerOrderID string              `json:"maker_order_id"`
+	TakerOrderID string              `json:"taker_order_id"`
+	Side         string              `json:"side"`
+	Time         time.Time           `json:"time"`
+}
+
+// orderFill is a Trade from the view of one of its orders in the shape of the GDAX fills
+type orderFill struct {
+	TradeID   string              `json:"trade_id"`
+	ProductID orderbook.ProductID `json:"product_id"`
+	OrderID   string              `json:"order_id"`
+	Price     decimal.Decimal     `json:"price"`
+	Size      decimal.Decimal     `json:"size"`
+	Liquidity orderbook.Liquidity `json:"liquidity"`
+	Side      string              `json:"side"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+type getTradesRequest struct {
+	ProductID orderbook.ProductID `json:"product_id"`
+	Page      Page                `json:"page"`
+}
+
+// getTradesResponse is encoded as array of Trades, the cursors are passed as headers
+type getTradesResponse struct {
+	Trades []publicTrade
+	Before int64
+	After  int64
+	Err    error
+}
+
+func (r getTradesResponse) error() error            { return r.Err }
+func (r getTradesResponse) cursors() (int64, int64) { return r.Before, r.After }
+func (r getTradesResponse) items() interface{}      { return r.Trades }
+
+func makeGetTradesEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		r, ok := request.(getTradesRequest)
+		if !ok {
+			return nil, ErrTypeCast
+		}
+		p, err := s.GetTrades(ctx, r.ProductID, r.Page)
+		trades := []publicTrade{}
+		for _, t := range p.Trades {
+			trades = append(trades, publicTrade{TradeID: t.TradeID, ProductID: t.ProductID, Price: t.Price, Size: t.Size,
+				MakerOrderID: t.MakerOrderID, TakerOrderID: t.TakerOrderID, Side: t.Side.String(), Time: t.Time})
+		}
+		return getTradesResponse{Trades: trades, Before: p.Before, After: p.After, Err: err}, nil
+	}
+}
+
+type getFillsRequest struct {
+	ID   string `json:"id"`
+	Page Page   `json:"page"`
+}
+
+func (r getFillsRequest) orderID() string { return r.ID }
+
+// getFillsResponse is encoded as array of fills, the cursors are passed as headers
+type getFillsResponse struct {
+	Fills  []orderFill
+	Before int64
+	After  int64
+	Err    error
+}
+
+func (r getFillsResponse) error() error            { return r.Err }
+func (r getFillsResponse) cursors() (int64, int64) { return r.Before, r.After }
+func (r getFillsResponse) items() interface{}      { return r.Fills }
+
+func makeGetFillsEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		r, ok := request.(getFillsRequest)
+		if !ok {
+			return nil, ErrTypeCast
+		}
+		p, err := s.GetFills(ctx, r.ID, r.Page)
+		fills := []orderFill{}
+		for _, t := range p.Trades {
+			// the taker is the aggressor, the maker was on the opposite side
+			f := orderFill{TradeID: t.TradeID, ProductID: t.ProductID, OrderID: r.ID, Price: t.Price, Size: t.Size,
+				Liquidity: orderbook.Taker, Side: t.Side.String(), CreatedAt: t.Time}
+			if t.MakerOrderID == r.ID {
+				f.Liquidity = orderbook.Maker
+				f.Side = opposite(t.Side).String()
+			}
+			fills = append(fills, f)
+		}
+		return getFillsResponse{Fills: fills, Before: p.Before, After: p.After, Err: err}, nil
+	}
+}
+
+func opposite(side orderbook.OrderSide) orderbook.OrderSide {
+	if side == orderbook.Buy {
+		return orderbook.Sell
+	}
+	return orderbook.Buy
+}
+
+type getBalancesRequest struct{}
+
+// balance of a currency in the shape of a GDAX account
+type balance struct {
+	Currency  string          `json:"currency"`
+	Balance   decimal.Decimal `json:"balance"`
+	Hold      decimal.Decimal `json:"hold"`
+	Available decimal.Decimal `json:"available"`
+}
+
+type getBalancesResponse struct {
+	Balances []balance `json:"balances"`
+	Err      error     `json:"error,omitempty"`
+}
+
+func (r getBalancesResponse) error() error { return r.Err }
+
+func makeGetBalancesEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		if _, ok := request.(getBalancesRequest); !ok {
+			return nil, ErrTypeCast
+		}
+		// balances are always returned for the account of the caller
+		p, _ := PrincipalFromContext(ctx)
+		balances, err := s.GetBalances(ctx, p.AccountID)
+		resp := getBalancesResponse{Balances: []balance{}, Err: err}
+		for currency, b := range balances {
+			resp.Balances = append(resp.Balances, balance{Currency: currency, Balance: b.Total, Hold: b.Hold, Available: b.Available()})
+		}
+		sort.Slice(resp.Balances, func(i, j int) bool { return resp.Balances[i].Currency < resp.Balances[j].Currency })
+		return resp, nil
+	}
+}
+
+type depositRequest struct {
+	AccountID string
+	Currency  string
+	Amount    decimal.Decimal
+}
+
+func makeDepositEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(depositRequest)
+		if !ok {
+			return nil, ErrTypeCast
+		}
+		err := s.Deposit(ctx, req.AccountID, req.Currency, req.Amount)
+		return commonOrderResponse{Err: err}, nil
+	}
+}
+
+type getTrialBalanceRequest struct{}
+
+type trialBalanceAccount struct {
+	Account  string          `json:"account"`
+	Currency string          `json:"currency"`
+	Debit    decimal.Decimal `json:"debit"`
+	Credit   decimal.Decimal `json:"credit"`
+	Balance  decimal.Decimal `json:"balance"`
+}
+
+type trialBalanceTotal struct {
+	Currency string          `json:"currency"`
+	Debit    decimal.Decimal `json:"debit"`
+	Credit   decimal.Decimal `json:"credit"`
+}
+
+type getTrialBalanceResponse struct {
+	Accounts []trialBalanceAccount `json:"accounts"`
+	Totals   []trialBalanceTotal   `json:"totals"`
+	Balanced bool                  `json:"balanced"`
+	Err      error                 `json:"error,omitempty"`
+}
+
+func (r getTrialBalanceResponse) error() error { return r.Err }
+
+func makeGetTrialBalanceEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		if _, ok := request.(getTrialBalanceRequest); !ok {
+			return nil, ErrTypeCast
+		}
+		t, err := s.GetTrialBalance(ctx)
+		resp := getTrialBalanceResponse{Accounts: []trialBalanceAccount{}, Totals: []trialBalanceTotal{}, Balanced: t.Balanced(), Err: err}
+		for _, b := range t.Accounts {
+			resp.Accounts = append(resp.Accounts, trialBalanceAccount{Account: b.Account, Currency: b.Currency,
+				Debit: b.Debit, Credit: b.Credit, Balance: b.Balance()})
+		}
+		for currency, debit := range t.Debits {
+			resp.Totals = append(resp.Totals, trialBalanceTotal{Currency: currency, Debit: debit, Credit: t.Credits[currency]})
+		}
+		sort.Slice(resp.Totals, func(i, j int) bool { return resp.Totals[i].Currency < resp.Totals[j].Currency })
+		return resp, nil
+	}
+}
+
+// clearingInstruction is the net amount an account receives (positive) or pays (negative) in a currency
+type clearingInstruction struct {
+	Account  string          `json:"account"`
+	Currency string          `json:"currency"`
+	Amount   decimal.Decimal `json:"amount"`
+}
+
+type clearingCycle struct {
+	ID           string                `json:"id"`
+	WindowStart  time.Time             `json:"window_start"`
+	WindowEnd    time.Time             `json:"window_end"`
+	ClearedAt    time.Time             `json:"cleared_at"`
+	OrderIDs     []string              `json:"order_ids"`
+	Obligations  int                   `json:"obligations"`
+	Instructions []clearingInstruction `json:"instructions"`
+}
+
+// toClearingCycle returns the cycle with the instructions of all accounts unless account is set
+func toClearingCycle(c clearing.Cycle, account string) clearingCycle {
+	instructions := c.Instructions
+	if account != "" {
+		instructions = c.Owes(account)
+	}
+	cycle := clearingCycle{ID: c.ID(), WindowStart: c.WindowStart, WindowEnd: c.WindowEnd, ClearedAt: c.ClearedAt(),
+		OrderIDs: c.OrderIDs, Obligations: c.Obligations, Instructions: []clearingInstruction{}}
+	for _, i := range instructions {
+		cycle.Instructions = append(cycle.Instructions, clearingInstruction{Account: i.Account, Currency: i.Currency, Amount: i.Amount})
+	}
+	return cycle
+}
+
+type getClearingCyclesRequest struct {
+	AccountID string
+}
+
+type getClearingCyclesResponse struct {
+	Cycles []clearingCycle `json:"cycles"`
+	Err    error           `json:"error,omitempty"`
+}
+
+func (r getClearingCyclesResponse) error() error { return r.Err }
+
+func makeGetClearingCyclesEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(getClearingCyclesRequest)
+		if !ok {
+			return nil, ErrTypeCast
+		}
+		cycles, err := s.GetClearingCycles(ctx)
+		resp := getClearingCyclesResponse{Cycles: []clearingCycle{}, Err: err}
+		for _, c := range cycles {
+			resp.Cycles = append(resp.Cycles, toClearingCycle(c, req.AccountID))
+		}
+		return resp, nil
 	}
 }
 
+type getClearingCycleRequest struct {
+	ID        string
+	AccountID string
+}
+
+type getClearingCycleResponse struct {
+	clearingCycle
+	Err error `json:"error,omitempty"`
+}
+
+func (r getClearingCycleResponse) error() error { return r.Err }
+
+func makeGetClearingCycleEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(getClearingCycleRequest)
+		if !ok {
+			return nil, ErrTypeCast
+		}
+		c, err := s.GetClearingCycle(ctx, req.ID)
+		if err != nil {
+			return getClearingCycleResponse{Err: err}, nil
+		}
+		return getClearingCycleResponse{clearingCycle: toClearingCycle(c, req.AccountID)}, nil
+	}
+}
+
+// allowedCommands returns the commands a client may send for the order, internal commands are omitted
+func allowedCommands(o orderbook.Order) []string {
+	commands := []string{}
+	for _, t := range o.AllowedCommands() {
+		if !t.Internal {
+			commands = append(commands, t.Command)
+		}
+	}
+	return commands
+}
+
 type commonOrderRequest struct {
 	ID string `json:"id"`
 }
 
+func (r commonOrderRequest) orderID() string { return r.ID }
+
 type commonOrderResponse struct {
 	Err error `json:"error,omitempty"`
 }
@@ -77,6 +439,25 @@ func makeCancelOrderEndpoint(s Service) endpoint.Endpoint {
 	}
 }
 
+type amendOrderRequest struct {
+	ID    string
+	Size  decimal.Decimal
+	Price decimal.Decimal
+}
+
+func (r amendOrderRequest) orderID() string { return r.ID }
+
+func makeAmendOrderEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(amendOrderRequest)
+		if !ok {
+			return nil, ErrTypeCast
+		}
+		err := s.AmendOrder(ctx, req.ID, req.Size, req.Price)
+		return commonOrderResponse{Err: err}, nil
+	}
+}
+
 func makeAcceptOrderEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req, ok := request.(commonOrderRequest)
@@ -88,6 +469,25 @@ func makeAcceptOrderEndpoint(s Service) endpoint.Endpoint {
 	}
 }
 
+type rejectOrderRequest struct {
+	ID      string
+	Reason  orderbook.RejectReason
+	Message string
+}
+
+func (r rejectOrderRequest) orderID() string { return r.ID }
+
+func makeRejectOrderEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(rejectOrderRequest)
+		if !ok {
+			return nil, ErrTypeCast
+		}
+		err := s.RejectOrder(ctx, req.ID, req.Reason, req.Message)
+		return commonOrderResponse{Err: err}, nil
+	}
+}
+
 func makePublishOrderEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req, ok := request.(commonOrderRequest)
@@ -99,28 +499,158 @@ func makePublishOrderEndpoint(s Service) endpoint.Endpoint {
 	}
 }
 
-func makeMatchOrderEndpoint(s Service) endpoint.Endpoint {
+// tradeRequest is a request on the confirmation of a trade, the message is used by disputes and resolutions
+type tradeRequest struct {
+	TradeID string
+	Message string
+}
+
+func makeConfirmTradeEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		req, ok := request.(commonOrderRequest)
+		req, ok := request.(tradeRequest)
 		if !ok {
 			return nil, ErrTypeCast
 		}
-		err := s.MatchOrder(ctx, req.ID)
+		// trades are always confirmed for the party of the caller
+		p, _ := PrincipalFromContext(ctx)
+		err := s.ConfirmTrade(ctx, req.TradeID, p.AccountID)
 		return commonOrderResponse{Err: err}, nil
 	}
 }
 
-func makeConfirmOrderEndpoint(s Service) endpoint.Endpoint {
+func makeDisputeTradeEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		req, ok := request.(commonOrderRequest)
+		req, ok := request.(tradeRequest)
 		if !ok {
 			return nil, ErrTypeCast
 		}
-		err := s.ConfirmOrder(ctx, req.ID)
+		p, _ := PrincipalFromContext(ctx)
+		err := s.DisputeTrade(ctx, req.TradeID, p.AccountID, req.Message)
 		return commonOrderResponse{Err: err}, nil
 	}
 }
 
+func makeResolveDisputeEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(tradeRequest)
+		if !ok {
+			return nil, ErrTypeCast
+		}
+		err := s.ResolveDispute(ctx, req.TradeID, req.Message)
+		return commonOrderResponse{Err: err}, nil
+	}
+}
+
+type getConfirmationResponse struct {
+	TradeID         string              `json:"trade_id"`
+	ProductID       orderbook.ProductID `json:"product_id"`
+	BuyOrderID      string              `json:"buy_order_id"`
+	SellOrderID     string              `json:"sell_order_id"`
+	BuyerConfirmed  bool                `json:"buyer_confirmed"`
+	SellerConfirmed bool                `json:"seller_confirmed"`
+	Status          confirmation.Status `json:"status"`
+	DisputedBy      string              `json:"disputed_by,omitempty"`
+	Dispute         string              `json:"dispute,omitempty"`
+	Resolution      string              `json:"resolution,omitempty"`
+	Err             error               `json:"error,omitempty"`
+}
+
+func (r getConfirmationResponse) error() error { return r.Err }
+
+func makeGetConfirmationEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(tradeRequest)
+		if !ok {
+			return nil, ErrTypeCast
+		}
+		c, err := s.GetConfirmation(ctx, req.TradeID)
+		if err != nil {
+			return getConfirmationResponse{Err: err}, nil
+		}
+		// parties see their own trades only, operators all trades
+		if p, _ := PrincipalFromContext(ctx); !p.Operator {
+			if _, ok := c.PartyOf(p.AccountID); !ok {
+				return getConfirmationResponse{Err: eventsource.NewError(nil, eventsource.ErrAggregateNotFound, "unable to load trade, %v", req.TradeID)}, nil
+			}
+		}
+		resp := getConfirmationResponse{TradeID: c.ID(), ProductID: c.ProductID, BuyOrderID: c.BuyOrderID, SellOrderID: c.SellOrderID,
+			BuyerConfirmed: c.BuyerConfirmed, SellerConfirmed: c.SellerConfirmed, Status: c.Status(),
+			Dispute: c.Dispute, Resolution: c.Resolution}
+		if c.Status() == confirmation.Disputed || c.Resolution != "" {
+			resp.DisputedBy = c.DisputedBy.String()
+		}
+		return resp, nil
+	}
+}
+
+// tradingStatusRequest reads or changes the trading status of a product
+type tradingStatusRequest struct {
+	ProductID orderbook.ProductID
+	Status    trading.Status
+	Reason    string
+}
+
+type tradingStatusResponse struct {
+	ProductID orderbook.ProductID `json:"product_id"`
+	Status    trading.Status      `json:"status"`
+	Reason    string              `json:"reason,omitempty"`
+	ChangedAt *time.Time          `json:"changed_at,omitempty"`
+	Err       error               `json:"error,omitempty"`
+}
+
+func (r tradingStatusResponse) error() error { return r.Err }
+
+func makeGetTradingStatusEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(tradingStatusRequest)
+		if !ok {
+			return nil, ErrTypeCast
+		}
+		m, err := s.GetTradingStatus(ctx, req.ProductID)
+		if err != nil {
+			return tradingStatusResponse{Err: err}, nil
+		}
+		resp := tradingStatusResponse{ProductID: req.ProductID, Status: m.Status(), Reason: m.Reason}
+		if changedAt := m.ChangedAt(); !changedAt.IsZero() {
+			resp.ChangedAt = &changedAt
+		}
+		return resp, nil
+	}
+}
+
+func makeSetTradingStatusEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(tradingStatusRequest)
+		if !ok {
+			return nil, ErrTypeCast
+		}
+		err := s.SetTradingStatus(ctx, req.ProductID, req.Status, req.Reason)
+		return commonOrderResponse{Err: err}, nil
+	}
+}
+
+// getAuctionResponse is the indicative uncross of a product in auction, the volume is zero if no order crosses
+type getAuctionResponse struct {
+	ProductID        orderbook.ProductID `json:"product_id"`
+	IndicativePrice  decimal.Decimal     `json:"indicative_price"`
+	IndicativeVolume decimal.Decimal     `json:"indicative_volume"`
+	Imbalance        decimal.Decimal     `json:"imbalance"`
+	Err              error               `json:"error,omitempty"`
+}
+
+func (r getAuctionResponse) error() error { return r.Err }
+
+func makeGetAuctionEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(tradingStatusRequest)
+		if !ok {
+			return nil, ErrTypeCast
+		}
+		u, err := s.GetAuction(ctx, req.ProductID)
+		return getAuctionResponse{ProductID: req.ProductID, IndicativePrice: u.Price, IndicativeVolume: u.Volume, Imbalance: u.Imbalance, Err: err}, nil
+	}
+}
+
 func makeClearOrderEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req, ok := request.(commonOrderRequest)
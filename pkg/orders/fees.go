@@ -0,0 +1,72 @@
+package orders
+
+import (
+	"sync"
+	"time"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+)
+
+// VolumeWindow is the period of traded volume which determines the fee tier of an account
+const VolumeWindow = 30 * 24 * time.Hour
+
+// VolumeTracker keeps the traded volume of each account and product in the quote currency
+type VolumeTracker interface {
+	// Add records the value of a fill
+	Add(account string, productID orderbook.ProductID, value decimal.Decimal, at time.Time)
+	// Trailing returns the volume traded within VolumeWindow before now
+	Trailing(account string, productID orderbook.ProductID, now time.Time) decimal.Decimal
+}
+
+type volumeKey struct {
+	account   string
+	productID orderbook.ProductID
+}
+
+type fill struct {
+	value decimal.Decimal
+	at    time.Time
+}
+
+// memoryVolumeTracker keeps the fills of the last VolumeWindow in memory
+type memoryVolumeTracker struct {
+	mux   sync.Mutex
+	fills map[volumeKey][]fill
+}
+
+// NewVolumeTracker returns an in-memory VolumeTracker, volumes start at zero after a restart
+func NewVolumeTracker() VolumeTracker {
+	return &memoryVolumeTracker{fills: map[volumeKey][]fill{}}
+}
+
+func (m *memoryVolumeTracker) Add(account string, productID orderbook.ProductID, value decimal.Decimal, at time.Time) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	key := volumeKey{account: account, productID: productID}
+	m.fills[key] = append(m.fills[key], fill{value: value, at: at})
+}
+
+func (m *memoryVolumeTracker) Trailing(account string, productID orderbook.ProductID, now time.Time) decimal.Decimal {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	key := volumeKey{account: account, productID: productID}
+	since := now.Add(-VolumeWindow)
+
+	// fills are added in time order, fills outside of the window are dropped
+	fills := m.fills[key]
+	n := 0
+	for n < len(fills) && !fills[n].at.After(since) {
+		n++
+	}
+	fills = fills[n:]
+	m.fills[key] = fills
+
+	volume := decimal.Zero
+	for _, f := range fills {
+		volume = volume.Add(f.value)
+	}
+	return volume
+}
@@ -1,7 +1,8 @@
 package orders
 
 import (
-	"strconv"
+	"crypto/rand"
+	"sync"
 	"time"
 )
 
@@ -10,8 +11,15 @@ type Generator interface {
 	Generate() string
 }
 
-// IDGenerator generates IDs
+// crockford is the base32 alphabet of Douglas Crockford, it sorts like the encoded bytes
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// IDGenerator generates ULIDs, 26 characters of a 48 bit millisecond timestamp followed by 80 random bits.
+// IDs sort by creation time, IDs of the same millisecond increment the random part so they stay ordered.
 type IDGenerator struct {
+	mux     sync.Mutex
+	lastMs  uint64
+	entropy [10]byte
 }
 
 // NewIDGenerator return an IDGenerator
@@ -19,7 +27,58 @@ func NewIDGenerator() Generator {
 	return &IDGenerator{}
 }
 
-// Generate returns an ID (not unique)
+// Generate returns a unique ID which sorts after all IDs generated before
 func (i *IDGenerator) Generate() string {
-	return strconv.FormatInt(time.Now().UnixNano(), 36)
+	i.mux.Lock()
+	defer i.mux.Unlock()
+
+	ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	if ms <= i.lastMs && increment(&i.entropy) {
+		ms = i.lastMs
+	} else {
+		// a new millisecond, or an overflow of the random part which borrows the next millisecond
+		if ms <= i.lastMs {
+			ms = i.lastMs + 1
+		}
+		if _, err := rand.Read(i.entropy[:]); err != nil {
+			panic(err)
+		}
+	}
+	i.lastMs = ms
+
+	var id [16]byte
+	for b := 0; b < 6; b++ {
+		id[b] = byte(ms >> uint(40-8*b))
+	}
+	copy(id[6:], i.entropy[:])
+	return encode(id)
+}
+
+// increment adds one to the big endian random part, it returns false on overflow
+func increment(b *[10]byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// encode returns the 128 bits of id in 26 characters of base32, the first character holds 3 bits
+func encode(id [16]byte) string {
+	out := make([]byte, 26)
+	var acc uint32
+	bits := uint(2) // 130 encoded bits - 128 bits of id, the first 2 bits are zero
+	j := 0
+	for _, b := range id {
+		acc = acc<<8 | uint32(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			out[j] = crockford[(acc>>bits)&31]
+			j++
+		}
+	}
+	return string(out)
 }
@@ -0,0 +1,45 @@
+package orders
+
+import (
+	"testing"
+)
+
+func TestIDGenerator_Generate(t *testing.T) {
+	g := NewIDGenerator()
+
+	last := ""
+	for i := 0; i < 10000; i++ {
+		id := g.Generate()
+		if len(id) != 26 {
+			t.Fatalf("IDGenerator.Generate() = %v, want 26 characters", id)
+		}
+		if id <= last {
+			t.Fatalf("IDGenerator.Generate() = %v, want it to sort after %v", id, last)
+		}
+		last = id
+	}
+}
+
+func Test_encode(t *testing.T) {
+	max := [16]byte{}
+	for i := range max {
+		max[i] = 0xff
+	}
+
+	tests := []struct {
+		name string
+		id   [16]byte
+		want string
+	}{
+		{"should encode zero", [16]byte{}, "00000000000000000000000000"},
+		{"should encode the largest id", max, "7ZZZZZZZZZZZZZZZZZZZZZZZZZ"},
+		{"should encode the last bit", [16]byte{15: 1}, "00000000000000000000000001"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := encode(tt.id); got != tt.want {
+				t.Errorf("encode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,7 +4,13 @@ import (
 	"context"
 	"time"
 
+	"github.com/LAtanassov/godax/pkg/clearing"
+	"github.com/LAtanassov/godax/pkg/confirmation"
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/ledger"
 	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/LAtanassov/godax/pkg/trading"
+	"github.com/LAtanassov/godax/pkg/wallet"
 
 	"github.com/go-kit/kit/metrics"
 )
@@ -26,14 +32,13 @@ func NewInstrumentingMiddleware(counter metrics.Counter, latency metrics.Histogr
 	}
 }
 
-func (s *instrumentingService) CreateOrder(ctx context.Context, size, price float32,
-	orderType orderbook.OrderType, orderSide orderbook.OrderSide, productID orderbook.ProductID) (id string, err error) {
+func (s *instrumentingService) CreateOrder(ctx context.Context, spec OrderSpec) (id string, err error) {
 	defer func(begin time.Time) {
 		s.requestCount.With("method", "CreateOrder").Add(1)
 		s.requestLatency.With("method", "CreateOrder").Observe(time.Since(begin).Seconds())
 	}(time.Now())
 
-	return s.Service.CreateOrder(ctx, size, price, orderType, orderSide, productID)
+	return s.Service.CreateOrder(ctx, spec)
 }
 
 func (s *instrumentingService) GetOrder(ctx context.Context, id string) (order orderbook.Order, err error) {
@@ -45,6 +50,24 @@ func (s *instrumentingService) GetOrder(ctx context.Context, id string) (order o
 	return s.Service.GetOrder(ctx, id)
 }
 
+func (s *instrumentingService) GetOrderAt(ctx context.Context, id string, asOf time.Time) (order orderbook.Order, err error) {
+	defer func(begin time.Time) {
+		s.requestCount.With("method", "GetOrderAt").Add(1)
+		s.requestLatency.With("method", "GetOrderAt").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.Service.GetOrderAt(ctx, id, asOf)
+}
+
+func (s *instrumentingService) GetOrderAtVersion(ctx context.Context, id string, version int) (order orderbook.Order, err error) {
+	defer func(begin time.Time) {
+		s.requestCount.With("method", "GetOrderAtVersion").Add(1)
+		s.requestLatency.With("method", "GetOrderAtVersion").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.Service.GetOrderAtVersion(ctx, id, version)
+}
+
 func (s *instrumentingService) CancelOrder(ctx context.Context, id string) (err error) {
 	defer func(begin time.Time) {
 		s.requestCount.With("method", "CancelOrder").Add(1)
@@ -53,3 +76,165 @@ func (s *instrumentingService) CancelOrder(ctx context.Context, id string) (err
 
 	return s.Service.CancelOrder(ctx, id)
 }
+
+func (s *instrumentingService) AmendOrder(ctx context.Context, id string, size, price decimal.Decimal) (err error) {
+	defer func(begin time.Time) {
+		s.requestCount.With("method", "AmendOrder").Add(1)
+		s.requestLatency.With("method", "AmendOrder").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.Service.AmendOrder(ctx, id, size, price)
+}
+
+func (s *instrumentingService) RejectOrder(ctx context.Context, id string, reason orderbook.RejectReason, message string) (err error) {
+	defer func(begin time.Time) {
+		s.requestCount.With("method", "RejectOrder").Add(1)
+		s.requestLatency.With("method", "RejectOrder").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.Service.RejectOrder(ctx, id, reason, message)
+}
+
+func (s *instrumentingService) TriggerOrders(ctx context.Context, productID orderbook.ProductID, lastPrice decimal.Decimal) (err error) {
+	defer func(begin time.Time) {
+		s.requestCount.With("method", "TriggerOrders").Add(1)
+		s.requestLatency.With("method", "TriggerOrders").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.Service.TriggerOrders(ctx, productID, lastPrice)
+}
+
+func (s *instrumentingService) GetBook(ctx context.Context, productID orderbook.ProductID, level, depth int) (book orderbook.BookSnapshot, err error) {
+	defer func(begin time.Time) {
+		s.requestCount.With("method", "GetBook").Add(1)
+		s.requestLatency.With("method", "GetBook").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.Service.GetBook(ctx, productID, level, depth)
+}
+
+func (s *instrumentingService) GetTrades(ctx context.Context, productID orderbook.ProductID, page Page) (trades TradePage, err error) {
+	defer func(begin time.Time) {
+		s.requestCount.With("method", "GetTrades").Add(1)
+		s.requestLatency.With("method", "GetTrades").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.Service.GetTrades(ctx, productID, page)
+}
+
+func (s *instrumentingService) GetFills(ctx context.Context, id string, page Page) (fills TradePage, err error) {
+	defer func(begin time.Time) {
+		s.requestCount.With("method", "GetFills").Add(1)
+		s.requestLatency.With("method", "GetFills").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.Service.GetFills(ctx, id, page)
+}
+
+func (s *instrumentingService) Deposit(ctx context.Context, accountID, currency string, amount decimal.Decimal) (err error) {
+	defer func(begin time.Time) {
+		s.requestCount.With("method", "Deposit").Add(1)
+		s.requestLatency.With("method", "Deposit").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.Service.Deposit(ctx, accountID, currency, amount)
+}
+
+func (s *instrumentingService) GetBalances(ctx context.Context, accountID string) (balances map[string]wallet.Balance, err error) {
+	defer func(begin time.Time) {
+		s.requestCount.With("method", "GetBalances").Add(1)
+		s.requestLatency.With("method", "GetBalances").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.Service.GetBalances(ctx, accountID)
+}
+
+func (s *instrumentingService) GetTrialBalance(ctx context.Context) (t ledger.TrialBalance, err error) {
+	defer func(begin time.Time) {
+		s.requestCount.With("method", "GetTrialBalance").Add(1)
+		s.requestLatency.With("method", "GetTrialBalance").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.Service.GetTrialBalance(ctx)
+}
+
+func (s *instrumentingService) GetClearingCycles(ctx context.Context) (cycles []clearing.Cycle, err error) {
+	defer func(begin time.Time) {
+		s.requestCount.With("method", "GetClearingCycles").Add(1)
+		s.requestLatency.With("method", "GetClearingCycles").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.Service.GetClearingCycles(ctx)
+}
+
+func (s *instrumentingService) GetClearingCycle(ctx context.Context, id string) (cycle clearing.Cycle, err error) {
+	defer func(begin time.Time) {
+		s.requestCount.With("method", "GetClearingCycle").Add(1)
+		s.requestLatency.With("method", "GetClearingCycle").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.Service.GetClearingCycle(ctx, id)
+}
+
+func (s *instrumentingService) ConfirmTrade(ctx context.Context, tradeID, accountID string) (err error) {
+	defer func(begin time.Time) {
+		s.requestCount.With("method", "ConfirmTrade").Add(1)
+		s.requestLatency.With("method", "ConfirmTrade").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.Service.ConfirmTrade(ctx, tradeID, accountID)
+}
+
+func (s *instrumentingService) DisputeTrade(ctx context.Context, tradeID, accountID, message string) (err error) {
+	defer func(begin time.Time) {
+		s.requestCount.With("method", "DisputeTrade").Add(1)
+		s.requestLatency.With("method", "DisputeTrade").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.Service.DisputeTrade(ctx, tradeID, accountID, message)
+}
+
+func (s *instrumentingService) ResolveDispute(ctx context.Context, tradeID, message string) (err error) {
+	defer func(begin time.Time) {
+		s.requestCount.With("method", "ResolveDispute").Add(1)
+		s.requestLatency.With("method", "ResolveDispute").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.Service.ResolveDispute(ctx, tradeID, message)
+}
+
+func (s *instrumentingService) GetConfirmation(ctx context.Context, tradeID string) (c confirmation.Confirmation, err error) {
+	defer func(begin time.Time) {
+		s.requestCount.With("method", "GetConfirmation").Add(1)
+		s.requestLatency.With("method", "GetConfirmation").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.Service.GetConfirmation(ctx, tradeID)
+}
+
+func (s *instrumentingService) SetTradingStatus(ctx context.Context, productID orderbook.ProductID, status trading.Status, reason string) (err error) {
+	defer func(begin time.Time) {
+		s.requestCount.With("method", "SetTradingStatus").Add(1)
+		s.requestLatency.With("method", "SetTradingStatus").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.Service.SetTradingStatus(ctx, productID, status, reason)
+}
+
+func (s *instrumentingService) GetTradingStatus(ctx context.Context, productID orderbook.ProductID) (m trading.Market, err error) {
+	defer func(begin time.Time) {
+		s.requestCount.With("method", "GetTradingStatus").Add(1)
+		s.requestLatency.With("method", "GetTradingStatus").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.Service.GetTradingStatus(ctx, productID)
+}
+
+func (s *instrumentingService) GetAuction(ctx context.Context, productID orderbook.ProductID) (u orderbook.Uncross, err error) {
+	defer func(begin time.Time) {
+		s.requestCount.With("method", "GetAuction").Add(1)
+		s.requestLatency.With("method", "GetAuction").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return s.Service.GetAuction(ctx, productID)
+}
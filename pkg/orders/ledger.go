@@ -0,0 +1,149 @@
+package orders
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/LAtanassov/godax/pkg/accessor"
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/ledger"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/mysqlstore"
+)
+
+// ledgerSerializer binds the journal events with their current schema version, see serializer
+var ledgerSerializer = newSchemaSerializer().
+	Bind(1,
+		ledger.EntryPosted{},
+	)
+
+// Ledger books the fills of settled orders as double-entry journal entries
+type Ledger interface {
+	// Post books the postings of a fill as entry id, an entry which was posted before is ignored
+	Post(ctx context.Context, id, tradeID, orderID string, postings []ledger.Posting) error
+	// TrialBalance returns the balance of every account and currency of the posted entries
+	TrialBalance(ctx context.Context) ledger.TrialBalance
+}
+
+// eventLedger keeps the entries as aggregates in their own event store
+// and projects the trial balance from the posted entries in memory, rebuilt from the store at startup.
+type eventLedger struct {
+	repository *eventsource.Repository
+
+	mux     sync.Mutex
+	posted  map[string]bool
+	journal *ledger.Ledger
+}
+
+// NewLedger returns a Ledger depending on driver
+func NewLedger(dbDriver, dbURL, tableName string) (Ledger, error) {
+	switch dbDriver {
+	case inmem:
+		return newInMemLedger(), nil
+	case mysql:
+		a, err := accessor.New(dbDriver, dbURL, tableName)
+		if err != nil {
+			return nil, err
+		}
+		store, err := mysqlstore.New(accessor.LedgerTableName(tableName), a)
+		if err != nil {
+			return nil, err
+		}
+		l := newLedger(store)
+		if err := l.rebuild(context.Background()); err != nil {
+			return nil, err
+		}
+		return l, nil
+	default:
+		return nil, ErrUnsupportedDriver
+	}
+}
+
+func newInMemLedger() Ledger {
+	return newLedger(newMemoryStore())
+}
+
+func newLedger(store eventsource.Store) *eventLedger {
+	l := &eventLedger{posted: map[string]bool{}, journal: ledger.NewLedger()}
+	l.repository = eventsource.New(&ledger.Entry{},
+		eventsource.WithStore(store),
+		eventsource.WithSerializer(ledgerSerializer),
+		eventsource.WithObservers(l.on),
+	)
+	return l
+}
+
+func (l *eventLedger) Post(ctx context.Context, id, tradeID, orderID string, postings []ledger.Posting) error {
+	_, err := l.repository.Apply(ctx, &ledger.PostEntry{
+		TradeID:      tradeID,
+		OrderID:      orderID,
+		Postings:     postings,
+		Metadata:     orderbook.MetadataFromContext(ctx),
+		CommandModel: eventsource.CommandModel{ID: id},
+	})
+	return err
+}
+
+func (l *eventLedger) TrialBalance(ctx context.Context) ledger.TrialBalance {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	return l.journal.TrialBalance()
+}
+
+// rebuild projects the trial balance from the entries posted before startup
+func (l *eventLedger) rebuild(ctx context.Context) error {
+	return replayStore(ctx, l.repository.Store(), ledgerSerializer, l.on)
+}
+
+// on adds the postings of an entry to the trial balance, entries are counted once even if their event is replayed
+func (l *eventLedger) on(event eventsource.Event) {
+	v, ok := event.(*ledger.EntryPosted)
+	if !ok {
+		return
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if l.posted[v.AggregateID()] {
+		return
+	}
+	l.posted[v.AggregateID()] = true
+	l.journal.Post(v.Postings)
+}
+
+// executedFill is a fill of an order with the fee charged for it
+type executedFill struct {
+	entryID string
+	tradeID string
+	price   decimal.Decimal
+	size    decimal.Decimal
+	fee     decimal.Decimal
+}
+
+// executedFills returns the fills of an order from its events, the entry id of a fill is the order id
+// and the trade id, or the event version for matches recorded before trades had ids.
+func executedFills(id string, events []eventsource.Event) []executedFill {
+	fees := map[string]decimal.Decimal{}
+	for _, event := range events {
+		if v, ok := event.(*orderbook.OrderFeeCharged); ok {
+			fees[v.TradeID] = fees[v.TradeID].Add(v.Fee)
+		}
+	}
+
+	fills := []executedFill{}
+	for _, event := range events {
+		switch v := event.(type) {
+		case *orderbook.OrderMatched:
+			fills = append(fills, executedFill{entryID: id + "/" + strconv.Itoa(v.Version), price: v.Price, size: v.Size})
+		case *orderbook.OrderPartiallyFilled:
+			fills = append(fills, executedFill{entryID: id + "/" + v.TradeID, tradeID: v.TradeID, price: v.Price, size: v.Size, fee: fees[v.TradeID]})
+		case *orderbook.OrderFilled:
+			fills = append(fills, executedFill{entryID: id + "/" + v.TradeID, tradeID: v.TradeID, price: v.Price, size: v.Size, fee: fees[v.TradeID]})
+		}
+	}
+	return fills
+}
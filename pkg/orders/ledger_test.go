@@ -0,0 +1,189 @@
+package orders
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/ledger"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/altairsix/eventsource"
+	kitlog "github.com/go-kit/kit/log"
+)
+
+func Test_service_SettleOrder_ledger(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	l := newLedger(store)
+	s := newTestService(Dependencies{Ledger: l})
+
+	place := func(account string, spec OrderSpec) string {
+		spec.AccountID = account
+		id, _ := s.CreateOrder(ctx, spec)
+		s.AcceptOrder(ctx, id)
+		s.PublishOrder(ctx, id)
+		return id
+	}
+	maker := place("alice", newLimitOrder(orderbook.Sell, 2, 100))
+	taker := place("bob", newLimitOrder(orderbook.Buy, 2, 100))
+	for _, id := range []string{maker, taker} {
+		s.ConfirmOrder(ctx, id)
+		s.ClearOrder(ctx, id)
+	}
+
+	balances := func() map[string]decimal.Decimal {
+		t.Helper()
+		tb, err := s.GetTrialBalance(ctx)
+		if err != nil {
+			t.Fatalf("service.GetTrialBalance() error = %v", err)
+		}
+		if !tb.Balanced() {
+			t.Errorf("TrialBalance.Balanced() = false, debits %v credits %v", tb.Debits, tb.Credits)
+		}
+		got := map[string]decimal.Decimal{}
+		for _, b := range tb.Accounts {
+			got[b.Account+"/"+b.Currency] = b.Balance()
+		}
+		return got
+	}
+
+	t.Run("should post the fills of the maker against the clearing account", func(t *testing.T) {
+		if err := s.SettleOrder(ctx, maker); err != nil {
+			t.Fatalf("service.SettleOrder() error = %v", err)
+		}
+		got := balances()
+		if !got["alice/BTC"].Equal(decimal.NewFromInt(-2)) || !got[ledger.ClearingAccount+"/BTC"].Equal(decimal.NewFromInt(2)) {
+			t.Errorf("service.GetTrialBalance() = %v, want alice to deliver 2 BTC to clearing", got)
+		}
+	})
+
+	t.Run("should balance the clearing account once both orders are settled", func(t *testing.T) {
+		if err := s.SettleOrder(ctx, taker); err != nil {
+			t.Fatalf("service.SettleOrder() error = %v", err)
+		}
+		sold, _ := s.GetOrder(ctx, maker)
+		bought, _ := s.GetOrder(ctx, taker)
+		got := balances()
+		want := map[string]decimal.Decimal{
+			"alice/BTC":                     decimal.NewFromInt(-2),
+			"alice/USD":                     decimal.NewFromInt(200).Sub(sold.FillFees),
+			"bob/BTC":                       decimal.NewFromInt(2),
+			"bob/USD":                       decimal.NewFromInt(-200).Sub(bought.FillFees),
+			ledger.ClearingAccount + "/BTC": decimal.Zero,
+			ledger.ClearingAccount + "/USD": decimal.Zero,
+			ledger.FeeAccount + "/USD":      sold.FillFees.Add(bought.FillFees),
+		}
+		for k, w := range want {
+			if !got[k].Equal(w) {
+				t.Errorf("service.GetTrialBalance() %v = %v, want %v", k, got[k], w)
+			}
+		}
+	})
+
+	t.Run("should not double-post when the fills or their events are replayed", func(t *testing.T) {
+		before := balances()
+		if err := s.(*service).postFills(ctx, maker); err != nil {
+			t.Fatalf("service.postFills() error = %v", err)
+		}
+		history, _ := s.(*service).repository.History(ctx, maker, 0)
+		for _, f := range executedFills(maker, history) {
+			v, err := l.repository.Load(ctx, f.entryID)
+			if err != nil {
+				t.Fatalf("Ledger.Load() error = %v", err)
+			}
+			e := v.(*ledger.Entry)
+			l.on(&ledger.EntryPosted{Postings: e.Postings, Model: eventsource.Model{ID: e.ID(), Version: e.Version()}})
+		}
+		after := balances()
+		for k, w := range before {
+			if !after[k].Equal(w) {
+				t.Errorf("service.GetTrialBalance() %v = %v after replay, want %v", k, after[k], w)
+			}
+		}
+	})
+
+	t.Run("should rebuild the trial balance from the store at startup", func(t *testing.T) {
+		restarted := newLedger(store)
+		if err := restarted.rebuild(ctx); err != nil {
+			t.Fatalf("Ledger.rebuild() error = %v", err)
+		}
+		want, _ := s.GetTrialBalance(ctx)
+		if got := restarted.TrialBalance(ctx); !reflect.DeepEqual(got, want) {
+			t.Errorf("Ledger.TrialBalance() = %+v, want %+v", got, want)
+		}
+	})
+}
+
+func Test_service_SettleOrder_partiallyFilled(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(Dependencies{Ledger: newLedger(newMemoryStore())})
+
+	place := func(account string, spec OrderSpec) string {
+		spec.AccountID = account
+		id, _ := s.CreateOrder(ctx, spec)
+		s.AcceptOrder(ctx, id)
+		s.PublishOrder(ctx, id)
+		return id
+	}
+	maker := place("alice", newLimitOrder(orderbook.Sell, 2, 100))
+	taker := place("bob", newLimitOrder(orderbook.Buy, 1, 100))
+	if err := s.CancelOrder(ctx, maker); err != nil {
+		t.Fatalf("service.CancelOrder() error = %v", err)
+	}
+	for _, id := range []string{maker, taker} {
+		s.ConfirmOrder(ctx, id)
+		s.ClearOrder(ctx, id)
+		if err := s.SettleOrder(ctx, id); err != nil {
+			t.Fatalf("service.SettleOrder() error = %v", err)
+		}
+	}
+
+	tb, _ := s.GetTrialBalance(ctx)
+	got := map[string]decimal.Decimal{}
+	for _, b := range tb.Accounts {
+		got[b.Account+"/"+b.Currency] = b.Balance()
+	}
+	if !got["alice/BTC"].Equal(decimal.NewFromInt(-1)) || !got[ledger.ClearingAccount+"/BTC"].IsZero() || !got[ledger.ClearingAccount+"/USD"].IsZero() {
+		t.Errorf("service.GetTrialBalance() = %v, want the fill of the canceled order posted and clearing balanced", got)
+	}
+}
+
+func TestMakeHandler_trialBalance(t *testing.T) {
+	s := newTestService(Dependencies{})
+	h := MakeHandler(s, NewAPIKeyAuthenticator(map[string]Principal{
+		"alice-key": {AccountID: "alice"},
+		"risk-key":  {AccountID: "risk", Operator: true},
+	}), kitlog.NewNopLogger())
+
+	tests := []struct {
+		name       string
+		apiKey     string
+		wantStatus int
+	}{
+		{"should return the trial balance to operators", "risk-key", http.StatusOK},
+		{"should forbid the trial balance to accounts", "alice-key", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/godax/v1/ledger/trial-balance", nil)
+			r.Header.Set(APIKeyHeader, tt.apiKey)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("MakeHandler() status = %v, want %v: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got getTrialBalanceResponse
+			json.NewDecoder(w.Body).Decode(&got)
+			if !got.Balanced || len(got.Accounts) != 0 {
+				t.Errorf("MakeHandler() = %+v, want an empty balanced trial balance", got)
+			}
+		})
+	}
+}
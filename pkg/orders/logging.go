@@ -4,7 +4,13 @@ import (
 	"context"
 	"time"
 
+	"github.com/LAtanassov/godax/pkg/clearing"
+	"github.com/LAtanassov/godax/pkg/confirmation"
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/ledger"
 	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/LAtanassov/godax/pkg/trading"
+	"github.com/LAtanassov/godax/pkg/wallet"
 
 	"github.com/go-kit/kit/log"
 )
@@ -21,18 +27,18 @@ func NewLoggingMiddleware(logger log.Logger) ServiceMiddleware {
 	}
 }
 
-func (s *loggingService) CreateOrder(ctx context.Context, size, price float32,
-	orderType orderbook.OrderType, orderSide orderbook.OrderSide, productID orderbook.ProductID) (id string, err error) {
+func (s *loggingService) CreateOrder(ctx context.Context, spec OrderSpec) (id string, err error) {
 	defer func(begin time.Time) {
 		s.logger.Log(
 			"method", "CreateOrder",
 			"id", id,
+			"account", spec.AccountID,
 			"took", time.Since(begin),
 			"err", err,
 		)
 	}(time.Now())
 
-	return s.Service.CreateOrder(ctx, size, price, orderType, orderSide, productID)
+	return s.Service.CreateOrder(ctx, spec)
 }
 
 func (s *loggingService) GetOrder(ctx context.Context, id string) (order orderbook.Order, err error) {
@@ -48,6 +54,34 @@ func (s *loggingService) GetOrder(ctx context.Context, id string) (order orderbo
 	return s.Service.GetOrder(ctx, id)
 }
 
+func (s *loggingService) GetOrderAt(ctx context.Context, id string, asOf time.Time) (order orderbook.Order, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "GetOrderAt",
+			"id", id,
+			"asOf", asOf,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return s.Service.GetOrderAt(ctx, id, asOf)
+}
+
+func (s *loggingService) GetOrderAtVersion(ctx context.Context, id string, version int) (order orderbook.Order, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "GetOrderAtVersion",
+			"id", id,
+			"version", version,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return s.Service.GetOrderAtVersion(ctx, id, version)
+}
+
 func (s *loggingService) CancelOrder(ctx context.Context, id string) (err error) {
 	defer func(begin time.Time) {
 		s.logger.Log(
@@ -60,3 +94,248 @@ func (s *loggingService) CancelOrder(ctx context.Context, id string) (err error)
 
 	return s.Service.CancelOrder(ctx, id)
 }
+
+func (s *loggingService) AmendOrder(ctx context.Context, id string, size, price decimal.Decimal) (err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "AmendOrder",
+			"id", id,
+			"size", size,
+			"price", price,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return s.Service.AmendOrder(ctx, id, size, price)
+}
+
+func (s *loggingService) RejectOrder(ctx context.Context, id string, reason orderbook.RejectReason, message string) (err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "RejectOrder",
+			"id", id,
+			"reason", reason,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return s.Service.RejectOrder(ctx, id, reason, message)
+}
+
+func (s *loggingService) TriggerOrders(ctx context.Context, productID orderbook.ProductID, lastPrice decimal.Decimal) (err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "TriggerOrders",
+			"productID", productID,
+			"lastPrice", lastPrice,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return s.Service.TriggerOrders(ctx, productID, lastPrice)
+}
+
+func (s *loggingService) GetBook(ctx context.Context, productID orderbook.ProductID, level, depth int) (book orderbook.BookSnapshot, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "GetBook",
+			"productID", productID,
+			"level", level,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return s.Service.GetBook(ctx, productID, level, depth)
+}
+
+func (s *loggingService) GetTrades(ctx context.Context, productID orderbook.ProductID, page Page) (trades TradePage, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "GetTrades",
+			"productID", productID,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return s.Service.GetTrades(ctx, productID, page)
+}
+
+func (s *loggingService) GetFills(ctx context.Context, id string, page Page) (fills TradePage, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "GetFills",
+			"id", id,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return s.Service.GetFills(ctx, id, page)
+}
+
+func (s *loggingService) Deposit(ctx context.Context, accountID, currency string, amount decimal.Decimal) (err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "Deposit",
+			"account_id", accountID,
+			"currency", currency,
+			"amount", amount,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return s.Service.Deposit(ctx, accountID, currency, amount)
+}
+
+func (s *loggingService) GetBalances(ctx context.Context, accountID string) (balances map[string]wallet.Balance, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "GetBalances",
+			"account_id", accountID,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return s.Service.GetBalances(ctx, accountID)
+}
+
+func (s *loggingService) GetTrialBalance(ctx context.Context) (t ledger.TrialBalance, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "GetTrialBalance",
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return s.Service.GetTrialBalance(ctx)
+}
+
+func (s *loggingService) GetClearingCycles(ctx context.Context) (cycles []clearing.Cycle, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "GetClearingCycles",
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return s.Service.GetClearingCycles(ctx)
+}
+
+func (s *loggingService) GetClearingCycle(ctx context.Context, id string) (cycle clearing.Cycle, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "GetClearingCycle",
+			"id", id,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return s.Service.GetClearingCycle(ctx, id)
+}
+
+func (s *loggingService) ConfirmTrade(ctx context.Context, tradeID, accountID string) (err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "ConfirmTrade",
+			"trade_id", tradeID,
+			"account_id", accountID,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return s.Service.ConfirmTrade(ctx, tradeID, accountID)
+}
+
+func (s *loggingService) DisputeTrade(ctx context.Context, tradeID, accountID, message string) (err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "DisputeTrade",
+			"trade_id", tradeID,
+			"account_id", accountID,
+			"message", message,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return s.Service.DisputeTrade(ctx, tradeID, accountID, message)
+}
+
+func (s *loggingService) ResolveDispute(ctx context.Context, tradeID, message string) (err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "ResolveDispute",
+			"trade_id", tradeID,
+			"message", message,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return s.Service.ResolveDispute(ctx, tradeID, message)
+}
+
+func (s *loggingService) GetConfirmation(ctx context.Context, tradeID string) (c confirmation.Confirmation, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "GetConfirmation",
+			"trade_id", tradeID,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return s.Service.GetConfirmation(ctx, tradeID)
+}
+
+func (s *loggingService) SetTradingStatus(ctx context.Context, productID orderbook.ProductID, status trading.Status, reason string) (err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "SetTradingStatus",
+			"product_id", productID,
+			"status", status,
+			"reason", reason,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return s.Service.SetTradingStatus(ctx, productID, status, reason)
+}
+
+func (s *loggingService) GetTradingStatus(ctx context.Context, productID orderbook.ProductID) (m trading.Market, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "GetTradingStatus",
+			"product_id", productID,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return s.Service.GetTradingStatus(ctx, productID)
+}
+
+func (s *loggingService) GetAuction(ctx context.Context, productID orderbook.ProductID) (u orderbook.Uncross, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "GetAuction",
+			"product_id", productID,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return s.Service.GetAuction(ctx, productID)
+}
@@ -0,0 +1,78 @@
+package orders
+
+import (
+	"context"
+
+	"github.com/LAtanassov/godax/pkg/accessor"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/LAtanassov/godax/pkg/trading"
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/mysqlstore"
+)
+
+// marketSerializer binds the trading status events with their current schema version, see serializer
+var marketSerializer = newSchemaSerializer().
+	Bind(1,
+		trading.StatusChanged{},
+	)
+
+// Markets keeps the trading status of every product, the id of a market is the product id
+type Markets interface {
+	// Apply executes a command of the trading package
+	Apply(ctx context.Context, command eventsource.Command) error
+	// Get returns the market of a product, products without status change are open
+	Get(ctx context.Context, productID orderbook.ProductID) (trading.Market, error)
+}
+
+// eventMarkets keeps the markets as aggregates in their own event store
+type eventMarkets struct {
+	repository *eventsource.Repository
+}
+
+// NewMarkets returns Markets depending on driver
+func NewMarkets(dbDriver, dbURL, tableName string) (Markets, error) {
+	switch dbDriver {
+	case inmem:
+		return newInMemMarkets(), nil
+	case mysql:
+		a, err := accessor.New(dbDriver, dbURL, tableName)
+		if err != nil {
+			return nil, err
+		}
+		store, err := mysqlstore.New(accessor.MarketsTableName(tableName), a)
+		if err != nil {
+			return nil, err
+		}
+		return &eventMarkets{
+			repository: eventsource.New(&trading.Market{}, eventsource.WithStore(store), eventsource.WithSerializer(marketSerializer)),
+		}, nil
+	default:
+		return nil, ErrUnsupportedDriver
+	}
+}
+
+func newInMemMarkets() Markets {
+	return &eventMarkets{
+		repository: eventsource.New(&trading.Market{}, eventsource.WithStore(newMemoryStore()), eventsource.WithSerializer(marketSerializer)),
+	}
+}
+
+func (m *eventMarkets) Apply(ctx context.Context, command eventsource.Command) error {
+	_, err := m.repository.Apply(ctx, command)
+	return err
+}
+
+func (m *eventMarkets) Get(ctx context.Context, productID orderbook.ProductID) (trading.Market, error) {
+	v, err := m.repository.Load(ctx, string(productID))
+	if eventsource.IsNotFound(err) {
+		return trading.Market{}, nil
+	}
+	if err != nil {
+		return trading.Market{}, err
+	}
+	market, ok := v.(*trading.Market)
+	if !ok {
+		return trading.Market{}, ErrTypeCast
+	}
+	return *market, nil
+}
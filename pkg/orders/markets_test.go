@@ -0,0 +1,260 @@
+package orders
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/LAtanassov/godax/pkg/trading"
+	kitlog "github.com/go-kit/kit/log"
+)
+
+func TestService_SetTradingStatus(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(Dependencies{})
+
+	create := func(account string, spec OrderSpec) (string, error) {
+		spec.AccountID = account
+		id, err := s.CreateOrder(ctx, spec)
+		if err != nil {
+			return "", err
+		}
+		return id, s.AcceptOrder(ctx, id)
+	}
+	state := func(id string) orderbook.State {
+		o, _ := s.GetOrder(ctx, id)
+		return o.State()
+	}
+
+	maker, _ := create("alice", newLimitOrder(orderbook.Sell, 1, 100))
+	spec := newLimitOrder(orderbook.Sell, 1, 100)
+	spec.ClientOID = "oid-1"
+	retried, _ := create("alice", spec)
+	if err := s.SetTradingStatus(ctx, orderbook.BtcUsd, trading.Halted, "incident"); err != nil {
+		t.Fatalf("service.SetTradingStatus() error = %v", err)
+	}
+
+	t.Run("should reject new orders of a halted product", func(t *testing.T) {
+		if _, err := create("bob", newLimitOrder(orderbook.Buy, 1, 100)); err != trading.ErrHalted {
+			t.Errorf("service.CreateOrder() error = %v, want %v", err, trading.ErrHalted)
+		}
+	})
+
+	t.Run("should return the earlier order of a retry during a halt", func(t *testing.T) {
+		spec := newLimitOrder(orderbook.Sell, 1, 100)
+		spec.AccountID, spec.ClientOID = "alice", "oid-1"
+		if id, err := s.CreateOrder(ctx, spec); err != nil || id != retried {
+			t.Errorf("service.CreateOrder() = %v, %v, want %v", id, err, retried)
+		}
+	})
+
+	t.Run("should keep accepted orders of a halted product parked", func(t *testing.T) {
+		if err := s.PublishOrder(ctx, maker); err != trading.ErrHalted {
+			t.Errorf("service.PublishOrder() error = %v, want %v", err, trading.ErrHalted)
+		}
+		if state(maker) != "accepted" {
+			t.Errorf("service.GetOrder() state = %v, want accepted", state(maker))
+		}
+	})
+
+	t.Run("should accept post only orders only", func(t *testing.T) {
+		s.SetTradingStatus(ctx, orderbook.BtcUsd, trading.PostOnly, "")
+		if _, err := create("bob", newLimitOrder(orderbook.Buy, 1, 100)); err != trading.ErrPostOnly {
+			t.Errorf("service.CreateOrder() error = %v, want %v", err, trading.ErrPostOnly)
+		}
+		spec := newLimitOrder(orderbook.Buy, 1, 90)
+		spec.PostOnly = true
+		if _, err := create("bob", spec); err != nil {
+			t.Errorf("service.CreateOrder() error = %v", err)
+		}
+	})
+
+	t.Run("should hold published orders without matching during an auction", func(t *testing.T) {
+		s.SetTradingStatus(ctx, orderbook.BtcUsd, trading.Auction, "reopening")
+		if err := s.PublishOrder(ctx, maker); err != nil {
+			t.Fatalf("service.PublishOrder() error = %v", err)
+		}
+		taker, _ := create("bob", newLimitOrder(orderbook.Buy, 1, 100))
+		s.PublishOrder(ctx, taker)
+		if state(maker) != "published" || state(taker) != "published" {
+			t.Fatalf("service.GetOrder() states = %v, %v, want both published", state(maker), state(taker))
+		}
+
+		if err := s.SetTradingStatus(ctx, orderbook.BtcUsd, trading.Open, ""); err != nil {
+			t.Fatalf("service.SetTradingStatus() error = %v", err)
+		}
+		if state(maker) != "matched" || state(taker) != "matched" {
+			t.Errorf("service.GetOrder() states = %v, %v, want both matched once the product is open", state(maker), state(taker))
+		}
+	})
+}
+
+func TestService_uncross(t *testing.T) {
+	ctx := context.Background()
+	books := NewBookProjection()
+	s := newTestService(Dependencies{Repository: newInMemRepository(books.On), Books: books})
+	h := MakeHandler(s, NewAPIKeyAuthenticator(map[string]Principal{}), kitlog.NewNopLogger())
+
+	place := func(account string, spec OrderSpec) string {
+		spec.AccountID = account
+		id, _ := s.CreateOrder(ctx, spec)
+		s.AcceptOrder(ctx, id)
+		s.PublishOrder(ctx, id)
+		return id
+	}
+	state := func(id string) orderbook.State {
+		o, _ := s.GetOrder(ctx, id)
+		return o.State()
+	}
+
+	resting := place("alice", newLimitOrder(orderbook.Sell, 2, 101))
+	s.SetTradingStatus(ctx, orderbook.BtcUsd, trading.Halted, "incident")
+	s.SetTradingStatus(ctx, orderbook.BtcUsd, trading.Auction, "reopening")
+	buy := place("bob", newLimitOrder(orderbook.Buy, 3, 102))
+	sell := place("carol", newLimitOrder(orderbook.Sell, 1, 100))
+	below := place("dave", newLimitOrder(orderbook.Buy, 1, 99))
+
+	t.Run("should publish the indicative uncross without matching", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/godax/v1/products/BTC-USD/auction", nil))
+		var got getAuctionResponse
+		json.NewDecoder(w.Body).Decode(&got)
+		if w.Code != http.StatusOK || got.IndicativePrice.String() != "101" || got.IndicativeVolume.String() != "3" || !got.Imbalance.IsZero() {
+			t.Errorf("MakeHandler() = %v %+v, want 3 at 101 without imbalance", w.Code, got)
+		}
+		if state(buy) != "published" || state(sell) != "published" {
+			t.Errorf("service.GetOrder() states = %v, %v, want published", state(buy), state(sell))
+		}
+	})
+
+	t.Run("should match all crossing orders at the uncross price", func(t *testing.T) {
+		if err := s.SetTradingStatus(ctx, orderbook.BtcUsd, trading.Open, ""); err != nil {
+			t.Fatalf("service.SetTradingStatus() error = %v", err)
+		}
+		for _, id := range []string{resting, buy, sell} {
+			if state(id) != "matched" {
+				t.Errorf("service.GetOrder(%v) state = %v, want matched", id, state(id))
+			}
+		}
+		p, _ := s.GetTrades(ctx, orderbook.BtcUsd, Page{})
+		if len(p.Trades) != 2 {
+			t.Fatalf("service.GetTrades() = %+v, want 2 trades", p.Trades)
+		}
+		for _, trade := range p.Trades {
+			if trade.Price.String() != "101" {
+				t.Errorf("service.GetTrades() price = %v, want 101", trade.Price)
+			}
+		}
+	})
+
+	t.Run("should leave the orders which do not cross in the book", func(t *testing.T) {
+		b, _ := s.GetBook(ctx, orderbook.BtcUsd, 3, 0)
+		if state(below) != "published" || len(b.Bids) != 1 || b.Bids[0].OrderID != below {
+			t.Errorf("service.GetBook() = %+v, want %v resting", b, below)
+		}
+		if _, err := s.GetAuction(ctx, orderbook.BtcUsd); err != trading.ErrNoAuction {
+			t.Errorf("service.GetAuction() error = %v, want %v", err, trading.ErrNoAuction)
+		}
+	})
+}
+
+func TestService_uncross_amended(t *testing.T) {
+	ctx := context.Background()
+	books := NewBookProjection()
+	r := &failingRepository{Repository: newInMemRepository(books.On)}
+	s := newTestService(Dependencies{Repository: r, Books: books})
+
+	place := func(account string, spec OrderSpec) string {
+		spec.AccountID = account
+		id, _ := s.CreateOrder(ctx, spec)
+		s.AcceptOrder(ctx, id)
+		s.PublishOrder(ctx, id)
+		return id
+	}
+	s.SetTradingStatus(ctx, orderbook.BtcUsd, trading.Auction, "reopening")
+	sell := place("alice", newLimitOrder(orderbook.Sell, 3, 100))
+	buy := place("bob", newLimitOrder(orderbook.Buy, 3, 100))
+	if err := s.AmendOrder(ctx, sell, decimal.NewFromInt(1), decimal.NewFromInt(100)); err != nil {
+		t.Fatalf("service.AmendOrder() error = %v", err)
+	}
+
+	t.Run("should stay in auction if the uncross fails", func(t *testing.T) {
+		r.failures = 1
+		if err := s.SetTradingStatus(ctx, orderbook.BtcUsd, trading.Open, ""); err == nil {
+			t.Fatalf("service.SetTradingStatus() error = nil, want the failure of the event store")
+		}
+		if u, err := s.GetAuction(ctx, orderbook.BtcUsd); err != nil || !u.Volume.Equal(decimal.NewFromInt(1)) {
+			t.Errorf("service.GetAuction() = %+v, %v, want the amended size still in auction", u, err)
+		}
+	})
+
+	t.Run("should uncross the amended size once the product opens", func(t *testing.T) {
+		if err := s.SetTradingStatus(ctx, orderbook.BtcUsd, trading.Open, ""); err != nil {
+			t.Fatalf("service.SetTradingStatus() error = %v", err)
+		}
+		maker, _ := s.GetOrder(ctx, sell)
+		taker, _ := s.GetOrder(ctx, buy)
+		if maker.State() != "matched" || !taker.FilledSize.Equal(decimal.NewFromInt(1)) || !taker.RemainingSize.Equal(decimal.NewFromInt(2)) {
+			t.Errorf("service.GetOrder() = %+v, %+v, want 1 matched and 2 left of the buy", maker, taker)
+		}
+		b, _ := s.GetBook(ctx, orderbook.BtcUsd, 3, 0)
+		if len(b.Bids) != 1 || b.Bids[0].OrderID != buy {
+			t.Errorf("service.GetBook() = %+v, want the rest of %v resting", b, buy)
+		}
+	})
+}
+
+func TestMakeHandler_tradingStatus(t *testing.T) {
+	s := newTestService(Dependencies{})
+	h := MakeHandler(s, NewAPIKeyAuthenticator(map[string]Principal{
+		"alice-key": {AccountID: "alice"},
+		"risk-key":  {AccountID: "risk", Operator: true},
+	}), kitlog.NewNopLogger())
+
+	tests := []struct {
+		name     string
+		key      string
+		body     string
+		wantCode int
+	}{
+		{"should forbid accounts to halt a product", "alice-key", `{"status": "halted"}`, http.StatusForbidden},
+		{"should reject an unknown status", "risk-key", `{"status": "closed"}`, http.StatusBadRequest},
+		{"should halt a product", "risk-key", `{"status": "halted", "reason": "incident"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PUT", "/godax/v1/products/BTC-USD/status", strings.NewReader(tt.body))
+			r.Header.Set(APIKeyHeader, tt.key)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.wantCode {
+				t.Errorf("MakeHandler() status = %v, want %v: %s", w.Code, tt.wantCode, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("should return the trading status without authentication", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/godax/v1/products/BTC-USD/status", nil))
+		var got tradingStatusResponse
+		json.NewDecoder(w.Body).Decode(&got)
+		if w.Code != http.StatusOK || got.Status != trading.Halted || got.Reason != "incident" || got.ChangedAt == nil {
+			t.Errorf("MakeHandler() = %v %+v, want the halted product", w.Code, got)
+		}
+	})
+
+	t.Run("should reject orders of a halted product with a conflict", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/godax/v1/orders", strings.NewReader(`{"size": "1", "price": "100", "type": "limit", "side": "buy", "product_id": "BTC-USD"}`))
+		r.Header.Set(APIKeyHeader, "alice-key")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), trading.ErrHalted.Error()) {
+			t.Errorf("MakeHandler() status = %v: %s, want %v", w.Code, w.Body.String(), http.StatusConflict)
+		}
+	})
+}
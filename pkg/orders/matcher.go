@@ -0,0 +1,146 @@
+package orders
+
+import (
+	"sync"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+)
+
+// Matcher matches published orders against the order book of their product,
+// keeps pending stop orders until their stop price is reached and holds published orders
+// in the call auction of their product while the product does not match
+type Matcher interface {
+	Match(o orderbook.Order) orderbook.Execution
+	Restore(o orderbook.Order)
+	Cancel(productID orderbook.ProductID, id string) bool
+	Amend(o orderbook.Order, retainsPriority bool) bool
+
+	Park(o orderbook.Order)
+	Trigger(productID orderbook.ProductID, lastPrice decimal.Decimal) []string
+
+	Hold(o orderbook.Order)
+	Collect(productID orderbook.ProductID)
+	Indicative(productID orderbook.ProductID) (orderbook.Uncross, bool)
+	Uncross(productID orderbook.ProductID) (orderbook.Uncross, []orderbook.Match)
+	Fill(productID orderbook.ProductID, m orderbook.Match)
+	Release(productID orderbook.ProductID) []string
+}
+
+// EngineMatcher keeps one in-process matching engine, trigger and auction per product
+type EngineMatcher struct {
+	mux      sync.Mutex
+	engines  map[orderbook.ProductID]*orderbook.Engine
+	triggers map[orderbook.ProductID]*orderbook.Trigger
+	auctions map[orderbook.ProductID]*orderbook.Auction
+}
+
+// NewMatcher returns a Matcher which creates matching engines, triggers and auctions on demand
+func NewMatcher() Matcher {
+	return &EngineMatcher{
+		engines:  map[orderbook.ProductID]*orderbook.Engine{},
+		triggers: map[orderbook.ProductID]*orderbook.Trigger{},
+		auctions: map[orderbook.ProductID]*orderbook.Auction{},
+	}
+}
+
+// Match submits the order to the engine of its product
+func (m *EngineMatcher) Match(o orderbook.Order) orderbook.Execution {
+	e, _, _ := m.get(o.ProductID)
+	return e.Submit(o)
+}
+
+// Restore puts a resting order back on the book of its product without matching it
+func (m *EngineMatcher) Restore(o orderbook.Order) {
+	e, _, _ := m.get(o.ProductID)
+	e.Restore(o)
+}
+
+// Cancel removes a resting order from the engine, a pending stop order from the trigger or a held order
+// from the auction of its product
+func (m *EngineMatcher) Cancel(productID orderbook.ProductID, id string) bool {
+	e, t, a := m.get(productID)
+	return e.Cancel(id) || t.Remove(id) || a.Remove(id)
+}
+
+// Amend updates a resting, pending or held order after it was amended. It returns true if a resting order
+// lost its priority and was removed from the book, it has to be matched again.
+func (m *EngineMatcher) Amend(o orderbook.Order, retainsPriority bool) bool {
+	e, t, a := m.get(o.ProductID)
+	if t.Remove(o.ID()) {
+		t.Add(o)
+		return false
+	}
+	if a.Amend(o, retainsPriority) {
+		return false
+	}
+	if retainsPriority {
+		e.Amend(o.ID(), o.RemainingSize)
+		return false
+	}
+	return e.Cancel(o.ID())
+}
+
+// Park adds a stop order to the trigger of its product
+func (m *EngineMatcher) Park(o orderbook.Order) {
+	_, t, _ := m.get(o.ProductID)
+	t.Add(o)
+}
+
+// Trigger returns the ids of the stop orders to activate at the last trade price
+func (m *EngineMatcher) Trigger(productID orderbook.ProductID, lastPrice decimal.Decimal) []string {
+	_, t, _ := m.get(productID)
+	return t.Fire(lastPrice)
+}
+
+// Hold adds a published order to the auction of its product, it is not matched until the auction is uncrossed or released
+func (m *EngineMatcher) Hold(o orderbook.Order) {
+	_, _, a := m.get(o.ProductID)
+	a.Add(o)
+}
+
+// Collect moves the resting orders of a product from the engine into its auction
+func (m *EngineMatcher) Collect(productID orderbook.ProductID) {
+	e, _, a := m.get(productID)
+	a.Collect(e)
+}
+
+// Indicative returns the uncross of the auction of a product, false if no held order crosses
+func (m *EngineMatcher) Indicative(productID orderbook.ProductID) (orderbook.Uncross, bool) {
+	_, _, a := m.get(productID)
+	return a.Indicative()
+}
+
+// Uncross returns the uncross and the matches of the auction of a product, the held orders stay in the auction
+// until their matches are filled
+func (m *EngineMatcher) Uncross(productID orderbook.ProductID) (orderbook.Uncross, []orderbook.Match) {
+	_, _, a := m.get(productID)
+	return a.Uncross()
+}
+
+// Fill removes an applied match from the auction of a product
+func (m *EngineMatcher) Fill(productID orderbook.ProductID, match orderbook.Match) {
+	_, _, a := m.get(productID)
+	a.Fill(match)
+}
+
+// Release empties the auction of a product without executing it and returns the ids of the held orders
+// in the order they were held
+func (m *EngineMatcher) Release(productID orderbook.ProductID) []string {
+	_, _, a := m.get(productID)
+	return a.Release()
+}
+
+func (m *EngineMatcher) get(productID orderbook.ProductID) (*orderbook.Engine, *orderbook.Trigger, *orderbook.Auction) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	e, ok := m.engines[productID]
+	if !ok {
+		e = orderbook.NewEngine(productID)
+		m.engines[productID] = e
+		m.triggers[productID] = orderbook.NewTrigger(productID)
+		m.auctions[productID] = orderbook.NewAuction(productID)
+	}
+	return e, m.triggers[productID], m.auctions[productID]
+}
@@ -0,0 +1,73 @@
+package orders
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/LAtanassov/godax/pkg/orderbook"
+)
+
+const (
+	// RequestIDHeader correlates all events of a request, a request id is generated if the header is missing
+	RequestIDHeader = "X-Request-Id"
+	// ReasonHeader is a free text reason of the caller which is recorded on the events
+	ReasonHeader = "X-Reason"
+	// SourceHeader names the service which issued the request
+	SourceHeader = "X-Source-Service"
+
+	// ServiceName is the source of commands issued by the orders service itself
+	ServiceName = "orders"
+	// defaultSource is the source of requests which do not name their service
+	defaultSource = "api"
+)
+
+// expiryMetadata is recorded on the events of orders expired by the ExpiryScheduler
+var expiryMetadata = orderbook.Metadata{Actor: "expiry-scheduler", Reason: "expire time reached", Source: ServiceName}
+
+var requestIDs = NewIDGenerator()
+
+// populateMetadata moves the request id, reason and source of the request into the context,
+// the actor is added once the request is authenticated
+func populateMetadata(ctx context.Context, r *http.Request) context.Context {
+	md := orderbook.Metadata{
+		Reason:        r.Header.Get(ReasonHeader),
+		CorrelationID: r.Header.Get(RequestIDHeader),
+		Source:        r.Header.Get(SourceHeader),
+	}
+	if md.CorrelationID == "" {
+		md.CorrelationID = requestIDs.Generate()
+	}
+	if md.Source == "" {
+		md.Source = defaultSource
+	}
+	return orderbook.NewContext(ctx, md)
+}
+
+// setRequestIDHeader returns the request id, so callers can look up the events of their request
+func setRequestIDHeader(ctx context.Context, w http.ResponseWriter) context.Context {
+	if id := orderbook.MetadataFromContext(ctx).CorrelationID; id != "" {
+		w.Header().Set(RequestIDHeader, id)
+	}
+	return ctx
+}
+
+// withActor records the authenticated account as actor of the commands issued for the request
+func withActor(ctx context.Context, p Principal) context.Context {
+	md := orderbook.MetadataFromContext(ctx)
+	md.Actor = p.AccountID
+	return orderbook.NewContext(ctx, md)
+}
+
+// setMetadataHeaders passes the metadata of ctx on to the orders api
+func setMetadataHeaders(ctx context.Context, r *http.Request) {
+	md := orderbook.MetadataFromContext(ctx)
+	if md.CorrelationID != "" {
+		r.Header.Set(RequestIDHeader, md.CorrelationID)
+	}
+	if md.Reason != "" {
+		r.Header.Set(ReasonHeader, md.Reason)
+	}
+	if md.Source != "" {
+		r.Header.Set(SourceHeader, md.Source)
+	}
+}
@@ -0,0 +1,60 @@
+package orders
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/altairsix/eventsource"
+	kitlog "github.com/go-kit/kit/log"
+)
+
+func TestMakeHandler_metadata(t *testing.T) {
+	var events []eventsource.Event
+	r := newInMemRepository(func(e eventsource.Event) { events = append(events, e) })
+	s := newTestService(Dependencies{Repository: r})
+	h := MakeHandler(s, NewAPIKeyAuthenticator(map[string]Principal{"alice-key": {AccountID: "alice"}}), kitlog.NewNopLogger())
+
+	tests := []struct {
+		name      string
+		requestID string
+		source    string
+		want      orderbook.Metadata
+	}{
+		{"should record actor, reason, request id and source of the request", "req-1", "web",
+			orderbook.Metadata{Actor: "alice", Reason: "duplicate", CorrelationID: "req-1", Source: "web"}},
+		{"should generate a request id and default the source", "", "",
+			orderbook.Metadata{Actor: "alice", Reason: "duplicate", Source: defaultSource}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := newLimitOrder(orderbook.Sell, 1, 100)
+			spec.AccountID = "alice"
+			id, _ := s.CreateOrder(context.Background(), spec)
+			events = nil
+
+			req := httptest.NewRequest("DELETE", "/godax/v1/orders/"+id, nil)
+			req.Header.Set(APIKeyHeader, "alice-key")
+			req.Header.Set(ReasonHeader, "duplicate")
+			req.Header.Set(RequestIDHeader, tt.requestID)
+			req.Header.Set(SourceHeader, tt.source)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if w.Code != http.StatusOK || len(events) != 1 {
+				t.Fatalf("MakeHandler() status = %v, events = %v: %s", w.Code, events, w.Body.String())
+			}
+
+			if tt.want.CorrelationID == "" {
+				tt.want.CorrelationID = w.Header().Get(RequestIDHeader)
+			}
+			if tt.want.CorrelationID == "" || w.Header().Get(RequestIDHeader) != tt.want.CorrelationID {
+				t.Errorf("MakeHandler() %v = %q, want %q", RequestIDHeader, w.Header().Get(RequestIDHeader), tt.want.CorrelationID)
+			}
+			if got := events[0].(*orderbook.OrderCanceled).Metadata; got != tt.want {
+				t.Errorf("OrderCanceled.Metadata = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package orders
 import (
 	"context"
 	"errors"
+	"sort"
+	"sync"
 
 	"github.com/LAtanassov/godax/pkg/accessor"
 	"github.com/LAtanassov/godax/pkg/orderbook"
@@ -21,6 +23,17 @@ type Repository interface {
 	Apply(ctx context.Context, command eventsource.Command) (int, error)
 	// Load retrieves the specified aggregate from the underlying store
 	Load(ctx context.Context, aggregateID string) (eventsource.Aggregate, error)
+	// History retrieves the events of the specified aggregate up to toVersion, all events if toVersion is 0
+	History(ctx context.Context, aggregateID string, toVersion int) ([]eventsource.Event, error)
+
+	DedupeIndex
+}
+
+// Replayer is a Repository whose events can be replayed, e.g. to rebuild the in-memory state after a restart
+type Replayer interface {
+	// Replay notifies the observers of the repository and the observers given of every stored event
+	// in the order the events were applied
+	Replay(ctx context.Context, observers ...func(event eventsource.Event)) error
 }
 
 // DatabaseConnection contains all fields to establish a database connection
@@ -32,28 +45,52 @@ type DatabaseConnection struct {
 	Database string
 }
 
-var serializer = eventsource.NewJSONSerializer(
-	orderbook.OrderAccepted{},
-	orderbook.OrderCanceled{},
-	orderbook.OrderCleared{},
-	orderbook.OrderConfirmed{},
-	orderbook.OrderCreated{},
-	orderbook.OrderMatched{},
-	orderbook.OrderPublished{},
-	orderbook.OrderSettled{},
-)
+// serializer binds the events with their current schema version,
+// increase the version and register an upcaster from the previous version whenever a field of an event
+// is renamed, removed or changes its meaning. Added fields are zero in older events.
+var serializer = newSchemaSerializer().
+	Bind(1,
+		orderbook.OrderAccepted{},
+		orderbook.OrderRejected{},
+		orderbook.OrderExpired{},
+		orderbook.OrderCleared{},
+		orderbook.OrderConfirmed{},
+		orderbook.OrderMatched{},
+		orderbook.OrderPartiallyFilled{},
+		orderbook.OrderFilled{},
+		orderbook.OrderPublished{},
+		orderbook.OrderAmended{},
+		orderbook.OrderActivated{},
+		orderbook.OrderSelfTradePrevented{},
+		orderbook.OrderFeeCharged{},
+		orderbook.OrderSettled{},
+	).
+	Bind(2,
+		orderbook.OrderCanceled{},
+	).
+	Bind(3,
+		orderbook.OrderCreated{},
+	).
+	Upcast(orderbook.OrderCreated{}, 1, upcastOrderCreatedV1).
+	Upcast(orderbook.OrderCreated{}, 2, upcastOrderCreatedV2).
+	Upcast(orderbook.OrderCanceled{}, 1, upcastOrderCanceledV1)
 
 const (
 	inmem = "inmem"
 	mysql = "mysql"
 )
 
-// NewRepository return a repository depending on driver
-func NewRepository(dbDriver, dbURL, tableName string) (Repository, error) {
+// NewRepository return a repository depending on driver,
+// a snapshot of an Order is taken every snapshotInterval events unless snapshotInterval is 0.
+// The observers are notified of every applied event, e.g. to maintain the BookProjection.
+func NewRepository(dbDriver, dbURL, tableName string, snapshotInterval int, observers ...func(event eventsource.Event)) (Repository, error) {
 
 	switch dbDriver {
 	case inmem:
-		return newInMemRepository(), nil
+		if snapshotInterval > 0 {
+			return newSnapshotRepository(newMemoryStore(), NewMemorySnapshotStore(), NewMemoryDedupeIndex(), snapshotInterval, observers...), nil
+		}
+		return newInMemRepository(observers...), nil
 	case mysql:
 		accessor, err := accessor.New(dbDriver, dbURL, tableName)
 		if err != nil {
@@ -64,28 +101,165 @@ func NewRepository(dbDriver, dbURL, tableName string) (Repository, error) {
 			return nil, err
 		}
 
-		return newRepository(store), nil
+		dedupe := NewMysqlDedupeIndex(tableName, accessor)
+		if snapshotInterval > 0 {
+			return newSnapshotRepository(store, NewMysqlSnapshotStore(tableName, accessor), dedupe, snapshotInterval, observers...), nil
+		}
+		return newRepository(store, dedupe, observers...), nil
 	default:
 		return nil, ErrUnsupportedDriver
 	}
 }
 
 func newInMemRepository(observers ...func(event eventsource.Event)) Repository {
-	return eventsource.New(&orderbook.Order{},
+	return eventRepository{eventsource.New(&orderbook.Order{},
 		eventsource.WithSerializer(serializer),
 		eventsource.WithObservers(observers...),
-	)
+	), NewMemoryDedupeIndex(), observers}
 }
 
-func newRepository(store eventsource.Store, observers ...func(event eventsource.Event)) Repository {
-	return eventsource.New(&orderbook.Order{},
+func newRepository(store eventsource.Store, dedupe DedupeIndex, observers ...func(event eventsource.Event)) Repository {
+	return eventRepository{eventsource.New(&orderbook.Order{},
 		eventsource.WithStore(store),
 		eventsource.WithSerializer(serializer),
 		eventsource.WithObservers(observers...),
-	)
+	), dedupe, observers}
+}
+
+// eventRepository adds the history of an aggregate, the dedupe index and the replay of its events
+// to the eventsource repository
+type eventRepository struct {
+	*eventsource.Repository
+	DedupeIndex
+
+	observers []func(eventsource.Event)
+}
+
+// History retrieves the events of the specified aggregate up to toVersion, all events if toVersion is 0
+func (r eventRepository) History(ctx context.Context, aggregateID string, toVersion int) ([]eventsource.Event, error) {
+	return history(ctx, r.Store(), aggregateID, toVersion)
+}
+
+// Replay notifies the observers of every stored event in the order the events were applied
+func (r eventRepository) Replay(ctx context.Context, observers ...func(event eventsource.Event)) error {
+	return replayStore(ctx, r.Store(), serializer, append(append([]func(eventsource.Event){}, r.observers...), observers...)...)
+}
+
+// history loads and deserializes the events of an aggregate up to toVersion
+func history(ctx context.Context, store eventsource.Store, aggregateID string, toVersion int) ([]eventsource.Event, error) {
+	records, err := store.Load(ctx, aggregateID, 0, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []eventsource.Event{}
+	for _, record := range records {
+		// the in-memory store of eventsource ignores the requested versions
+		if toVersion > 0 && record.Version > toVersion {
+			continue
+		}
+		event, err := serializer.UnmarshalEvent(record)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	if len(events) == 0 {
+		return nil, eventsource.NewError(nil, eventsource.ErrAggregateNotFound, "unable to load order, %v", aggregateID)
+	}
+	return events, nil
 }
 
 // NewMysqlStore return a repository with oberserves
 func newMysqlStore(tableName string, accessor mysqlstore.Accessor) (eventsource.Store, error) {
 	return mysqlstore.New(tableName, accessor)
 }
+
+// memoryStore is an in-memory eventsource.Store which, unlike the store of eventsource,
+// loads only the requested range of versions and can be read as a stream like the mysql store
+type memoryStore struct {
+	mux    sync.Mutex
+	events map[string]eventsource.History
+	stream []eventsource.StreamRecord
+}
+
+func newMemoryStore() eventsource.Store {
+	return &memoryStore{events: map[string]eventsource.History{}}
+}
+
+func (m *memoryStore) Save(ctx context.Context, aggregateID string, records ...eventsource.Record) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	history := append(m.events[aggregateID], records...)
+	sort.Sort(history)
+	m.events[aggregateID] = history
+	for _, record := range records {
+		m.stream = append(m.stream, eventsource.StreamRecord{Record: record, Offset: uint64(len(m.stream) + 1), AggregateID: aggregateID})
+	}
+	return nil
+}
+
+// Read returns the next recordCount records starting at the offset in the order they were saved
+func (m *memoryStore) Read(ctx context.Context, startingOffset uint64, recordCount int) ([]eventsource.StreamRecord, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	records := []eventsource.StreamRecord{}
+	for _, record := range m.stream {
+		if len(records) == recordCount {
+			break
+		}
+		if record.Offset >= startingOffset {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func (m *memoryStore) Load(ctx context.Context, aggregateID string, fromVersion, toVersion int) (eventsource.History, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	history := eventsource.History{}
+	for _, record := range m.events[aggregateID] {
+		if record.Version >= fromVersion && (toVersion == 0 || record.Version <= toVersion) {
+			history = append(history, record)
+		}
+	}
+	return history, nil
+}
+
+// replayBatch is the number of records read at once when a store is replayed
+const replayBatch = 1000
+
+// replayStore passes every event of the store to the observers in the order the events were saved,
+// it rebuilds the in-memory projections at startup. Stores which can not be read as a stream are skipped.
+func replayStore(ctx context.Context, store eventsource.Store, serializer eventsource.Serializer, observers ...func(event eventsource.Event)) error {
+	reader, ok := store.(eventsource.StreamReader)
+	if !ok {
+		return nil
+	}
+
+	var offset uint64
+	for {
+		records, err := reader.Read(ctx, offset, replayBatch)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			event, err := serializer.UnmarshalEvent(record.Record)
+			if err != nil {
+				return err
+			}
+			for _, observer := range observers {
+				observer(event)
+			}
+			offset = record.Offset + 1
+		}
+		if len(records) < replayBatch {
+			return nil
+		}
+	}
+}
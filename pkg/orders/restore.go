@@ -0,0 +1,191 @@
+package orders
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/LAtanassov/godax/pkg/trading"
+	"github.com/altairsix/eventsource"
+)
+
+// Restore rebuilds the in-memory state of the Service from the order events of its Repository after a restart:
+// the observers of the Repository, e.g. the BookProjection and the ClearingHouse, see every stored event again,
+// the trades are added to the TradeHistory again and the open orders are put back into the Matcher, held in the
+// auction of products in auction. The expiry of open good til time orders is scheduled again, orders whose
+// expire time passed during the restart are expired at once. It has to be called before the Service handles requests,
+// repositories which can not be replayed are skipped.
+func Restore(ctx context.Context, d Dependencies) error {
+	r, ok := d.Repository.(Replayer)
+	if !ok {
+		return nil
+	}
+
+	p := newRestoration()
+	if err := r.Replay(ctx, p.on); err != nil {
+		return err
+	}
+
+	for _, trade := range p.trades {
+		d.Trades.Add(trade)
+	}
+
+	if err := reschedule(ctx, d, p.expiring); err != nil {
+		return err
+	}
+
+	for _, id := range p.open() {
+		v, err := d.Repository.Load(ctx, id)
+		if err != nil {
+			return err
+		}
+		o, ok := v.(*orderbook.Order)
+		if !ok {
+			return ErrTypeCast
+		}
+
+		switch {
+		case o.Allows("ActivateOrder"):
+			d.Matcher.Park(*o)
+		case o.Allows("MatchOrder"):
+			market, err := d.Markets.Get(ctx, o.ProductID)
+			if err != nil {
+				return err
+			}
+			if market.Status() == trading.Auction {
+				d.Matcher.Hold(*o)
+				continue
+			}
+			d.Matcher.Restore(*o)
+		}
+	}
+	return nil
+}
+
+// reschedule schedules the expiry of the open good til time orders, due orders are expired through the Service
+// before their arrival at the book is restored
+func reschedule(ctx context.Context, d Dependencies, expiring map[string]bool) error {
+	svc := NewService(d)
+	now := time.Now()
+	for id := range expiring {
+		v, err := d.Repository.Load(ctx, id)
+		if err != nil {
+			return err
+		}
+		o, ok := v.(*orderbook.Order)
+		if !ok {
+			return ErrTypeCast
+		}
+		if !o.Allows("ExpireOrder") {
+			continue
+		}
+		if o.ExpireTime.After(now) {
+			d.Scheduler.Schedule(id, o.ProductID, o.ExpireTime)
+			continue
+		}
+		if err := svc.ExpireOrder(orderbook.NewContext(ctx, expiryMetadata), id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoredOrder is what the restoration needs to know about an order to record its trades
+type restoredOrder struct {
+	productID orderbook.ProductID
+	side      orderbook.OrderSide
+}
+
+// restoration collects the trades, the arrival of the orders and the good til time orders from the replayed order events
+type restoration struct {
+	orders   map[string]restoredOrder
+	makers   map[string]string
+	trades   []orderbook.Trade
+	arrivals map[string]int
+	seq      int
+	expiring map[string]bool
+}
+
+func newRestoration() *restoration {
+	return &restoration{
+		orders:   map[string]restoredOrder{},
+		makers:   map[string]string{},
+		arrivals: map[string]int{},
+		expiring: map[string]bool{},
+	}
+}
+
+// on records the trades, the arrival of the orders and the good til time orders, an order arrives when it is
+// published or activated and again when an amendment loses its priority. Closed orders are forgotten.
+func (p *restoration) on(event eventsource.Event) {
+	id := event.AggregateID()
+
+	switch v := event.(type) {
+	case *orderbook.OrderCreated:
+		p.orders[id] = restoredOrder{productID: v.ProductID, side: v.OrderSide}
+		if v.TimeInForce == orderbook.GoodTilTime {
+			p.expiring[id] = true
+		}
+	case *orderbook.OrderPublished, *orderbook.OrderActivated:
+		p.arrive(id)
+	case *orderbook.OrderAmended:
+		if !v.PriorityRetained {
+			p.arrive(id)
+		}
+	case *orderbook.OrderPartiallyFilled:
+		p.fill(id, v.TradeID, v.Price, v.Size, v.At)
+	case *orderbook.OrderFilled:
+		p.fill(id, v.TradeID, v.Price, v.Size, v.At)
+		p.close(id)
+	case *orderbook.OrderCanceled, *orderbook.OrderExpired, *orderbook.OrderRejected:
+		p.close(id)
+	}
+}
+
+func (p *restoration) close(id string) {
+	delete(p.arrivals, id)
+	delete(p.expiring, id)
+}
+
+func (p *restoration) arrive(id string) {
+	p.seq++
+	p.arrivals[id] = p.seq
+}
+
+// fill records a trade once the fills of both orders were replayed, the fill of the maker is applied first.
+// Matches recorded before trades had ids are skipped.
+func (p *restoration) fill(id, tradeID string, price, size decimal.Decimal, at time.Time) {
+	if tradeID == "" {
+		return
+	}
+	maker, ok := p.makers[tradeID]
+	if !ok {
+		p.makers[tradeID] = id
+		return
+	}
+	delete(p.makers, tradeID)
+
+	taker := p.orders[id]
+	p.trades = append(p.trades, orderbook.Trade{
+		TradeID:      tradeID,
+		ProductID:    taker.productID,
+		Price:        price,
+		Size:         size,
+		MakerOrderID: maker,
+		TakerOrderID: id,
+		Side:         taker.side,
+		Time:         at,
+	})
+}
+
+// open returns the orders which arrived at the book in the order of their last arrival
+func (p *restoration) open() []string {
+	ids := make([]string, 0, len(p.arrivals))
+	for id := range p.arrivals {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return p.arrivals[ids[i]] < p.arrivals[ids[j]] })
+	return ids
+}
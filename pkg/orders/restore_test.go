@@ -0,0 +1,171 @@
+package orders
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/LAtanassov/godax/pkg/trading"
+)
+
+func TestRestore(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	idg := &sequenceIDGenerator{}
+	markets := newInMemMarkets()
+
+	// start returns a service on the order events of the store, restored like after a restart
+	start := func(t *testing.T) (Service, Dependencies) {
+		books := NewBookProjection()
+		d := Dependencies{
+			IDGenerator: idg,
+			Repository:  newRepository(store, NewMemoryDedupeIndex(), books.On),
+			Matcher:     NewMatcher(),
+			Books:       books,
+			Trades:      NewTradeHistory(),
+			Markets:     markets,
+		}
+		if err := Restore(ctx, d); err != nil {
+			t.Fatalf("Restore() error = %v", err)
+		}
+		return newTestService(d), d
+	}
+	place := func(s Service, account string, spec OrderSpec) string {
+		spec.AccountID = account
+		id, _ := s.CreateOrder(ctx, spec)
+		s.AcceptOrder(ctx, id)
+		if err := s.PublishOrder(ctx, id); err != nil {
+			t.Fatalf("service.PublishOrder() error = %v", err)
+		}
+		return id
+	}
+	sameTrade := func(a, b orderbook.Trade) bool {
+		return a.TradeID == b.TradeID && a.MakerOrderID == b.MakerOrderID && a.TakerOrderID == b.TakerOrderID &&
+			a.Side == b.Side && a.Price.Equal(b.Price) && a.Size.Equal(b.Size)
+	}
+
+	s, _ := start(t)
+	partial := place(s, "alice", newLimitOrder(orderbook.Sell, 2, 100))
+	resting := place(s, "alice", newLimitOrder(orderbook.Sell, 1, 101))
+	place(s, "bob", newLimitOrder(orderbook.Buy, 1, 100))
+	stop := place(s, "carol", OrderSpec{Size: decimal.NewFromInt(1), StopPrice: decimal.NewFromInt(90),
+		OrderType: orderbook.Stop, OrderSide: orderbook.Sell, ProductID: orderbook.BtcUsd})
+
+	book, _ := s.GetBook(ctx, orderbook.BtcUsd, 3, 0)
+	trades, _ := s.GetTrades(ctx, orderbook.BtcUsd, Page{})
+
+	restarted, d := start(t)
+
+	t.Run("should restore the book from the order events", func(t *testing.T) {
+		got, _ := restarted.GetBook(ctx, orderbook.BtcUsd, 3, 0)
+		if !reflect.DeepEqual(got.Bids, book.Bids) || !reflect.DeepEqual(got.Asks, book.Asks) {
+			t.Errorf("service.GetBook() = %+v, want %+v", got, book)
+		}
+	})
+
+	t.Run("should restore the trades from the order events", func(t *testing.T) {
+		got, _ := restarted.GetTrades(ctx, orderbook.BtcUsd, Page{})
+		if len(got.Trades) != 1 || !sameTrade(got.Trades[0], trades.Trades[0]) {
+			t.Errorf("service.GetTrades() = %+v, want %+v", got.Trades, trades.Trades)
+		}
+	})
+
+	t.Run("should match against the restored orders in price-time priority", func(t *testing.T) {
+		taker := place(restarted, "dave", newLimitOrder(orderbook.Buy, 2, 101))
+		p, _ := restarted.GetFills(ctx, taker, Page{})
+		if len(p.Trades) != 2 || p.Trades[1].MakerOrderID != partial || !p.Trades[1].Size.Equal(decimal.NewFromInt(1)) ||
+			p.Trades[0].MakerOrderID != resting {
+			t.Errorf("service.GetFills() = %+v, want the remaining size of %v before %v", p.Trades, partial, resting)
+		}
+	})
+
+	t.Run("should park the restored stop orders", func(t *testing.T) {
+		if got := d.Matcher.Trigger(orderbook.BtcUsd, decimal.NewFromInt(90)); !reflect.DeepEqual(got, []string{stop}) {
+			t.Errorf("Matcher.Trigger() = %v, want %v", got, []string{stop})
+		}
+	})
+
+	t.Run("should hold the restored orders of a product in auction", func(t *testing.T) {
+		restarted.SetTradingStatus(ctx, orderbook.BtcUsd, trading.Halted, "incident")
+		restarted.SetTradingStatus(ctx, orderbook.BtcUsd, trading.Auction, "reopening")
+		place(restarted, "alice", newLimitOrder(orderbook.Sell, 1, 95))
+		place(restarted, "bob", newLimitOrder(orderbook.Buy, 1, 96))
+		want, _ := restarted.GetAuction(ctx, orderbook.BtcUsd)
+
+		again, _ := start(t)
+		if got, err := again.GetAuction(ctx, orderbook.BtcUsd); err != nil || !reflect.DeepEqual(got, want) || !got.Volume.IsPositive() {
+			t.Errorf("service.GetAuction() = %+v, %v, want %+v", got, err, want)
+		}
+	})
+}
+
+func TestRestore_expiry(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	idg := &sequenceIDGenerator{}
+	wallet := &mockWallet{}
+
+	// start returns a service on the order events of the store and the scheduler it was restored with
+	start := func(t *testing.T) (Service, *mockScheduler) {
+		books := NewBookProjection()
+		scheduler := &mockScheduler{}
+		d := testDependencies(Dependencies{
+			IDGenerator: idg,
+			Repository:  newRepository(store, NewMemoryDedupeIndex(), books.On),
+			Books:       books,
+			Scheduler:   scheduler,
+			Wallet:      wallet,
+		})
+		if err := Restore(ctx, d); err != nil {
+			t.Fatalf("Restore() error = %v", err)
+		}
+		return NewService(d), scheduler
+	}
+	place := func(s Service, account string, side orderbook.OrderSide, price int64, expireTime time.Time) string {
+		spec := newLimitOrder(side, 1, price)
+		spec.AccountID = account
+		spec.TimeInForce = orderbook.GoodTilTime
+		spec.ExpireTime = expireTime
+		id, err := s.CreateOrder(ctx, spec)
+		if err != nil {
+			t.Fatalf("service.CreateOrder() error = %v", err)
+		}
+		s.AcceptOrder(ctx, id)
+		if err := s.PublishOrder(ctx, id); err != nil {
+			t.Fatalf("service.PublishOrder() error = %v", err)
+		}
+		return id
+	}
+
+	s, _ := start(t)
+	later := place(s, "alice", orderbook.Sell, 110, time.Now().Add(time.Hour))
+	due := place(s, "bob", orderbook.Buy, 90, time.Now().Add(10*time.Millisecond))
+	canceled := place(s, "carol", orderbook.Buy, 80, time.Now().Add(time.Hour))
+	s.CancelOrder(ctx, canceled)
+
+	time.Sleep(20 * time.Millisecond)
+	restarted, scheduler := start(t)
+
+	t.Run("should schedule the expiry of the open good til time orders again", func(t *testing.T) {
+		if !reflect.DeepEqual(scheduler.scheduled, []string{later}) {
+			t.Errorf("Scheduler.Schedule() = %v, want %v", scheduler.scheduled, []string{later})
+		}
+	})
+
+	t.Run("should expire the orders whose expire time passed during the restart", func(t *testing.T) {
+		o, _ := restarted.GetOrder(ctx, due)
+		if o.State() != "expired" {
+			t.Errorf("service.GetOrder() state = %v, want expired", o.State())
+		}
+		if !reflect.DeepEqual(wallet.closed, []string{canceled, due}) {
+			t.Errorf("Wallet.Close() = %v, want %v", wallet.closed, []string{canceled, due})
+		}
+		book, _ := restarted.GetBook(ctx, orderbook.BtcUsd, 2, 0)
+		if len(book.Bids) != 0 {
+			t.Errorf("service.GetBook() bids = %+v, want none", book.Bids)
+		}
+	})
+}
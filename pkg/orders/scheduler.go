@@ -0,0 +1,101 @@
+package orders
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/go-kit/kit/log"
+)
+
+// Scheduler schedules the expiry of good til time orders
+type Scheduler interface {
+	Schedule(id string, productID orderbook.ProductID, expireTime time.Time)
+}
+
+// ExpiryScheduler expires good til time orders through the Service once they reach their expire time.
+// Scheduled expiries are kept in memory only.
+type ExpiryScheduler struct {
+	logger log.Logger
+
+	mux   sync.Mutex
+	queue expiryQueue
+}
+
+// NewExpiryScheduler returns an ExpiryScheduler, Run has to be called to expire orders
+func NewExpiryScheduler(logger log.Logger) *ExpiryScheduler {
+	return &ExpiryScheduler{
+		logger: logger,
+	}
+}
+
+// Schedule the expiry of an order
+func (s *ExpiryScheduler) Schedule(id string, productID orderbook.ProductID, expireTime time.Time) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	heap.Push(&s.queue, expiry{id: id, productID: productID, at: expireTime})
+}
+
+// Run expires due orders through the Service every interval until the context is done
+func (s *ExpiryScheduler) Run(ctx context.Context, svc Service, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			s.Expire(ctx, svc, now)
+		}
+	}
+}
+
+// Expire all orders with an expire time before now.
+// Orders which were filled or canceled in the meantime are skipped.
+func (s *ExpiryScheduler) Expire(ctx context.Context, svc Service, now time.Time) {
+	ctx = orderbook.NewContext(ctx, expiryMetadata)
+	for _, e := range s.due(now) {
+		err := svc.ExpireOrder(ctx, e.id)
+		if err == orderbook.ErrInvalidStateTransition {
+			continue
+		}
+		if err != nil {
+			s.logger.Log("method", "Expire", "id", e.id, "product_id", e.productID, "err", err)
+		}
+	}
+}
+
+func (s *ExpiryScheduler) due(now time.Time) []expiry {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	due := []expiry{}
+	for s.queue.Len() > 0 && !s.queue[0].at.After(now) {
+		due = append(due, heap.Pop(&s.queue).(expiry))
+	}
+	return due
+}
+
+type expiry struct {
+	id        string
+	productID orderbook.ProductID
+	at        time.Time
+}
+
+// expiryQueue implements heap.Interface ordered by expire time
+type expiryQueue []expiry
+
+func (q expiryQueue) Len() int            { return len(q) }
+func (q expiryQueue) Less(i, j int) bool  { return q[i].at.Before(q[j].at) }
+func (q expiryQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *expiryQueue) Push(x interface{}) { *q = append(*q, x.(expiry)) }
+func (q *expiryQueue) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
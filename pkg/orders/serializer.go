@@ -0,0 +1,129 @@
+package orders
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/altairsix/eventsource"
+)
+
+// Upcaster transforms the fields of a serialized event from one schema version to the next
+type Upcaster func(fields map[string]json.RawMessage) error
+
+// schemaEvent is the serialized form of an event, records written before
+// schema versions were introduced have no version and are version 1
+type schemaEvent struct {
+	Type    string          `json:"t"`
+	Version int             `json:"v,omitempty"`
+	Data    json.RawMessage `json:"d"`
+}
+
+// schemaSerializer is an eventsource.Serializer which stores the schema version of each event
+// and upcasts events of older schema versions to the current event types at load time.
+type schemaSerializer struct {
+	types     map[string]reflect.Type
+	versions  map[string]int
+	upcasters map[string]map[int]Upcaster
+}
+
+func newSchemaSerializer() *schemaSerializer {
+	return &schemaSerializer{
+		types:     map[string]reflect.Type{},
+		versions:  map[string]int{},
+		upcasters: map[string]map[int]Upcaster{},
+	}
+}
+
+// Bind registers events with their current schema version
+func (s *schemaSerializer) Bind(version int, events ...eventsource.Event) *schemaSerializer {
+	for _, event := range events {
+		eventType, t := eventsource.EventType(event)
+		s.types[eventType] = t
+		s.versions[eventType] = version
+	}
+	return s
+}
+
+// Upcast registers an upcaster which transforms an event from version to version+1
+func (s *schemaSerializer) Upcast(event eventsource.Event, version int, upcaster Upcaster) *schemaSerializer {
+	eventType, _ := eventsource.EventType(event)
+	if s.upcasters[eventType] == nil {
+		s.upcasters[eventType] = map[int]Upcaster{}
+	}
+	s.upcasters[eventType][version] = upcaster
+	return s
+}
+
+// MarshalEvent converts an event into a record with the current schema version
+func (s *schemaSerializer) MarshalEvent(event eventsource.Event) (eventsource.Record, error) {
+	eventType, _ := eventsource.EventType(event)
+	version, ok := s.versions[eventType]
+	if !ok {
+		return eventsource.Record{}, eventsource.NewError(nil, eventsource.ErrUnboundEventType, "unbound event type, %v", eventType)
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return eventsource.Record{}, err
+	}
+
+	data, err = json.Marshal(schemaEvent{Type: eventType, Version: version, Data: data})
+	if err != nil {
+		return eventsource.Record{}, eventsource.NewError(err, eventsource.ErrInvalidEncoding, "unable to encode event")
+	}
+
+	return eventsource.Record{Version: event.EventVersion(), Data: data}, nil
+}
+
+// UnmarshalEvent converts a record into the current event type, upcasting it if necessary
+func (s *schemaSerializer) UnmarshalEvent(record eventsource.Record) (eventsource.Event, error) {
+	e := schemaEvent{}
+	if err := json.Unmarshal(record.Data, &e); err != nil {
+		return nil, eventsource.NewError(err, eventsource.ErrInvalidEncoding, "unable to unmarshal event")
+	}
+
+	t, ok := s.types[e.Type]
+	if !ok {
+		return nil, eventsource.NewError(nil, eventsource.ErrUnboundEventType, "unbound event type, %v", e.Type)
+	}
+
+	data, err := s.upcast(e)
+	if err != nil {
+		return nil, eventsource.NewError(err, eventsource.ErrInvalidEncoding, "unable to upcast event, %v", e.Type)
+	}
+
+	v := reflect.New(t).Interface()
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, eventsource.NewError(err, eventsource.ErrInvalidEncoding, "unable to unmarshal event data into %#v", v)
+	}
+	return v.(eventsource.Event), nil
+}
+
+func (s *schemaSerializer) upcast(e schemaEvent) (json.RawMessage, error) {
+	version, current := e.Version, s.versions[e.Type]
+	if version == 0 {
+		version = 1
+	}
+	if version > current {
+		return nil, fmt.Errorf("schema version %d is newer than %d", version, current)
+	}
+	if version == current {
+		return e.Data, nil
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(e.Data, &fields); err != nil {
+		return nil, err
+	}
+	for ; version < current; version++ {
+		upcaster, ok := s.upcasters[e.Type][version]
+		if !ok {
+			return nil, fmt.Errorf("no upcaster from schema version %d", version)
+		}
+		if err := upcaster(fields); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(fields)
+}
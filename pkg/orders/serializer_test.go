@@ -0,0 +1,116 @@
+package orders
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/altairsix/eventsource"
+)
+
+// corpus is a stream of serialized events of a past build and the Order it must replay to
+type corpus struct {
+	Records []struct {
+		Version int             `json:"version"`
+		Data    json.RawMessage `json:"data"`
+	} `json:"records"`
+	Want map[string]interface{} `json:"want"`
+}
+
+func Test_serializer_replaysCorpus(t *testing.T) {
+	files, err := filepath.Glob("testdata/events/*.json")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no corpus in testdata/events, error = %v", err)
+	}
+
+	covered := map[string]bool{}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			b, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatalf("ioutil.ReadFile() error = %v", err)
+			}
+			var c corpus
+			if err := json.Unmarshal(b, &c); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+
+			o := orderbook.Order{}
+			for _, r := range c.Records {
+				var e schemaEvent
+				json.Unmarshal(r.Data, &e)
+				if e.Version == serializer.versions[e.Type] {
+					covered[e.Type] = true
+				}
+
+				event, err := serializer.UnmarshalEvent(eventsource.Record{Version: r.Version, Data: r.Data})
+				if err != nil {
+					t.Fatalf("serializer.UnmarshalEvent() error = %v", err)
+				}
+				if err := o.On(event); err != nil {
+					t.Fatalf("Order.On() error = %v", err)
+				}
+			}
+
+			b, _ = json.Marshal(o)
+			got := map[string]interface{}{}
+			json.Unmarshal(b, &got)
+			for field, want := range c.Want {
+				if !reflect.DeepEqual(got[field], want) {
+					t.Errorf("Order.%s = %v, want %v", field, got[field], want)
+				}
+			}
+		})
+	}
+
+	// every event has to be replayed at least once in its current schema version
+	for eventType := range serializer.types {
+		if !covered[eventType] {
+			t.Errorf("no %v of schema version %d in testdata/events", eventType, serializer.versions[eventType])
+		}
+	}
+}
+
+func Test_serializer_UnmarshalEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    eventsource.Event
+		wantErr bool
+	}{
+		{"should upcast the legacy product id",
+			`{"t":"OrderCreated","d":{"ProductID":0,"Size":1.34}}`,
+			&orderbook.OrderCreated{ProductID: orderbook.BtcUsd, Size: decimal.RequireFromString("1.34")}, false},
+		{"should upcast the owner to the account id",
+			`{"t":"OrderCreated","v":2,"d":{"ProductID":"BTC-USD","Owner":"desk-1"}}`,
+			&orderbook.OrderCreated{ProductID: orderbook.BtcUsd, AccountID: "desk-1"}, false},
+		{"should upcast a cancel without reason",
+			`{"t":"OrderCanceled","d":{}}`,
+			&orderbook.OrderCanceled{Reason: orderbook.CanceledByUser}, false},
+		{"should not upcast the current schema version",
+			`{"t":"OrderCanceled","v":2,"d":{"Reason":"post_only"}}`,
+			&orderbook.OrderCanceled{Reason: orderbook.CanceledByPostOnly}, false},
+		{"should return error for an unknown legacy product id",
+			`{"t":"OrderCreated","d":{"ProductID":7}}`, nil, true},
+		{"should return error for a schema version newer than the build",
+			`{"t":"OrderCanceled","v":3,"d":{}}`, nil, true},
+		{"should return error for an unbound event type",
+			`{"t":"OrderVanished","d":{}}`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := serializer.UnmarshalEvent(eventsource.Record{Version: 1, Data: []byte(tt.data)})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("serializer.UnmarshalEvent() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("serializer.UnmarshalEvent() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,34 +3,106 @@ package orders
 import (
 	"context"
 	"errors"
+	"sync"
+	"time"
 
+	"github.com/LAtanassov/godax/pkg/clearing"
+	"github.com/LAtanassov/godax/pkg/confirmation"
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/ledger"
 	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/LAtanassov/godax/pkg/products"
+	"github.com/LAtanassov/godax/pkg/trading"
+	"github.com/LAtanassov/godax/pkg/wallet"
 	"github.com/altairsix/eventsource"
 )
 
 var (
 	// ErrTypeCast represents a unexpected type cast error
 	ErrTypeCast = errors.New("type cast failed")
+	// ErrInsufficientLiquidity is returned when a market buy is larger than the asks on the book, its value can not be held
+	ErrInsufficientLiquidity = errors.New("insufficient liquidity")
 )
 
+// OrderSpec specifies an order to create
+type OrderSpec struct {
+	Size      decimal.Decimal
+	Price     decimal.Decimal
+	StopPrice decimal.Decimal // only used by stop orders
+	OrderType orderbook.OrderType
+	OrderSide orderbook.OrderSide
+	ProductID orderbook.ProductID
+
+	TimeInForce orderbook.TimeInForce
+	ExpireTime  time.Time // only used by good til time orders
+
+	AccountID           string // orders of the same account never match each other
+	PostOnly            bool
+	SelfTradePrevention orderbook.SelfTradePrevention
+
+	ClientOID      string // stored on the order, a repeated client order id of the account returns the original order
+	IdempotencyKey string // not stored, a repeated key of the account returns the original order
+}
+
 // Service specifies methods for Order API.
 type Service interface {
 	// CreateNewOrder create a new order
-	CreateOrder(ctx context.Context, size, price float32,
-		orderType orderbook.OrderType, side orderbook.OrderSide, productID orderbook.ProductID) (string, error)
+	CreateOrder(ctx context.Context, spec OrderSpec) (string, error)
 	// CreateNewOrder create a new order
 	GetOrder(ctx context.Context, id string) (orderbook.Order, error)
+	// GetOrderAt returns the order as it was at a point in time
+	GetOrderAt(ctx context.Context, id string, asOf time.Time) (orderbook.Order, error)
+	// GetOrderAtVersion returns the order as it was after the event with the version was applied
+	GetOrderAtVersion(ctx context.Context, id string, version int) (orderbook.Order, error)
+	// GetBook returns the book of a product at level 1 (best bid and ask), 2 (top depth price levels, all if 0) or 3 (all orders)
+	GetBook(ctx context.Context, productID orderbook.ProductID, level, depth int) (orderbook.BookSnapshot, error)
+	// GetTrades returns a page of the trades of a product, newest first
+	GetTrades(ctx context.Context, productID orderbook.ProductID, page Page) (TradePage, error)
+	// GetFills returns a page of the trades of an Order, newest first
+	GetFills(ctx context.Context, id string, page Page) (TradePage, error)
+
+	// Deposit adds funds to an account
+	Deposit(ctx context.Context, accountID, currency string, amount decimal.Decimal) error
+	// GetBalances returns the total, held and available funds of every currency of an account
+	GetBalances(ctx context.Context, accountID string) (map[string]wallet.Balance, error)
+	// GetTrialBalance returns the balance of every account and currency of the settlement ledger
+	GetTrialBalance(ctx context.Context) (ledger.TrialBalance, error)
+	// GetClearingCycles returns the cleared cycles with their net instructions, newest first
+	GetClearingCycles(ctx context.Context) ([]clearing.Cycle, error)
+	// GetClearingCycle returns a cleared cycle with its net instructions
+	GetClearingCycle(ctx context.Context, id string) (clearing.Cycle, error)
 	// CancelOrder cancels an existing Order
 	CancelOrder(ctx context.Context, id string) error
+	// ExpireOrder expires an open Order, it is issued by the ExpiryScheduler once the expire time of the Order is reached
+	ExpireOrder(ctx context.Context, id string) error
+	// AmendOrder replaces size and price of an existing Order, a zero size or price keeps the current value
+	AmendOrder(ctx context.Context, id string, size, price decimal.Decimal) error
 
 	// AcceptOrder accepts an existing Order
 	AcceptOrder(ctx context.Context, id string) error
+	// RejectOrder rejects an existing Order with a reason code and a free text message
+	RejectOrder(ctx context.Context, id string, reason orderbook.RejectReason, message string) error
 	// PublishOrder publish an existing Order
 	PublishOrder(ctx context.Context, id string) error
-	// MatchOrder matches an existing Order
-	MatchOrder(ctx context.Context, id string) error
-	// ConfirmOrder confirms an existing Order
+	// TriggerOrders activates the stop orders of a product reached by the last trade price
+	TriggerOrders(ctx context.Context, productID orderbook.ProductID, lastPrice decimal.Decimal) error
+	// ConfirmOrder confirms an existing Order, it is issued once both parties confirmed all trades of the Order
 	ConfirmOrder(ctx context.Context, id string) error
+	// ConfirmTrade confirms a trade for the party of the account
+	ConfirmTrade(ctx context.Context, tradeID, accountID string) error
+	// DisputeTrade disputes a trade for the party of the account, the trade stays unconfirmed until the dispute is resolved
+	DisputeTrade(ctx context.Context, tradeID, accountID, message string) error
+	// ResolveDispute resolves the dispute of a trade, the trade stands and is confirmed
+	ResolveDispute(ctx context.Context, tradeID, message string) error
+	// GetConfirmation returns the confirmation of a trade
+	GetConfirmation(ctx context.Context, tradeID string) (confirmation.Confirmation, error)
+	// SetTradingStatus changes the trading status of a product, held orders are matched once the product matches again
+	// and the auction is uncrossed once the product opens
+	SetTradingStatus(ctx context.Context, productID orderbook.ProductID, status trading.Status, reason string) error
+	// GetTradingStatus returns the trading status of a product
+	GetTradingStatus(ctx context.Context, productID orderbook.ProductID) (trading.Market, error)
+	// GetAuction returns the indicative uncross price, volume and imbalance of a product in auction
+	GetAuction(ctx context.Context, productID orderbook.ProductID) (orderbook.Uncross, error)
 	// ClearOrder clears an existing Order
 	ClearOrder(ctx context.Context, id string) error
 	// SettleOrder settles an existing Order
@@ -43,38 +115,235 @@ type ServiceMiddleware func(Service) Service
 type service struct {
 	idGenerator Generator
 	repository  Repository
+	matcher     Matcher
+	catalog     products.Catalog
+	scheduler   Scheduler
+	volumes     VolumeTracker
+	books       BookProjection
+	trades      TradeHistory
+	wallet      Wallet
+	ledger      Ledger
+	clearing    Clearing
+
+	confirmations Confirmations
+	markets       Markets
+
+	mux      sync.Mutex
+	products map[orderbook.ProductID]*sync.Mutex
+}
+
+// Dependencies of the booking service, all of them are required.
+type Dependencies struct {
+	IDGenerator Generator
+	Repository  Repository
+	Matcher     Matcher
+	Catalog     products.Catalog
+	Scheduler   Scheduler
+	Volumes     VolumeTracker
+	Books       BookProjection
+	Trades      TradeHistory
+	Wallet      Wallet
+	Ledger      Ledger
+	Clearing    Clearing
+
+	Confirmations Confirmations
+	Markets       Markets
 }
 
 // NewService creates a booking service with necessary dependencies.
-func NewService(idGenerator Generator, repository Repository) Service {
+func NewService(d Dependencies) Service {
 	return &service{
-		idGenerator: idGenerator,
-		repository:  repository,
+		idGenerator: d.IDGenerator,
+		repository:  d.Repository,
+		matcher:     d.Matcher,
+		catalog:     d.Catalog,
+		scheduler:   d.Scheduler,
+		volumes:     d.Volumes,
+		books:       d.Books,
+		trades:      d.Trades,
+		wallet:      d.Wallet,
+		ledger:      d.Ledger,
+		clearing:    d.Clearing,
+
+		confirmations: d.Confirmations,
+		markets:       d.Markets,
+
+		products: map[orderbook.ProductID]*sync.Mutex{},
+	}
+}
+
+// productLock returns the lock of a product which is held while an order of the product is submitted to the
+// matching engine and its matches are applied, so the event store sees the executions in the order of the engine
+func (s *service) productLock(productID orderbook.ProductID) *sync.Mutex {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	l, ok := s.products[productID]
+	if !ok {
+		l = &sync.Mutex{}
+		s.products[productID] = l
 	}
+	return l
 }
 
 // CreateOrder creates a CreateOrder command and apply it on the Order.
-func (s *service) CreateOrder(ctx context.Context, size, price float32,
-	orderType orderbook.OrderType, orderSide orderbook.OrderSide, productID orderbook.ProductID) (string, error) {
+// A retried request with the client order id or idempotency key of an earlier request returns the id of the earlier Order.
+// The funds of the Order are held until it is closed, the expiry of good til time orders is scheduled.
+// Orders are rejected unless the trading status of the product accepts them, retries are deduplicated first
+// so that a retry during a halt still returns the earlier Order.
+func (s *service) CreateOrder(ctx context.Context, spec OrderSpec) (string, error) {
+
+	product, err := s.catalog.Get(spec.ProductID)
+	if err != nil {
+		return "", err
+	}
+	if err := product.ValidateOrder(spec.Size, spec.Price, spec.StopPrice, spec.OrderType); err != nil {
+		return "", err
+	}
 
 	id := s.idGenerator.Generate()
+	keys := dedupeKeys(spec)
+	for i, key := range keys {
+		existing, err := s.repository.Reserve(ctx, key, id)
+		if err != nil || existing != id {
+			s.release(ctx, keys[:i], id)
+			return existing, err
+		}
+	}
+
+	if err := s.accepts(ctx, spec.ProductID, spec.PostOnly); err != nil {
+		s.release(ctx, keys, id)
+		return "", err
+	}
+
+	currency, amount, err := s.holdAmount(spec, product)
+	if err != nil {
+		s.release(ctx, keys, id)
+		return "", err
+	}
+	if err := s.wallet.Hold(ctx, spec.AccountID, id, currency, amount); err != nil {
+		s.release(ctx, keys, id)
+		return "", err
+	}
+
 	createOrder := &orderbook.CreateOrder{
-		Size:      size,
-		Price:     price,
-		OrderType: orderType,
-		OrderSide: orderSide,
-		ProductID: productID,
+		Size:      spec.Size,
+		Price:     spec.Price,
+		StopPrice: spec.StopPrice,
+		OrderType: spec.OrderType,
+		OrderSide: spec.OrderSide,
+		ProductID: spec.ProductID,
 
+		TimeInForce: spec.TimeInForce,
+		ExpireTime:  spec.ExpireTime,
+
+		AccountID:           spec.AccountID,
+		ClientOID:           spec.ClientOID,
+		PostOnly:            spec.PostOnly,
+		SelfTradePrevention: spec.SelfTradePrevention,
+
+		Metadata:     orderbook.MetadataFromContext(ctx),
 		CommandModel: eventsource.CommandModel{ID: id},
 	}
 
-	_, err := s.repository.Apply(ctx, createOrder)
+	_, err = s.repository.Apply(ctx, createOrder)
 	if err != nil {
+		s.release(ctx, keys, id)
+		s.wallet.Release(ctx, spec.AccountID, id)
 		return "", err
 	}
+
+	if spec.TimeInForce == orderbook.GoodTilTime {
+		s.scheduler.Schedule(id, spec.ProductID, spec.ExpireTime)
+	}
 	return id, nil
 }
 
+// holdAmount returns the currency and the funds to hold for an order, the size for sells and
+// the value including the highest taker fee for buys. Stop buys are valued at their stop price and
+// market buys by the asks on the book, a higher execution price is debited from the available funds at settlement.
+// Market buys larger than the asks on the book are rejected with ErrInsufficientLiquidity rather than held partly,
+// orders whose value does not fit into a decimal are rejected with decimal.ErrOutOfRange.
+func (s *service) holdAmount(spec OrderSpec, product products.Product) (string, decimal.Decimal, error) {
+	if spec.OrderSide == orderbook.Sell {
+		return product.BaseCurrency, spec.Size, nil
+	}
+
+	var value decimal.Decimal
+	var err error
+	switch {
+	case spec.OrderType.IsLimit():
+		value, err = spec.Size.CheckedMul(spec.Price)
+	case spec.OrderType.IsStop():
+		value, err = spec.Size.CheckedMul(spec.StopPrice)
+	default:
+		value, err = s.marketValue(spec.ProductID, spec.Size)
+	}
+	if err != nil {
+		return "", decimal.Zero, err
+	}
+	fee, err := value.CheckedMul(product.FeeRate(orderbook.Taker, decimal.Zero))
+	if err != nil {
+		return "", decimal.Zero, err
+	}
+	value, err = value.CheckedAdd(fee)
+	return product.QuoteCurrency, value, err
+}
+
+// specOf returns the spec of an order to compute its hold
+func specOf(o orderbook.Order) OrderSpec {
+	return OrderSpec{
+		Size:      o.Size,
+		Price:     o.Price,
+		StopPrice: o.StopPrice,
+		OrderType: o.OrderType,
+		OrderSide: o.OrderSide,
+		ProductID: o.ProductID,
+	}
+}
+
+// marketValue returns the value of buying size at the asks of the book or ErrInsufficientLiquidity if the book is too thin
+func (s *service) marketValue(productID orderbook.ProductID, size decimal.Decimal) (decimal.Decimal, error) {
+	value := decimal.Zero
+	for _, ask := range s.books.Snapshot(productID, 2, 0).Asks {
+		if !size.IsPositive() {
+			break
+		}
+		fill := size.Min(ask.Size)
+		v, err := fill.CheckedMul(ask.Price)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		if value, err = value.CheckedAdd(v); err != nil {
+			return decimal.Zero, err
+		}
+		size = size.Sub(fill)
+	}
+	if size.IsPositive() {
+		return decimal.Zero, ErrInsufficientLiquidity
+	}
+	return value, nil
+}
+
+// dedupeKeys returns the keys of the dedupe index which identify a retried request, keys are scoped to the account
+func dedupeKeys(spec OrderSpec) []string {
+	keys := []string{}
+	if spec.ClientOID != "" {
+		keys = append(keys, spec.AccountID+"/client_oid/"+spec.ClientOID)
+	}
+	if spec.IdempotencyKey != "" {
+		keys = append(keys, spec.AccountID+"/idempotency_key/"+spec.IdempotencyKey)
+	}
+	return keys
+}
+
+// release frees the keys of an Order which was not created
+func (s *service) release(ctx context.Context, keys []string, id string) {
+	for _, key := range keys {
+		s.repository.Release(ctx, key, id)
+	}
+}
+
 // GetOrder loads and returns the order from the repository
 func (s *service) GetOrder(ctx context.Context, id string) (orderbook.Order, error) {
 
@@ -90,10 +359,74 @@ func (s *service) GetOrder(ctx context.Context, id string) (orderbook.Order, err
 	return *o, nil
 }
 
-// CancelOrder creates a CancelOrder command and apply it on the Order.
+// GetBook returns the book of a product from the BookProjection
+func (s *service) GetBook(ctx context.Context, productID orderbook.ProductID, level, depth int) (orderbook.BookSnapshot, error) {
+	if _, err := s.catalog.Get(productID); err != nil {
+		return orderbook.BookSnapshot{}, err
+	}
+	return s.books.Snapshot(productID, level, depth), nil
+}
+
+// GetTrades returns the trades of a product from the TradeHistory
+func (s *service) GetTrades(ctx context.Context, productID orderbook.ProductID, page Page) (TradePage, error) {
+	if _, err := s.catalog.Get(productID); err != nil {
+		return TradePage{}, err
+	}
+	return s.trades.Trades(productID, page), nil
+}
+
+// GetFills returns the trades of an existing Order from the TradeHistory
+func (s *service) GetFills(ctx context.Context, id string, page Page) (TradePage, error) {
+	if _, err := s.GetOrder(ctx, id); err != nil {
+		return TradePage{}, err
+	}
+	return s.trades.Fills(id, page), nil
+}
+
+// Deposit adds funds to the Wallet of an account
+func (s *service) Deposit(ctx context.Context, accountID, currency string, amount decimal.Decimal) error {
+	return s.wallet.Deposit(ctx, accountID, currency, amount)
+}
+
+// GetBalances returns the balances of an account from the Wallet
+func (s *service) GetBalances(ctx context.Context, accountID string) (map[string]wallet.Balance, error) {
+	return s.wallet.Balances(ctx, accountID)
+}
+
+// GetTrialBalance returns the trial balance of the Ledger
+func (s *service) GetTrialBalance(ctx context.Context) (ledger.TrialBalance, error) {
+	return s.ledger.TrialBalance(ctx), nil
+}
+
+// GetClearingCycles returns the cycles of the clearing house
+func (s *service) GetClearingCycles(ctx context.Context) ([]clearing.Cycle, error) {
+	return s.clearing.Cycles(ctx)
+}
+
+// GetClearingCycle returns a cycle of the clearing house
+func (s *service) GetClearingCycle(ctx context.Context, id string) (clearing.Cycle, error) {
+	return s.clearing.Cycle(ctx, id)
+}
+
+// closeOrder transfers the fills and releases the hold of the Order once it is closed.
+// An Order canceled or expired with fills is not closed before it is settled, it is confirmed
+// here if all its trades were confirmed while it was still open.
+func (s *service) closeOrder(ctx context.Context, id string) error {
+	o, err := s.GetOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.wallet.Close(ctx, o); err != nil {
+		return err
+	}
+	return s.confirmOrder(ctx, o)
+}
+
+// CancelOrder creates a CancelOrder command, apply it on the Order and closes it.
 func (s *service) CancelOrder(ctx context.Context, id string) error {
 
 	cancelOrder := &orderbook.CancelOrder{
+		Metadata:     orderbook.MetadataFromContext(ctx),
 		CommandModel: eventsource.CommandModel{ID: id},
 	}
 
@@ -101,6 +434,136 @@ func (s *service) CancelOrder(ctx context.Context, id string) error {
 	if err != nil {
 		return err
 	}
+
+	o, err := s.GetOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	s.matcher.Cancel(o.ProductID, id)
+	return s.closeOrder(ctx, id)
+}
+
+// ExpireOrder creates an ExpireOrder command, apply it on the Order, removes it from the matching engine and closes it.
+func (s *service) ExpireOrder(ctx context.Context, id string) error {
+
+	expireOrder := &orderbook.ExpireOrder{
+		Metadata:     orderbook.MetadataFromContext(ctx),
+		CommandModel: eventsource.CommandModel{ID: id},
+	}
+
+	_, err := s.repository.Apply(ctx, expireOrder)
+	if err != nil {
+		return err
+	}
+
+	o, err := s.GetOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	s.matcher.Cancel(o.ProductID, id)
+	return s.closeOrder(ctx, id)
+}
+
+// GetOrderAt replays the events of the order which happened until asOf
+func (s *service) GetOrderAt(ctx context.Context, id string, asOf time.Time) (orderbook.Order, error) {
+
+	events, err := s.repository.History(ctx, id, 0)
+	if err != nil {
+		return orderbook.Order{}, err
+	}
+
+	n := 0
+	for n < len(events) && !events[n].EventAt().After(asOf) {
+		n++
+	}
+	return replay(id, events[:n])
+}
+
+// GetOrderAtVersion replays the events of the order up to version
+func (s *service) GetOrderAtVersion(ctx context.Context, id string, version int) (orderbook.Order, error) {
+
+	events, err := s.repository.History(ctx, id, version)
+	if err != nil {
+		return orderbook.Order{}, err
+	}
+	return replay(id, events)
+}
+
+// replay applies the events on a new order
+func replay(id string, events []eventsource.Event) (orderbook.Order, error) {
+	if len(events) == 0 {
+		return orderbook.Order{}, eventsource.NewError(nil, eventsource.ErrAggregateNotFound, "order %v did not exist yet", id)
+	}
+
+	o := orderbook.Order{}
+	for _, event := range events {
+		if err := o.On(event); err != nil {
+			return orderbook.Order{}, err
+		}
+	}
+	return o, nil
+}
+
+// AmendOrder creates a ReplaceOrder command and apply it on the Order.
+// The hold of the Order is resized to the amended size and price first, wallet.ErrInsufficientFunds rejects the amendment.
+// A resting Order which lost its priority is matched again at the end of the queue.
+func (s *service) AmendOrder(ctx context.Context, id string, size, price decimal.Decimal) error {
+
+	o, err := s.GetOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	product, err := s.catalog.Get(o.ProductID)
+	if err != nil {
+		return err
+	}
+	amended := o
+	if !size.IsZero() {
+		amended.Size = size
+	}
+	if !price.IsZero() {
+		amended.Price = price
+	}
+	if err := product.ValidateOrder(amended.Size, amended.Price, o.StopPrice, o.OrderType); err != nil {
+		return err
+	}
+	if err := s.accepts(ctx, o.ProductID, o.PostOnly); err != nil {
+		return err
+	}
+
+	// the hold follows the amended size and price, the amendment is rejected if the funds are short
+	_, held, err := s.holdAmount(specOf(o), product)
+	if err != nil {
+		return err
+	}
+	_, hold, err := s.holdAmount(specOf(amended), product)
+	if err != nil {
+		return err
+	}
+	if err := s.wallet.Resize(ctx, o.AccountID, id, hold); err != nil {
+		return err
+	}
+
+	replaceOrder := &orderbook.ReplaceOrder{
+		Size:         size,
+		Price:        price,
+		Metadata:     orderbook.MetadataFromContext(ctx),
+		CommandModel: eventsource.CommandModel{ID: id},
+	}
+	if _, err := s.repository.Apply(ctx, replaceOrder); err != nil {
+		s.wallet.Resize(ctx, o.AccountID, id, held)
+		return err
+	}
+
+	if amended, err = s.GetOrder(ctx, id); err != nil {
+		return err
+	}
+	if s.matcher.Amend(amended, replaceOrder.RetainsPriority(o)) {
+		return s.match(ctx, id, amended)
+	}
 	return nil
 }
 
@@ -108,6 +571,7 @@ func (s *service) CancelOrder(ctx context.Context, id string) error {
 func (s *service) AcceptOrder(ctx context.Context, id string) error {
 
 	acceptOrder := &orderbook.AcceptOrder{
+		Metadata:     orderbook.MetadataFromContext(ctx),
 		CommandModel: eventsource.CommandModel{ID: id},
 	}
 
@@ -118,38 +582,543 @@ func (s *service) AcceptOrder(ctx context.Context, id string) error {
 	return nil
 }
 
-// PublishOrder creates a PublishOrder command and apply it on the Order.
+// RejectOrder creates a RejectOrder command, apply it on the Order and releases its hold.
+func (s *service) RejectOrder(ctx context.Context, id string, reason orderbook.RejectReason, message string) error {
+
+	rejectOrder := &orderbook.RejectOrder{
+		Reason:       reason,
+		Message:      message,
+		Metadata:     orderbook.MetadataFromContext(ctx),
+		CommandModel: eventsource.CommandModel{ID: id},
+	}
+
+	_, err := s.repository.Apply(ctx, rejectOrder)
+	if err != nil {
+		return err
+	}
+	return s.closeOrder(ctx, id)
+}
+
+// PublishOrder creates a PublishOrder command, apply it on the Order
+// and submits the published Order to the matching engine.
+// Stop orders are parked until their stop price is reached.
+// Orders of a product which does not accept them stay accepted and can be published later.
 func (s *service) PublishOrder(ctx context.Context, id string) error {
 
+	accepted, err := s.GetOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.accepts(ctx, accepted.ProductID, accepted.PostOnly); err != nil {
+		return err
+	}
+
 	publishOrder := &orderbook.PublishOrder{
+		Metadata:     orderbook.MetadataFromContext(ctx),
 		CommandModel: eventsource.CommandModel{ID: id},
 	}
 
-	_, err := s.repository.Apply(ctx, publishOrder)
+	_, err = s.repository.Apply(ctx, publishOrder)
+	if err != nil {
+		return err
+	}
+
+	o, err := s.GetOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if o.OrderType.IsStop() {
+		s.matcher.Park(o)
+		return nil
+	}
+	return s.match(ctx, id, o)
+}
+
+// TriggerOrders creates an ActivateOrder command for each stop order reached by the last trade price,
+// the last trade price is either from an own match or from an external feed.
+// Stop orders stay pending while the product does not match, activated stop orders would take liquidity.
+func (s *service) TriggerOrders(ctx context.Context, productID orderbook.ProductID, lastPrice decimal.Decimal) error {
+
+	market, err := s.markets.Get(ctx, productID)
 	if err != nil {
 		return err
 	}
+	if !market.Status().Matches(false) {
+		return nil
+	}
+
+	for _, id := range s.matcher.Trigger(productID, lastPrice) {
+		activateOrder := &orderbook.ActivateOrder{
+			TriggerPrice: lastPrice,
+			Metadata:     orderbook.MetadataFromContext(ctx),
+			CommandModel: eventsource.CommandModel{ID: id},
+		}
+
+		if _, err := s.repository.Apply(ctx, activateOrder); err != nil {
+			return err
+		}
+
+		o, err := s.GetOrder(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.match(ctx, id, o); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// MatchOrder creates a MatchOrder command and apply it on the Order.
-func (s *service) MatchOrder(ctx context.Context, id string) error {
+// match submits the Order to the matching engine, applies the matches, the prevented self trades
+// and the time in force of the Order and triggers stop orders at the last trade price.
+// A post only Order which would have taken liquidity is canceled.
+// The Order is held while its product does not match, e.g. during an auction.
+func (s *service) match(ctx context.Context, id string, o orderbook.Order) error {
+
+	matches, err := s.execute(ctx, id, o)
+	if err != nil || len(matches) == 0 {
+		return err
+	}
+	// triggered stop orders are matched after the lock of the product was released
+	return s.TriggerOrders(ctx, o.ProductID, matches[len(matches)-1].Price)
+}
+
+// execute holds the lock of the product of the Order while it is submitted to the matching engine
+// and its execution is applied, it returns the applied matches.
+func (s *service) execute(ctx context.Context, id string, o orderbook.Order) ([]orderbook.Match, error) {
+
+	l := s.productLock(o.ProductID)
+	l.Lock()
+	defer l.Unlock()
+
+	market, err := s.markets.Get(ctx, o.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	if !market.Status().Matches(o.PostOnly) {
+		s.matcher.Hold(o)
+		return nil, nil
+	}
+
+	execution := s.matcher.Match(o)
+	if execution.Rejected {
+		cancelOrder := &orderbook.CancelOrder{
+			Reason:       orderbook.CanceledByPostOnly,
+			Metadata:     orderbook.MetadataFromContext(ctx),
+			CommandModel: eventsource.CommandModel{ID: id},
+		}
+		if _, err := s.repository.Apply(ctx, cancelOrder); err != nil {
+			return nil, err
+		}
+		return nil, s.closeOrder(ctx, id)
+	}
+
+	matches := execution.Matches
+	for _, m := range matches {
+		if err := s.matchOrder(ctx, o, m); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, p := range execution.Prevented {
+		preventSelfTrade := &orderbook.PreventSelfTrade{
+			Size:         p.Size,
+			Metadata:     orderbook.MetadataFromContext(ctx),
+			CommandModel: eventsource.CommandModel{ID: p.OrderID},
+		}
+		if _, err := s.repository.Apply(ctx, preventSelfTrade); err != nil {
+			return nil, err
+		}
+		if err := s.closeOrder(ctx, p.OrderID); err != nil {
+			return nil, err
+		}
+	}
 
-	matchOrder := &orderbook.MatchOrder{
+	applyTimeInForce := &orderbook.ApplyTimeInForce{
+		Metadata:     orderbook.MetadataFromContext(ctx),
 		CommandModel: eventsource.CommandModel{ID: id},
 	}
+	if _, err := s.repository.Apply(ctx, applyTimeInForce); err != nil {
+		return nil, err
+	}
+	if err := s.closeOrder(ctx, id); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// matchOrder applies a MatchOrder command on the maker and the taker Order of a match,
+// records the Trade and charges the maker and the taker fee.
+func (s *service) matchOrder(ctx context.Context, taker orderbook.Order, m orderbook.Match) error {
+
+	tradeID := s.idGenerator.Generate()
+	matchMaker := &orderbook.MatchOrder{
+		TradeID:        tradeID,
+		CounterpartyID: m.TakerOrderID,
+		Price:          m.Price,
+		Size:           m.Size,
+		Metadata:       orderbook.MetadataFromContext(ctx),
+		CommandModel:   eventsource.CommandModel{ID: m.MakerOrderID},
+	}
+	if _, err := s.repository.Apply(ctx, matchMaker); err != nil {
+		return err
+	}
+	if err := s.chargeFee(ctx, m.MakerOrderID, tradeID, orderbook.Maker, m); err != nil {
+		return err
+	}
+
+	matchTaker := &orderbook.MatchOrder{
+		TradeID:        tradeID,
+		CounterpartyID: m.MakerOrderID,
+		Price:          m.Price,
+		Size:           m.Size,
+		Metadata:       orderbook.MetadataFromContext(ctx),
+		CommandModel:   eventsource.CommandModel{ID: m.TakerOrderID},
+	}
+	if _, err := s.repository.Apply(ctx, matchTaker); err != nil {
+		return err
+	}
+	s.trades.Add(orderbook.Trade{
+		TradeID:      tradeID,
+		ProductID:    taker.ProductID,
+		Price:        m.Price,
+		Size:         m.Size,
+		MakerOrderID: m.MakerOrderID,
+		TakerOrderID: m.TakerOrderID,
+		Side:         taker.OrderSide,
+		Time:         time.Now(),
+	})
+	if err := s.chargeFee(ctx, m.TakerOrderID, tradeID, orderbook.Taker, m); err != nil {
+		return err
+	}
+	return s.openConfirmation(ctx, tradeID, taker, m)
+}
+
+// openConfirmation opens the two-party confirmation of a trade
+func (s *service) openConfirmation(ctx context.Context, tradeID string, taker orderbook.Order, m orderbook.Match) error {
+
+	maker, err := s.GetOrder(ctx, m.MakerOrderID)
+	if err != nil {
+		return err
+	}
+
+	openConfirmation := &confirmation.OpenConfirmation{
+		ProductID:       taker.ProductID,
+		BuyOrderID:      m.TakerOrderID,
+		SellOrderID:     m.MakerOrderID,
+		BuyerAccountID:  taker.AccountID,
+		SellerAccountID: maker.AccountID,
+		Metadata:        orderbook.MetadataFromContext(ctx),
+		CommandModel:    eventsource.CommandModel{ID: tradeID},
+	}
+	if taker.OrderSide == orderbook.Sell {
+		openConfirmation.BuyOrderID, openConfirmation.SellOrderID = m.MakerOrderID, m.TakerOrderID
+		openConfirmation.BuyerAccountID, openConfirmation.SellerAccountID = maker.AccountID, taker.AccountID
+	}
+	return s.confirmations.Apply(ctx, openConfirmation)
+}
+
+// chargeFee applies a ChargeFee command with the fee rate of the tier reached by the trailing volume
+// of the account of the Order and adds the fill to the volume afterwards.
+func (s *service) chargeFee(ctx context.Context, id, tradeID string, liquidity orderbook.Liquidity, m orderbook.Match) error {
+
+	o, err := s.GetOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+	product, err := s.catalog.Get(o.ProductID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	value := m.Price.Mul(m.Size)
+	rate := product.FeeRate(liquidity, s.volumes.Trailing(o.AccountID, o.ProductID, now))
+	chargeFee := &orderbook.ChargeFee{
+		TradeID:      tradeID,
+		Liquidity:    liquidity,
+		Rate:         rate,
+		Fee:          value.Mul(rate),
+		Metadata:     orderbook.MetadataFromContext(ctx),
+		CommandModel: eventsource.CommandModel{ID: id},
+	}
+	if _, err := s.repository.Apply(ctx, chargeFee); err != nil {
+		return err
+	}
+
+	s.volumes.Add(o.AccountID, o.ProductID, value, now)
+	return nil
+}
+
+// ConfirmTrade creates a ConfirmTrade command for the party of the account and apply it on the confirmation of the trade,
+// the orders of the trade are confirmed once both parties confirmed.
+func (s *service) ConfirmTrade(ctx context.Context, tradeID, accountID string) error {
+
+	party, err := s.partyOf(ctx, tradeID, accountID)
+	if err != nil {
+		return err
+	}
+
+	confirmTrade := &confirmation.ConfirmTrade{
+		Party:        party,
+		Metadata:     orderbook.MetadataFromContext(ctx),
+		CommandModel: eventsource.CommandModel{ID: tradeID},
+	}
+	if err := s.confirmations.Apply(ctx, confirmTrade); err != nil {
+		return err
+	}
+	return s.confirmOrders(ctx, tradeID)
+}
+
+// DisputeTrade creates a DisputeTrade command for the party of the account and apply it on the confirmation of the trade.
+func (s *service) DisputeTrade(ctx context.Context, tradeID, accountID, message string) error {
+
+	party, err := s.partyOf(ctx, tradeID, accountID)
+	if err != nil {
+		return err
+	}
+
+	disputeTrade := &confirmation.DisputeTrade{
+		Party:        party,
+		Message:      message,
+		Metadata:     orderbook.MetadataFromContext(ctx),
+		CommandModel: eventsource.CommandModel{ID: tradeID},
+	}
+	return s.confirmations.Apply(ctx, disputeTrade)
+}
+
+// ResolveDispute creates a ResolveDispute command, apply it on the confirmation of the trade and confirms its orders.
+func (s *service) ResolveDispute(ctx context.Context, tradeID, message string) error {
+
+	resolveDispute := &confirmation.ResolveDispute{
+		Message:      message,
+		Metadata:     orderbook.MetadataFromContext(ctx),
+		CommandModel: eventsource.CommandModel{ID: tradeID},
+	}
+	if err := s.confirmations.Apply(ctx, resolveDispute); err != nil {
+		return err
+	}
+	return s.confirmOrders(ctx, tradeID)
+}
+
+// GetConfirmation returns the confirmation of a trade
+func (s *service) GetConfirmation(ctx context.Context, tradeID string) (confirmation.Confirmation, error) {
+	return s.confirmations.Get(ctx, tradeID)
+}
+
+// partyOf returns the side of the account in a trade, trades of other accounts are reported as not found
+func (s *service) partyOf(ctx context.Context, tradeID, accountID string) (orderbook.OrderSide, error) {
+	c, err := s.confirmations.Get(ctx, tradeID)
+	if err != nil {
+		return 0, err
+	}
+	party, ok := c.PartyOf(accountID)
+	if !ok {
+		return 0, eventsource.NewError(nil, eventsource.ErrAggregateNotFound, "unable to load trade, %v", tradeID)
+	}
+	return party, nil
+}
 
-	_, err := s.repository.Apply(ctx, matchOrder)
+// confirmOrders confirms the buy and the sell order of a confirmed trade together,
+// an order is confirmed once all its trades are confirmed and it is filled, canceled or expired.
+func (s *service) confirmOrders(ctx context.Context, tradeID string) error {
+
+	c, err := s.confirmations.Get(ctx, tradeID)
 	if err != nil {
 		return err
 	}
+	if c.Status() != confirmation.Confirmed {
+		return nil
+	}
+
+	for _, id := range c.OrderIDs() {
+		o, err := s.GetOrder(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.confirmOrder(ctx, o); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// confirmOrder confirms the Order if all its trades are confirmed. Orders which are still open
+// are confirmed with the confirmation of their last trade or once they are canceled or expire.
+func (s *service) confirmOrder(ctx context.Context, o orderbook.Order) error {
+	if !o.Allows("ConfirmOrder") {
+		return nil
+	}
+	confirmed, err := s.tradesConfirmed(ctx, o.ID())
+	if err != nil || !confirmed {
+		return err
+	}
+	return s.ConfirmOrder(ctx, o.ID())
+}
+
+// tradesConfirmed returns true if all trades of the Order are confirmed,
+// trades executed before trades had ids or confirmations are skipped
+func (s *service) tradesConfirmed(ctx context.Context, id string) (bool, error) {
+
+	events, err := s.repository.History(ctx, id, 0)
+	if err != nil {
+		return false, err
+	}
+	for _, f := range executedFills(id, events) {
+		if f.tradeID == "" {
+			continue
+		}
+		c, err := s.confirmations.Get(ctx, f.tradeID)
+		if eventsource.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if c.Status() != confirmation.Confirmed {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// SetTradingStatus creates a ChangeStatus command and apply it on the market of the product.
+// An auction collects the resting orders of the product, opening the product at the end of an auction uncrosses it.
+// Otherwise the held orders of the product are matched again, orders which still can not be matched are held again.
+func (s *service) SetTradingStatus(ctx context.Context, productID orderbook.ProductID, status trading.Status, reason string) error {
+
+	if _, err := s.catalog.Get(productID); err != nil {
+		return err
+	}
+
+	market, err := s.markets.Get(ctx, productID)
+	if err != nil {
+		return err
+	}
+
+	u, held, err := s.changeStatus(ctx, productID, market.Status(), status, reason)
+	if err != nil {
+		return err
+	}
+	if err := s.rematch(ctx, held); err != nil {
+		return err
+	}
+	if !u.Volume.IsPositive() {
+		return nil
+	}
+	return s.TriggerOrders(ctx, productID, u.Price)
+}
+
+// changeStatus holds the lock of the product while it uncrosses the auction, changes the trading status
+// and collects or releases the held orders, it returns the uncross and the released orders.
+func (s *service) changeStatus(ctx context.Context, productID orderbook.ProductID, from, to trading.Status, reason string) (orderbook.Uncross, []string, error) {
+
+	l := s.productLock(productID)
+	l.Lock()
+	defer l.Unlock()
+
+	// the auction executes before the product opens, if it fails the product stays in auction
+	// with the orders which are not filled yet and can be opened again
+	var u orderbook.Uncross
+	var err error
+	if from == trading.Auction && to == trading.Open {
+		if u, err = s.uncross(ctx, productID); err != nil {
+			return u, nil, err
+		}
+	}
+
+	changeStatus := &trading.ChangeStatus{
+		Status:       to,
+		Reason:       reason,
+		Metadata:     orderbook.MetadataFromContext(ctx),
+		CommandModel: eventsource.CommandModel{ID: string(productID)},
+	}
+	if err := s.markets.Apply(ctx, changeStatus); err != nil {
+		return u, nil, err
+	}
+
+	if to == trading.Auction {
+		s.matcher.Collect(productID)
+		return u, nil, nil
+	}
+	return u, s.matcher.Release(productID), nil
+}
+
+// uncross executes the auction of a product, it applies a MatchOrder command on both orders of every match
+// at the uncross price and fills the match in the auction once it is applied. The lock of the product is held by the caller.
+func (s *service) uncross(ctx context.Context, productID orderbook.ProductID) (orderbook.Uncross, error) {
+
+	u, matches := s.matcher.Uncross(productID)
+	for _, m := range matches {
+		taker, err := s.GetOrder(ctx, m.TakerOrderID)
+		if err != nil {
+			return u, err
+		}
+		if err := s.matchOrder(ctx, taker, m); err != nil {
+			return u, err
+		}
+		s.matcher.Fill(productID, m)
+	}
+	return u, nil
+}
+
+// rematch submits held orders to the matching engine in the order they were held
+func (s *service) rematch(ctx context.Context, ids []string) error {
+
+	for _, id := range ids {
+		o, err := s.GetOrder(ctx, id)
+		if err != nil {
+			return err
+		}
+		// held orders may have been canceled or expired in the meantime
+		if o.Closed() || !o.RemainingSize.IsPositive() {
+			continue
+		}
+		if err := s.match(ctx, id, o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAuction returns the indicative uncross of a product in auction, the volume is zero if no held order crosses
+func (s *service) GetAuction(ctx context.Context, productID orderbook.ProductID) (orderbook.Uncross, error) {
+
+	market, err := s.GetTradingStatus(ctx, productID)
+	if err != nil {
+		return orderbook.Uncross{}, err
+	}
+	if market.Status() != trading.Auction {
+		return orderbook.Uncross{}, trading.ErrNoAuction
+	}
+	u, _ := s.matcher.Indicative(productID)
+	return u, nil
+}
+
+// GetTradingStatus returns the market of a product with its trading status
+func (s *service) GetTradingStatus(ctx context.Context, productID orderbook.ProductID) (trading.Market, error) {
+
+	if _, err := s.catalog.Get(productID); err != nil {
+		return trading.Market{}, err
+	}
+	return s.markets.Get(ctx, productID)
+}
+
+// accepts returns an error if the trading status of the product does not accept the order
+func (s *service) accepts(ctx context.Context, productID orderbook.ProductID, postOnly bool) error {
+	market, err := s.markets.Get(ctx, productID)
+	if err != nil {
+		return err
+	}
+	return market.Status().Accepts(postOnly)
+}
+
 // ConfirmOrder creates a ConfirmOrder command and apply it on the Order.
 func (s *service) ConfirmOrder(ctx context.Context, id string) error {
 
 	confirmOrder := &orderbook.ConfirmOrder{
+		Metadata:     orderbook.MetadataFromContext(ctx),
 		CommandModel: eventsource.CommandModel{ID: id},
 	}
 
@@ -160,10 +1129,12 @@ func (s *service) ConfirmOrder(ctx context.Context, id string) error {
 	return nil
 }
 
-// ClearOrder creates a ClearOrder command and apply it on the Order.
+// ClearOrder creates a ClearOrder command and apply it on the Order,
+// it is issued by the ClearingHouse once the Order was netted in a clearing cycle.
 func (s *service) ClearOrder(ctx context.Context, id string) error {
 
 	clearOrder := &orderbook.ClearOrder{
+		Metadata:     orderbook.MetadataFromContext(ctx),
 		CommandModel: eventsource.CommandModel{ID: id},
 	}
 
@@ -174,10 +1145,12 @@ func (s *service) ClearOrder(ctx context.Context, id string) error {
 	return nil
 }
 
-// SettleOrder creates a SettleOrder command and apply it on the Order.
+// SettleOrder creates a SettleOrder command, apply it on the Order, posts its fills to the Ledger
+// and transfers them in the Wallet.
 func (s *service) SettleOrder(ctx context.Context, id string) error {
 
 	settleOrder := &orderbook.SettleOrder{
+		Metadata:     orderbook.MetadataFromContext(ctx),
 		CommandModel: eventsource.CommandModel{ID: id},
 	}
 
@@ -185,5 +1158,38 @@ func (s *service) SettleOrder(ctx context.Context, id string) error {
 	if err != nil {
 		return err
 	}
+	if err := s.postFills(ctx, id); err != nil {
+		return err
+	}
+	return s.closeOrder(ctx, id)
+}
+
+// postFills posts a journal entry for every fill of a settled Order, the entries of fills
+// which were posted before are ignored so settling the same Order again never double-posts.
+func (s *service) postFills(ctx context.Context, id string) error {
+
+	events, err := s.repository.History(ctx, id, 0)
+	if err != nil {
+		return err
+	}
+	fills := executedFills(id, events)
+	if len(fills) == 0 {
+		return nil
+	}
+
+	o, err := s.GetOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+	product, err := s.catalog.Get(o.ProductID)
+	if err != nil {
+		return err
+	}
+	for _, f := range fills {
+		postings := ledger.FillPostings(o.AccountID, o.OrderSide, product.BaseCurrency, product.QuoteCurrency, f.size, f.price, f.fee)
+		if err := s.ledger.Post(ctx, f.entryID, f.tradeID, id, postings); err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -4,10 +4,18 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/LAtanassov/godax/pkg/clearing"
+	"github.com/LAtanassov/godax/pkg/decimal"
 	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/LAtanassov/godax/pkg/products"
+	"github.com/LAtanassov/godax/pkg/wallet"
 	"github.com/altairsix/eventsource"
+	"github.com/go-kit/kit/log"
 )
 
 func Test_service_CreateOrder(t *testing.T) {
@@ -24,7 +32,7 @@ func Test_service_CreateOrder(t *testing.T) {
 	}{
 		{"should apply CreateOrder command to repository",
 			fields{&mockIDGenerator{id: "AB-CD"}, &mockRepository{wantErr: false, err: nil,
-				command: orderbook.CreateOrder{Size: 1.0, Price: 1.0, OrderType: orderbook.Limit, OrderSide: orderbook.Buy,
+				command: orderbook.CreateOrder{Size: decimal.NewFromInt(1), Price: decimal.NewFromInt(1), OrderType: orderbook.Limit, OrderSide: orderbook.Buy,
 					ProductID: orderbook.BtcUsd, CommandModel: eventsource.CommandModel{ID: "AB-CD"}}}},
 			context.Background(), "AB-CD", false},
 
@@ -33,8 +41,8 @@ func Test_service_CreateOrder(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := NewService(tt.fields.idGenerator, tt.fields.repository)
-			got, err := s.CreateOrder(tt.ctx, 1.0, 1.0, orderbook.Limit, orderbook.Buy, orderbook.BtcUsd)
+			s := newTestService(Dependencies{IDGenerator: tt.fields.idGenerator, Repository: tt.fields.repository})
+			got, err := s.CreateOrder(tt.ctx, newLimitOrder(orderbook.Buy, 1, 1))
 
 			if tt.wantErr && err != nil {
 				return
@@ -51,6 +59,50 @@ func Test_service_CreateOrder(t *testing.T) {
 	}
 }
 
+func Test_service_CreateOrder_idempotent(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(Dependencies{IDGenerator: NewIDGenerator()})
+
+	newSpec := func(account, clientOID, idempotencyKey string) OrderSpec {
+		spec := newLimitOrder(orderbook.Buy, 1, 100)
+		spec.AccountID = account
+		spec.ClientOID = clientOID
+		spec.IdempotencyKey = idempotencyKey
+		return spec
+	}
+	first, err := s.CreateOrder(ctx, newSpec("alice", "oid-1", "key-1"))
+	if err != nil {
+		t.Fatalf("service.CreateOrder() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		spec     OrderSpec
+		wantSame bool
+	}{
+		{"should return the original order for a repeated client order id", newSpec("alice", "oid-1", ""), true},
+		{"should return the original order for a repeated idempotency key", newSpec("alice", "", "key-1"), true},
+		{"should create a new order for the client order id of another account", newSpec("bob", "oid-1", ""), false},
+		{"should create a new order for a new client order id", newSpec("alice", "oid-2", ""), false},
+		{"should create a new order without keys", newSpec("alice", "", ""), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := s.CreateOrder(ctx, tt.spec)
+			if err != nil {
+				t.Fatalf("service.CreateOrder() error = %v", err)
+			}
+			if (id == first) != tt.wantSame {
+				t.Errorf("service.CreateOrder() = %v, original %v, want same %v", id, first, tt.wantSame)
+			}
+		})
+	}
+
+	if o, _ := s.GetOrder(ctx, first); o.ClientOID != "oid-1" || o.AccountID != "alice" {
+		t.Errorf("service.GetOrder() = %+v, want client order id oid-1", o)
+	}
+}
+
 func Test_service_GetOrder(t *testing.T) {
 
 	type fields struct {
@@ -70,7 +122,7 @@ func Test_service_GetOrder(t *testing.T) {
 	}{
 		{"should get order by id",
 			fields{idGenerator: nil, repository: &mockRepository{wantErr: false, err: nil, aggregate: &testOrder,
-				command: orderbook.CreateOrder{Size: 1.0, Price: 1.0, OrderType: orderbook.Limit, OrderSide: orderbook.Buy,
+				command: orderbook.CreateOrder{Size: decimal.NewFromInt(1), Price: decimal.NewFromInt(1), OrderType: orderbook.Limit, OrderSide: orderbook.Buy,
 					ProductID: orderbook.BtcUsd, CommandModel: eventsource.CommandModel{ID: "AB-CD"}}}},
 			args{context.Background(), "AB-CD"}, testOrder, false},
 		{"should return error when the repository returns so",
@@ -103,6 +155,65 @@ func Test_service_GetOrder(t *testing.T) {
 	}
 }
 
+func Test_service_GetOrderAt(t *testing.T) {
+	at := time.Date(2018, 5, 1, 12, 0, 0, 0, time.UTC)
+	events := []eventsource.Event{
+		&orderbook.OrderCreated{Size: decimal.NewFromInt(1), Model: eventsource.Model{ID: "AB-CD", Version: 1, At: at}},
+		&orderbook.OrderAccepted{Model: eventsource.Model{ID: "AB-CD", Version: 2, At: at.Add(time.Minute)}},
+		&orderbook.OrderPublished{Model: eventsource.Model{ID: "AB-CD", Version: 3, At: at.Add(2 * time.Minute)}},
+	}
+
+	tests := []struct {
+		name        string
+		asOf        time.Time
+		wantVersion int
+		wantErr     bool
+	}{
+		{"should return the order as of a point in time", at.Add(90 * time.Second), 2, false},
+		{"should include an event at exactly the point in time", at.Add(time.Minute), 2, false},
+		{"should return the latest order after the last event", at.Add(time.Hour), 3, false},
+		{"should return error before the order was created", at.Add(-time.Second), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(Dependencies{Repository: &mockRepository{events: events}})
+			got, err := s.GetOrderAt(context.Background(), "AB-CD", tt.asOf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("service.GetOrderAt() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got.Version() != tt.wantVersion {
+				t.Errorf("service.GetOrderAt() version = %v, want %v", got.Version(), tt.wantVersion)
+			}
+		})
+	}
+}
+
+func Test_service_GetOrderAtVersion(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(Dependencies{})
+
+	sell, _ := s.CreateOrder(ctx, newLimitOrder(orderbook.Sell, 5, 100))
+	buy, _ := s.CreateOrder(ctx, newLimitOrder(orderbook.Buy, 2, 100))
+	for _, id := range []string{sell, buy} {
+		s.AcceptOrder(ctx, id)
+		s.PublishOrder(ctx, id)
+	}
+	s.CancelOrder(ctx, sell)
+
+	// created, accepted, published, partially filled, fee charged and canceled
+	o, err := s.GetOrderAtVersion(ctx, sell, 4)
+	if err != nil {
+		t.Fatalf("service.GetOrderAtVersion() error = %v", err)
+	}
+	if o.Version() != 4 || !o.RemainingSize.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("service.GetOrderAtVersion() = %+v, want version 4 with remaining 3", o)
+	}
+	if o, _ := s.GetOrder(ctx, sell); o.Version() != 6 || !o.RemainingSize.IsZero() {
+		t.Errorf("service.GetOrder() = %+v, want version 6 with remaining 0", o)
+	}
+}
+
 func Test_service_CancelOrder(t *testing.T) {
 	type fields struct {
 		idGenerator Generator
@@ -116,8 +227,8 @@ func Test_service_CancelOrder(t *testing.T) {
 		wantErr bool
 	}{
 		{"should apply CancelOrder command to repository",
-			fields{nil, &mockRepository{wantErr: false, err: nil,
-				command: orderbook.CreateOrder{Size: 1.0, Price: 1.0, OrderType: orderbook.Limit, OrderSide: orderbook.Buy,
+			fields{nil, &mockRepository{wantErr: false, err: nil, aggregate: &testOrder,
+				command: orderbook.CreateOrder{Size: decimal.NewFromInt(1), Price: decimal.NewFromInt(1), OrderType: orderbook.Limit, OrderSide: orderbook.Buy,
 					ProductID: orderbook.BtcUsd, CommandModel: eventsource.CommandModel{ID: "AB-CD"}}}},
 			context.Background(), "AB-CD", false},
 
@@ -126,7 +237,7 @@ func Test_service_CancelOrder(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := NewService(tt.fields.idGenerator, tt.fields.repository)
+			s := newTestService(Dependencies{IDGenerator: tt.fields.idGenerator, Repository: tt.fields.repository})
 			err := s.CancelOrder(tt.ctx, "AB-CD")
 
 			if tt.wantErr && err != nil {
@@ -163,7 +274,7 @@ func Test_service_AcceptOrder(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := NewService(tt.fields.idGenerator, tt.fields.repository)
+			s := newTestService(Dependencies{IDGenerator: tt.fields.idGenerator, Repository: tt.fields.repository})
 			err := s.AcceptOrder(tt.ctx, "AB-CD")
 
 			if tt.wantErr && err != nil {
@@ -178,7 +289,7 @@ func Test_service_AcceptOrder(t *testing.T) {
 	}
 }
 
-func Test_service_PublishOrder(t *testing.T) {
+func Test_service_RejectOrder(t *testing.T) {
 	type fields struct {
 		idGenerator Generator
 		repository  Repository
@@ -190,9 +301,9 @@ func Test_service_PublishOrder(t *testing.T) {
 		want    string
 		wantErr bool
 	}{
-		{"should apply PublishOrder command to repository",
-			fields{nil, &mockRepository{wantErr: false, err: nil,
-				command: orderbook.PublishOrder{CommandModel: eventsource.CommandModel{ID: "AB-CD"}}}},
+		{"should apply RejectOrder command to repository",
+			fields{nil, &mockRepository{wantErr: false, err: nil, aggregate: &testOrder,
+				command: orderbook.RejectOrder{CommandModel: eventsource.CommandModel{ID: "AB-CD"}}}},
 			context.Background(), "AB-CD", false},
 
 		{"should return error when the repository returns so",
@@ -200,22 +311,22 @@ func Test_service_PublishOrder(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := NewService(tt.fields.idGenerator, tt.fields.repository)
-			err := s.PublishOrder(tt.ctx, "AB-CD")
+			s := newTestService(Dependencies{IDGenerator: tt.fields.idGenerator, Repository: tt.fields.repository})
+			err := s.RejectOrder(tt.ctx, "AB-CD", orderbook.RejectedByRiskLimit, "exceeds the daily limit")
 
 			if tt.wantErr && err != nil {
 				return
 			}
 
 			if (err != nil) != tt.wantErr {
-				t.Errorf("service.PublishOrder() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("service.RejectOrder() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 		})
 	}
 }
 
-func Test_service_MatchOrder(t *testing.T) {
+func Test_service_PublishOrder(t *testing.T) {
 	type fields struct {
 		idGenerator Generator
 		repository  Repository
@@ -227,9 +338,9 @@ func Test_service_MatchOrder(t *testing.T) {
 		want    string
 		wantErr bool
 	}{
-		{"should apply MatchOrder command to repository",
-			fields{nil, &mockRepository{wantErr: false, err: nil,
-				command: orderbook.MatchOrder{CommandModel: eventsource.CommandModel{ID: "AB-CD"}}}},
+		{"should apply PublishOrder command to repository",
+			fields{nil, &mockRepository{wantErr: false, err: nil, aggregate: &testOrder,
+				command: orderbook.PublishOrder{CommandModel: eventsource.CommandModel{ID: "AB-CD"}}}},
 			context.Background(), "AB-CD", false},
 
 		{"should return error when the repository returns so",
@@ -237,21 +348,396 @@ func Test_service_MatchOrder(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := NewService(tt.fields.idGenerator, tt.fields.repository)
-			err := s.MatchOrder(tt.ctx, "AB-CD")
+			s := newTestService(Dependencies{IDGenerator: tt.fields.idGenerator, Repository: tt.fields.repository})
+			err := s.PublishOrder(tt.ctx, "AB-CD")
 
 			if tt.wantErr && err != nil {
 				return
 			}
 
 			if (err != nil) != tt.wantErr {
-				t.Errorf("service.MatchOrder() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("service.PublishOrder() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 		})
 	}
 }
 
+func Test_service_PublishOrder_matches(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(Dependencies{})
+
+	sell, err := s.CreateOrder(ctx, newLimitOrder(orderbook.Sell, 5, 100))
+	if err != nil {
+		t.Fatalf("service.CreateOrder() error = %v", err)
+	}
+	buy, err := s.CreateOrder(ctx, newLimitOrder(orderbook.Buy, 5, 110))
+	if err != nil {
+		t.Fatalf("service.CreateOrder() error = %v", err)
+	}
+
+	for _, id := range []string{sell, buy} {
+		if err := s.AcceptOrder(ctx, id); err != nil {
+			t.Fatalf("service.AcceptOrder() error = %v", err)
+		}
+		if err := s.PublishOrder(ctx, id); err != nil {
+			t.Fatalf("service.PublishOrder() error = %v", err)
+		}
+	}
+
+	// only matched orders can be confirmed
+	for _, id := range []string{sell, buy} {
+		if err := s.ConfirmOrder(ctx, id); err != nil {
+			t.Errorf("service.ConfirmOrder() error = %v, order %v was not matched", err, id)
+		}
+	}
+}
+
+func Test_service_PublishOrder_partiallyFills(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(Dependencies{})
+
+	sell, _ := s.CreateOrder(ctx, newLimitOrder(orderbook.Sell, 5, 100))
+	buy, _ := s.CreateOrder(ctx, newLimitOrder(orderbook.Buy, 7, 100))
+	for _, id := range []string{sell, buy} {
+		if err := s.AcceptOrder(ctx, id); err != nil {
+			t.Fatalf("service.AcceptOrder() error = %v", err)
+		}
+		if err := s.PublishOrder(ctx, id); err != nil {
+			t.Fatalf("service.PublishOrder() error = %v", err)
+		}
+	}
+
+	o, err := s.GetOrder(ctx, buy)
+	if err != nil {
+		t.Fatalf("service.GetOrder() error = %v", err)
+	}
+	if !o.FilledSize.Equal(decimal.NewFromInt(5)) || !o.RemainingSize.Equal(decimal.NewFromInt(2)) ||
+		!o.AverageFillPrice.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("service.GetOrder() = %+v, want filled 5, remaining 2 at 100", o)
+	}
+
+	// the remaining size rests on the book and can be canceled
+	if err := s.CancelOrder(ctx, buy); err != nil {
+		t.Errorf("service.CancelOrder() error = %v", err)
+	}
+	other, _ := s.CreateOrder(ctx, newLimitOrder(orderbook.Sell, 2, 100))
+	s.AcceptOrder(ctx, other)
+	if err := s.PublishOrder(ctx, other); err != nil {
+		t.Fatalf("service.PublishOrder() error = %v", err)
+	}
+	if o, _ := s.GetOrder(ctx, other); !o.FilledSize.IsZero() {
+		t.Errorf("service.GetOrder() = %+v, want no fill against a canceled order", o)
+	}
+}
+
+func Test_service_PublishOrder_concurrently(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(Dependencies{
+		Repository: newRepository(slowStore{newMemoryStore()}, NewMemoryDedupeIndex()),
+		Wallet:     newInMemWallet(testCatalog),
+	})
+	s.Deposit(ctx, "seller", "BTC", decimal.NewFromInt(10))
+
+	spec := newLimitOrder(orderbook.Sell, 10, 100)
+	spec.AccountID = "seller"
+	sell, _ := s.CreateOrder(ctx, spec)
+	s.AcceptOrder(ctx, sell)
+	if err := s.PublishOrder(ctx, sell); err != nil {
+		t.Fatalf("service.PublishOrder() error = %v", err)
+	}
+	buys := make([]string, 10)
+	for i := range buys {
+		spec := newLimitOrder(orderbook.Buy, 1, 100)
+		spec.AccountID = "buyer-" + strconv.Itoa(i)
+		s.Deposit(ctx, spec.AccountID, "USD", decimal.NewFromInt(1000))
+		buys[i], _ = s.CreateOrder(ctx, spec)
+		s.AcceptOrder(ctx, buys[i])
+	}
+
+	// the fills of the maker are applied in the order the engine matched them
+	var wg sync.WaitGroup
+	errs := make(chan error, len(buys))
+	for _, id := range buys {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			errs <- s.PublishOrder(ctx, id)
+		}(id)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("service.PublishOrder() error = %v", err)
+		}
+	}
+
+	if o, _ := s.GetOrder(ctx, sell); !o.FilledSize.Equal(decimal.NewFromInt(10)) || o.State() != "matched" {
+		t.Errorf("service.GetOrder() = %+v, want matched with 10 filled", o)
+	}
+	history, _ := s.(*service).repository.History(ctx, sell, 0)
+	if len(history) == 0 {
+		t.Fatalf("repository.History() = %v, want the events of the maker", history)
+	}
+	for i, e := range history {
+		if e.EventVersion() != i+1 {
+			t.Fatalf("repository.History() version = %v at %v, want every event of the maker in its own version", e.EventVersion(), i)
+		}
+	}
+}
+
+func Test_service_PublishOrder_timeInForce(t *testing.T) {
+	tests := []struct {
+		name        string
+		timeInForce orderbook.TimeInForce
+		wantFilled  int64
+	}{
+		{"should cancel the remaining size of an immediate or cancel order", orderbook.ImmediateOrCancel, 2},
+		{"should cancel a fill or kill order which can not be filled", orderbook.FillOrKill, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newTestService(Dependencies{})
+
+			sell, _ := s.CreateOrder(ctx, newLimitOrder(orderbook.Sell, 2, 100))
+			spec := newLimitOrder(orderbook.Buy, 3, 100)
+			spec.TimeInForce = tt.timeInForce
+			buy, _ := s.CreateOrder(ctx, spec)
+			for _, id := range []string{sell, buy} {
+				s.AcceptOrder(ctx, id)
+				if err := s.PublishOrder(ctx, id); err != nil {
+					t.Fatalf("service.PublishOrder() error = %v", err)
+				}
+			}
+
+			if o, _ := s.GetOrder(ctx, buy); !o.FilledSize.Equal(decimal.NewFromInt(tt.wantFilled)) {
+				t.Errorf("service.GetOrder() = %+v, want filled %v", o, tt.wantFilled)
+			}
+			if err := s.CancelOrder(ctx, buy); err != orderbook.ErrInvalidStateTransition {
+				t.Errorf("service.CancelOrder() error = %v, want %v", err, orderbook.ErrInvalidStateTransition)
+			}
+		})
+	}
+}
+
+func Test_service_PublishOrder_postOnly(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(Dependencies{})
+
+	sell, _ := s.CreateOrder(ctx, newLimitOrder(orderbook.Sell, 1, 100))
+	spec := newLimitOrder(orderbook.Buy, 1, 100)
+	spec.PostOnly = true
+	buy, _ := s.CreateOrder(ctx, spec)
+	for _, id := range []string{sell, buy} {
+		s.AcceptOrder(ctx, id)
+		if err := s.PublishOrder(ctx, id); err != nil {
+			t.Fatalf("service.PublishOrder() error = %v", err)
+		}
+	}
+
+	if o, _ := s.GetOrder(ctx, buy); !o.FilledSize.IsZero() {
+		t.Errorf("service.GetOrder() = %+v, want a post only order not to take liquidity", o)
+	}
+	if err := s.CancelOrder(ctx, buy); err != orderbook.ErrInvalidStateTransition {
+		t.Errorf("service.CancelOrder() error = %v, want %v", err, orderbook.ErrInvalidStateTransition)
+	}
+}
+
+func Test_service_PublishOrder_selfTradePrevention(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(Dependencies{})
+
+	own := newLimitOrder(orderbook.Sell, 1, 100)
+	own.AccountID = "desk"
+	sell, _ := s.CreateOrder(ctx, own)
+	other, _ := s.CreateOrder(ctx, newLimitOrder(orderbook.Sell, 1, 100))
+	spec := newLimitOrder(orderbook.Buy, 1, 100)
+	spec.AccountID = "desk"
+	spec.SelfTradePrevention = orderbook.CancelOldest
+	buy, _ := s.CreateOrder(ctx, spec)
+	for _, id := range []string{sell, other, buy} {
+		s.AcceptOrder(ctx, id)
+		if err := s.PublishOrder(ctx, id); err != nil {
+			t.Fatalf("service.PublishOrder() error = %v", err)
+		}
+	}
+
+	if o, _ := s.GetOrder(ctx, sell); !o.FilledSize.IsZero() || !o.RemainingSize.IsZero() {
+		t.Errorf("service.GetOrder() = %+v, want the oldest order of the same account canceled", o)
+	}
+	if o, _ := s.GetOrder(ctx, buy); !o.FilledSize.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("service.GetOrder() = %+v, want filled against another account", o)
+	}
+}
+
+func Test_service_PublishOrder_fees(t *testing.T) {
+	ctx := context.Background()
+	volumes := NewVolumeTracker()
+	volumes.Add("whale", orderbook.BtcUsd, decimal.NewFromInt(10000000), time.Now())
+	s := newTestService(Dependencies{Volumes: volumes})
+
+	sell, _ := s.CreateOrder(ctx, newLimitOrder(orderbook.Sell, 2, 100))
+	whale := newLimitOrder(orderbook.Buy, 1, 100)
+	whale.AccountID = "whale"
+	b1, _ := s.CreateOrder(ctx, whale)
+	b2, _ := s.CreateOrder(ctx, newLimitOrder(orderbook.Buy, 1, 100))
+	for _, id := range []string{sell, b1, b2} {
+		s.AcceptOrder(ctx, id)
+		if err := s.PublishOrder(ctx, id); err != nil {
+			t.Fatalf("service.PublishOrder() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		id   string
+		want string
+	}{
+		{"should charge no fee to the maker", sell, "0"},
+		{"should charge the taker fee of the tier reached by the trailing volume", b1, "0.2"},
+		{"should charge the taker fee of the lowest tier", b2, "0.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if o, _ := s.GetOrder(ctx, tt.id); o.FillFees.String() != tt.want {
+				t.Errorf("service.GetOrder() fill fees = %v, want %v", o.FillFees, tt.want)
+			}
+		})
+	}
+	if got := volumes.Trailing("whale", orderbook.BtcUsd, time.Now()); got.String() != "10000100" {
+		t.Errorf("VolumeTracker.Trailing() = %v, want the fill added to the volume", got)
+	}
+}
+
+func Test_VolumeTracker_Trailing(t *testing.T) {
+	now := time.Now()
+	v := NewVolumeTracker()
+	v.Add("a", orderbook.BtcUsd, decimal.NewFromInt(5), now.Add(-VolumeWindow))
+	v.Add("a", orderbook.BtcUsd, decimal.NewFromInt(3), now.Add(-time.Hour))
+	v.Add("b", orderbook.BtcUsd, decimal.NewFromInt(7), now)
+
+	if got := v.Trailing("a", orderbook.BtcUsd, now); !got.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("VolumeTracker.Trailing() = %v, want 3", got)
+	}
+}
+
+func Test_service_AmendOrder(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(Dependencies{})
+
+	publish := func(spec OrderSpec) string {
+		id, err := s.CreateOrder(ctx, spec)
+		if err != nil {
+			t.Fatalf("service.CreateOrder() error = %v", err)
+		}
+		s.AcceptOrder(ctx, id)
+		if err := s.PublishOrder(ctx, id); err != nil {
+			t.Fatalf("service.PublishOrder() error = %v", err)
+		}
+		return id
+	}
+
+	first := publish(newLimitOrder(orderbook.Sell, 5, 101))
+	second := publish(newLimitOrder(orderbook.Sell, 5, 101))
+
+	// a size decrease keeps the queue position of the first order
+	if err := s.AmendOrder(ctx, first, decimal.NewFromInt(2), decimal.Zero); err != nil {
+		t.Fatalf("service.AmendOrder() error = %v", err)
+	}
+	publish(newLimitOrder(orderbook.Buy, 1, 101))
+	if o, _ := s.GetOrder(ctx, first); !o.FilledSize.Equal(decimal.NewFromInt(1)) || !o.RemainingSize.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("service.GetOrder() = %+v, want filled 1, remaining 1", o)
+	}
+
+	// a price change loses the queue position and matches the amended order again
+	buy := publish(newLimitOrder(orderbook.Buy, 5, 100))
+	if err := s.AmendOrder(ctx, second, decimal.Zero, decimal.NewFromInt(100)); err != nil {
+		t.Fatalf("service.AmendOrder() error = %v", err)
+	}
+	if o, _ := s.GetOrder(ctx, buy); !o.FilledSize.Equal(decimal.NewFromInt(5)) {
+		t.Errorf("service.GetOrder() = %+v, want filled by the repriced order", o)
+	}
+
+	if err := s.AmendOrder(ctx, buy, decimal.NewFromInt(6), decimal.Zero); err != orderbook.ErrInvalidStateTransition {
+		t.Errorf("service.AmendOrder() error = %v, want %v", err, orderbook.ErrInvalidStateTransition)
+	}
+}
+
+func Test_ExpiryScheduler_Expire(t *testing.T) {
+	ctx := context.Background()
+	repository := newInMemRepository()
+	matcher := NewMatcher()
+	scheduler := NewExpiryScheduler(log.NewNopLogger())
+	s := newTestService(Dependencies{Repository: repository, Matcher: matcher, Scheduler: scheduler})
+
+	spec := newLimitOrder(orderbook.Buy, 1, 100)
+	spec.TimeInForce = orderbook.GoodTilTime
+	spec.ExpireTime = time.Now().Add(10 * time.Millisecond)
+	buy, err := s.CreateOrder(ctx, spec)
+	if err != nil {
+		t.Fatalf("service.CreateOrder() error = %v", err)
+	}
+	s.AcceptOrder(ctx, buy)
+	if err := s.PublishOrder(ctx, buy); err != nil {
+		t.Fatalf("service.PublishOrder() error = %v", err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	scheduler.Expire(ctx, s, time.Now())
+
+	if err := s.CancelOrder(ctx, buy); err != orderbook.ErrInvalidStateTransition {
+		t.Errorf("service.CancelOrder() error = %v, want %v", err, orderbook.ErrInvalidStateTransition)
+	}
+	sell, _ := s.CreateOrder(ctx, newLimitOrder(orderbook.Sell, 1, 100))
+	s.AcceptOrder(ctx, sell)
+	s.PublishOrder(ctx, sell)
+	if o, _ := s.GetOrder(ctx, sell); !o.FilledSize.IsZero() {
+		t.Errorf("service.GetOrder() = %+v, want no fill against an expired order", o)
+	}
+}
+
+func Test_service_TriggerOrders(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(Dependencies{})
+
+	publish := func(id string) {
+		if err := s.AcceptOrder(ctx, id); err != nil {
+			t.Fatalf("service.AcceptOrder() error = %v", err)
+		}
+		if err := s.PublishOrder(ctx, id); err != nil {
+			t.Fatalf("service.PublishOrder() error = %v", err)
+		}
+	}
+
+	b1, _ := s.CreateOrder(ctx, newLimitOrder(orderbook.Buy, 1, 100))
+	b2, _ := s.CreateOrder(ctx, newLimitOrder(orderbook.Buy, 1, 95))
+	stop, err := s.CreateOrder(ctx, OrderSpec{Size: decimal.NewFromInt(1), StopPrice: decimal.NewFromInt(100), OrderType: orderbook.Stop, OrderSide: orderbook.Sell, ProductID: orderbook.BtcUsd})
+	if err != nil {
+		t.Fatalf("service.CreateOrder() error = %v", err)
+	}
+	publish(b1)
+	publish(b2)
+	publish(stop)
+
+	if o, _ := s.GetOrder(ctx, stop); !o.FilledSize.IsZero() {
+		t.Fatalf("service.GetOrder() = %+v, want a pending stop order", o)
+	}
+
+	// the trade at 100 activates the sell stop which is matched against the next best bid
+	s1, _ := s.CreateOrder(ctx, newLimitOrder(orderbook.Sell, 1, 100))
+	publish(s1)
+
+	o, err := s.GetOrder(ctx, stop)
+	if err != nil {
+		t.Fatalf("service.GetOrder() error = %v", err)
+	}
+	if !o.FilledSize.Equal(decimal.NewFromInt(1)) || !o.AverageFillPrice.Equal(decimal.NewFromInt(95)) {
+		t.Errorf("service.GetOrder() = %+v, want filled 1 at 95", o)
+	}
+}
+
 func Test_service_ConfirmOrder(t *testing.T) {
 	type fields struct {
 		idGenerator Generator
@@ -274,7 +760,7 @@ func Test_service_ConfirmOrder(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := NewService(tt.fields.idGenerator, tt.fields.repository)
+			s := newTestService(Dependencies{IDGenerator: tt.fields.idGenerator, Repository: tt.fields.repository})
 			err := s.ConfirmOrder(tt.ctx, "AB-CD")
 
 			if tt.wantErr && err != nil {
@@ -311,7 +797,7 @@ func Test_service_ClearOrder(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := NewService(tt.fields.idGenerator, tt.fields.repository)
+			s := newTestService(Dependencies{IDGenerator: tt.fields.idGenerator, Repository: tt.fields.repository})
 			err := s.ClearOrder(tt.ctx, "AB-CD")
 
 			if tt.wantErr && err != nil {
@@ -339,7 +825,7 @@ func Test_service_SettleOrder(t *testing.T) {
 		wantErr bool
 	}{
 		{"should apply SettleOrder command to repository",
-			fields{nil, &mockRepository{wantErr: false, err: nil,
+			fields{nil, &mockRepository{wantErr: false, err: nil, aggregate: &testOrder,
 				command: orderbook.SettleOrder{CommandModel: eventsource.CommandModel{ID: "AB-CD"}}}},
 			context.Background(), "AB-CD", false},
 
@@ -348,7 +834,7 @@ func Test_service_SettleOrder(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := NewService(tt.fields.idGenerator, tt.fields.repository)
+			s := newTestService(Dependencies{IDGenerator: tt.fields.idGenerator, Repository: tt.fields.repository})
 			err := s.SettleOrder(tt.ctx, "AB-CD")
 
 			if tt.wantErr && err != nil {
@@ -363,7 +849,57 @@ func Test_service_SettleOrder(t *testing.T) {
 	}
 }
 
+// newTestService returns a service of the given dependencies, the missing ones are in memory or mocks
+func newTestService(d Dependencies) Service {
+	return NewService(testDependencies(d))
+}
+
+// testDependencies fills the missing dependencies with test defaults
+func testDependencies(d Dependencies) Dependencies {
+	if d.IDGenerator == nil {
+		d.IDGenerator = &sequenceIDGenerator{}
+	}
+	if d.Repository == nil {
+		d.Repository = newInMemRepository()
+	}
+	if d.Matcher == nil {
+		d.Matcher = NewMatcher()
+	}
+	if d.Catalog == nil {
+		d.Catalog = testCatalog
+	}
+	if d.Scheduler == nil {
+		d.Scheduler = &mockScheduler{}
+	}
+	if d.Volumes == nil {
+		d.Volumes = NewVolumeTracker()
+	}
+	if d.Books == nil {
+		d.Books = NewBookProjection()
+	}
+	if d.Trades == nil {
+		d.Trades = NewTradeHistory()
+	}
+	if d.Wallet == nil {
+		d.Wallet = &mockWallet{}
+	}
+	if d.Ledger == nil {
+		d.Ledger = newInMemLedger()
+	}
+	if d.Clearing == nil {
+		d.Clearing = &mockClearing{}
+	}
+	if d.Confirmations == nil {
+		d.Confirmations = newInMemConfirmations()
+	}
+	if d.Markets == nil {
+		d.Markets = newInMemMarkets()
+	}
+	return d
+}
+
 var testOrder = orderbook.Order{}
+var testCatalog, _ = products.NewCatalog(products.DefaultProducts...)
 var testAggregate = mockAggregate{}
 
 type mockAggregate struct {
@@ -381,11 +917,101 @@ func (i *mockIDGenerator) Generate() string {
 	return i.id
 }
 
+type sequenceIDGenerator struct {
+	n int
+}
+
+func (i *sequenceIDGenerator) Generate() string {
+	i.n++
+	return strconv.Itoa(i.n)
+}
+
+func newLimitOrder(side orderbook.OrderSide, size, price int64) OrderSpec {
+	return OrderSpec{Size: decimal.NewFromInt(size), Price: decimal.NewFromInt(price),
+		OrderType: orderbook.Limit, OrderSide: side, ProductID: orderbook.BtcUsd}
+}
+
+type mockScheduler struct {
+	scheduled []string
+}
+
+func (m *mockScheduler) Schedule(id string, productID orderbook.ProductID, expireTime time.Time) {
+	m.scheduled = append(m.scheduled, id)
+}
+
+// mockWallet holds any amount and records the closed orders
+type mockWallet struct {
+	closed []string
+}
+
+func (m *mockWallet) Deposit(ctx context.Context, accountID, currency string, amount decimal.Decimal) error {
+	return nil
+}
+
+func (m *mockWallet) Balances(ctx context.Context, accountID string) (map[string]wallet.Balance, error) {
+	return map[string]wallet.Balance{}, nil
+}
+
+func (m *mockWallet) Hold(ctx context.Context, accountID, orderID, currency string, amount decimal.Decimal) error {
+	return nil
+}
+
+func (m *mockWallet) Release(ctx context.Context, accountID, orderID string) error {
+	return nil
+}
+
+func (m *mockWallet) Resize(ctx context.Context, accountID, orderID string, amount decimal.Decimal) error {
+	return nil
+}
+
+func (m *mockWallet) Close(ctx context.Context, o orderbook.Order) error {
+	if o.Closed() {
+		m.closed = append(m.closed, o.ID())
+	}
+	return nil
+}
+
+// mockClearing has no cycles
+type mockClearing struct{}
+
+func (m *mockClearing) Cycle(ctx context.Context, id string) (clearing.Cycle, error) {
+	return clearing.Cycle{}, eventsource.NewError(nil, eventsource.ErrAggregateNotFound, "unable to load cycle, %v", id)
+}
+
+func (m *mockClearing) Cycles(ctx context.Context) ([]clearing.Cycle, error) {
+	return []clearing.Cycle{}, nil
+}
+
+// failingRepository fails the next MatchOrder commands, e.g. while the event store is unavailable
+type failingRepository struct {
+	Repository
+	failures int
+}
+
+func (r *failingRepository) Apply(ctx context.Context, command eventsource.Command) (int, error) {
+	if _, ok := command.(*orderbook.MatchOrder); ok && r.failures > 0 {
+		r.failures--
+		return 0, errors.New("event store unavailable")
+	}
+	return r.Repository.Apply(ctx, command)
+}
+
+// slowStore delays saving the events, e.g. a remote event store, so concurrent commands on an order overlap
+type slowStore struct {
+	eventsource.Store
+}
+
+func (s slowStore) Save(ctx context.Context, aggregateID string, records ...eventsource.Record) error {
+	time.Sleep(time.Millisecond)
+	return s.Store.Save(ctx, aggregateID, records...)
+}
+
 type mockRepository struct {
 	err       error
 	wantErr   bool
 	aggregate eventsource.Aggregate
 	command   eventsource.Command
+	events    []eventsource.Event
 }
 
 func (m *mockRepository) Apply(ctx context.Context, command eventsource.Command) (int, error) {
@@ -398,9 +1024,24 @@ func (m *mockRepository) Apply(ctx context.Context, command eventsource.Command)
 	return 0, errors.New("unknown command")
 }
 
+func (m *mockRepository) History(ctx context.Context, aggregateID string, toVersion int) ([]eventsource.Event, error) {
+	if m.wantErr {
+		return nil, m.err
+	}
+	return m.events, nil
+}
+
 func (m *mockRepository) Load(ctx context.Context, aggregateID string) (eventsource.Aggregate, error) {
 	if m.wantErr {
 		return nil, m.err
 	}
 	return m.aggregate, nil
 }
+
+func (m *mockRepository) Reserve(ctx context.Context, key, id string) (string, error) {
+	return id, nil
+}
+
+func (m *mockRepository) Release(ctx context.Context, key, id string) error {
+	return nil
+}
@@ -0,0 +1,275 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/LAtanassov/godax/pkg/accessor"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/mysqlstore"
+)
+
+var (
+	// ErrSnapshotNotFound is returned when no snapshot of an aggregate exists
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+)
+
+// Snapshot is the serialized state of an Order at a version of its event stream
+type Snapshot struct {
+	AggregateID   string
+	Version       int
+	SchemaVersion int
+	Data          []byte
+}
+
+// SnapshotStore keeps the latest Snapshot of each Order
+type SnapshotStore interface {
+	// Save replaces the snapshot of an aggregate
+	Save(ctx context.Context, snapshot Snapshot) error
+	// Load returns the latest snapshot of an aggregate or ErrSnapshotNotFound
+	Load(ctx context.Context, aggregateID string) (Snapshot, error)
+	// Invalidate deletes all snapshots, aggregates are rebuilt from their event streams
+	Invalidate(ctx context.Context) error
+}
+
+// Invalidator is a Repository which keeps snapshots, they are invalidated e.g. after a release changed
+// how events are applied without changing the snapshot version
+type Invalidator interface {
+	// Invalidate deletes all snapshots, aggregates are rebuilt from their event streams
+	Invalidate(ctx context.Context) error
+}
+
+// snapshotRepository loads an Order from its latest snapshot and the events after it,
+// a new snapshot is taken every interval events.
+type snapshotRepository struct {
+	DedupeIndex
+
+	store     eventsource.Store
+	snapshots SnapshotStore
+	interval  int
+	observers []func(eventsource.Event)
+}
+
+func newSnapshotRepository(store eventsource.Store, snapshots SnapshotStore, dedupe DedupeIndex, interval int,
+	observers ...func(event eventsource.Event)) Repository {
+	return &snapshotRepository{
+		DedupeIndex: dedupe,
+		store:       store,
+		snapshots:   snapshots,
+		interval:    interval,
+		observers:   observers,
+	}
+}
+
+// Load retrieves the specified aggregate from the latest snapshot and the events after it
+func (r *snapshotRepository) Load(ctx context.Context, aggregateID string) (eventsource.Aggregate, error) {
+	return r.load(ctx, aggregateID)
+}
+
+func (r *snapshotRepository) load(ctx context.Context, aggregateID string) (*orderbook.Order, error) {
+	o := &orderbook.Order{}
+
+	// snapshots of an older Order shape are ignored
+	s, err := r.snapshots.Load(ctx, aggregateID)
+	if err == nil && s.SchemaVersion == orderbook.OrderSnapshotVersion {
+		if err := o.UnmarshalSnapshot(s.Data); err != nil {
+			o = &orderbook.Order{}
+		}
+	}
+
+	history, err := r.store.Load(ctx, aggregateID, o.Version()+1, 0)
+	if err != nil {
+		return nil, err
+	}
+	if o.Version() == 0 && len(history) == 0 {
+		return nil, eventsource.NewError(nil, eventsource.ErrAggregateNotFound, "unable to load order, %v", aggregateID)
+	}
+
+	for _, record := range history {
+		if record.Version <= o.Version() {
+			continue
+		}
+		event, err := serializer.UnmarshalEvent(record)
+		if err != nil {
+			return nil, err
+		}
+		if err := o.On(event); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// Invalidate deletes all snapshots
+func (r *snapshotRepository) Invalidate(ctx context.Context) error {
+	return r.snapshots.Invalidate(ctx)
+}
+
+// Apply executes the command specified and returns the current version of the aggregate,
+// a missing aggregate is created and any other failure to load it is returned
+func (r *snapshotRepository) Apply(ctx context.Context, command eventsource.Command) (int, error) {
+	o, err := r.load(ctx, command.AggregateID())
+	if eventsource.IsNotFound(err) {
+		o = &orderbook.Order{}
+	} else if err != nil {
+		return 0, err
+	}
+	version := o.Version()
+
+	events, err := o.Apply(ctx, command)
+	if err != nil {
+		return 0, err
+	}
+	if len(events) == 0 {
+		return version, nil
+	}
+
+	records := make(eventsource.History, 0, len(events))
+	for _, event := range events {
+		record, err := serializer.MarshalEvent(event)
+		if err != nil {
+			return 0, err
+		}
+		records = append(records, record)
+	}
+	if err := r.store.Save(ctx, command.AggregateID(), records...); err != nil {
+		return 0, err
+	}
+
+	for _, event := range events {
+		if err := o.On(event); err != nil {
+			return 0, err
+		}
+	}
+
+	// a failed snapshot is taken again at the next interval, the event stream stays complete
+	if r.interval > 0 && o.Version()/r.interval > version/r.interval {
+		if data, err := o.MarshalSnapshot(); err == nil {
+			r.snapshots.Save(ctx, Snapshot{
+				AggregateID:   command.AggregateID(),
+				Version:       o.Version(),
+				SchemaVersion: orderbook.OrderSnapshotVersion,
+				Data:          data,
+			})
+		}
+	}
+
+	for _, event := range events {
+		for _, observer := range r.observers {
+			observer(event)
+		}
+	}
+	return o.Version(), nil
+}
+
+// Replay notifies the observers of every stored event in the order the events were applied
+func (r *snapshotRepository) Replay(ctx context.Context, observers ...func(event eventsource.Event)) error {
+	return replayStore(ctx, r.store, serializer, append(append([]func(eventsource.Event){}, r.observers...), observers...)...)
+}
+
+// History retrieves the events of the specified aggregate up to toVersion, all events if toVersion is 0
+func (r *snapshotRepository) History(ctx context.Context, aggregateID string, toVersion int) ([]eventsource.Event, error) {
+	return history(ctx, r.store, aggregateID, toVersion)
+}
+
+// memorySnapshotStore keeps snapshots in memory
+type memorySnapshotStore struct {
+	mux       sync.Mutex
+	snapshots map[string]Snapshot
+}
+
+// NewMemorySnapshotStore returns an in-memory SnapshotStore
+func NewMemorySnapshotStore() SnapshotStore {
+	return &memorySnapshotStore{snapshots: map[string]Snapshot{}}
+}
+
+func (m *memorySnapshotStore) Save(ctx context.Context, snapshot Snapshot) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.snapshots[snapshot.AggregateID] = snapshot
+	return nil
+}
+
+func (m *memorySnapshotStore) Load(ctx context.Context, aggregateID string) (Snapshot, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	s, ok := m.snapshots[aggregateID]
+	if !ok {
+		return Snapshot{}, ErrSnapshotNotFound
+	}
+	return s, nil
+}
+
+func (m *memorySnapshotStore) Invalidate(ctx context.Context) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.snapshots = map[string]Snapshot{}
+	return nil
+}
+
+// mysqlSnapshotStore keeps snapshots in the snapshot table created by accessor.New
+type mysqlSnapshotStore struct {
+	tableName string
+	accessor  mysqlstore.Accessor
+}
+
+// NewMysqlSnapshotStore returns a SnapshotStore backed by the snapshot table of the event table
+func NewMysqlSnapshotStore(tableName string, a mysqlstore.Accessor) SnapshotStore {
+	return &mysqlSnapshotStore{
+		tableName: accessor.SnapshotTableName(tableName),
+		accessor:  a,
+	}
+}
+
+func (m *mysqlSnapshotStore) Save(ctx context.Context, snapshot Snapshot) error {
+	db, err := m.accessor.Open(ctx)
+	if err != nil {
+		return err
+	}
+	defer m.accessor.Close(db)
+
+	_, err = db.Exec(fmt.Sprintf(`INSERT INTO %s (aggregate_id, version, schema_version, data) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE version = VALUES(version), schema_version = VALUES(schema_version), data = VALUES(data)`, m.tableName),
+		snapshot.AggregateID, snapshot.Version, snapshot.SchemaVersion, snapshot.Data)
+	return err
+}
+
+func (m *mysqlSnapshotStore) Load(ctx context.Context, aggregateID string) (Snapshot, error) {
+	db, err := m.accessor.Open(ctx)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer m.accessor.Close(db)
+
+	rows, err := db.Query(fmt.Sprintf(`SELECT version, schema_version, data FROM %s WHERE aggregate_id = ?`, m.tableName), aggregateID)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return Snapshot{}, ErrSnapshotNotFound
+	}
+	s := Snapshot{AggregateID: aggregateID}
+	if err := rows.Scan(&s.Version, &s.SchemaVersion, &s.Data); err != nil {
+		return Snapshot{}, err
+	}
+	return s, nil
+}
+
+func (m *mysqlSnapshotStore) Invalidate(ctx context.Context) error {
+	db, err := m.accessor.Open(ctx)
+	if err != nil {
+		return err
+	}
+	defer m.accessor.Close(db)
+
+	_, err = db.Exec(fmt.Sprintf(`DELETE FROM %s`, m.tableName))
+	return err
+}
@@ -0,0 +1,135 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/altairsix/eventsource"
+)
+
+func Test_snapshotRepository(t *testing.T) {
+	ctx := context.Background()
+	snapshots := NewMemorySnapshotStore()
+	r := newSnapshotRepository(newMemoryStore(), snapshots, NewMemoryDedupeIndex(), 2)
+	s := newTestService(Dependencies{Repository: r})
+
+	sell, _ := s.CreateOrder(ctx, newLimitOrder(orderbook.Sell, 5, 100))
+	buy, _ := s.CreateOrder(ctx, newLimitOrder(orderbook.Buy, 2, 100))
+	for _, id := range []string{sell, buy} {
+		s.AcceptOrder(ctx, id)
+		if err := s.PublishOrder(ctx, id); err != nil {
+			t.Fatalf("service.PublishOrder() error = %v", err)
+		}
+	}
+
+	// created, accepted, published and partially filled
+	snapshot, err := snapshots.Load(ctx, sell)
+	if err != nil {
+		t.Fatalf("SnapshotStore.Load() error = %v", err)
+	}
+	if snapshot.Version != 4 || snapshot.SchemaVersion != orderbook.OrderSnapshotVersion {
+		t.Errorf("SnapshotStore.Load() = %+v, want version 4", snapshot)
+	}
+
+	want, err := s.GetOrder(ctx, sell)
+	if err != nil {
+		t.Fatalf("service.GetOrder() error = %v", err)
+	}
+	if err := s.CancelOrder(ctx, sell); err != nil {
+		t.Fatalf("service.CancelOrder() error = %v", err)
+	}
+
+	// a snapshot of another Order shape is ignored
+	snapshot, _ = snapshots.Load(ctx, sell)
+	snapshot.SchemaVersion = orderbook.OrderSnapshotVersion + 1
+	snapshot.Data = []byte(`{"Size":"42"}`)
+	snapshots.Save(ctx, snapshot)
+
+	got, err := s.GetOrder(ctx, sell)
+	if err != nil {
+		t.Fatalf("service.GetOrder() error = %v", err)
+	}
+	if got.Version() != want.Version()+1 || !got.Size.Equal(want.Size) || !got.RemainingSize.IsZero() {
+		t.Errorf("service.GetOrder() = %+v, want canceled %+v", got, want)
+	}
+}
+
+func Test_snapshotRepository_Load(t *testing.T) {
+	ctx := context.Background()
+	r := newSnapshotRepository(newMemoryStore(), NewMemorySnapshotStore(), NewMemoryDedupeIndex(), 1)
+
+	if _, err := r.Load(ctx, "unknown"); !eventsource.IsNotFound(err) {
+		t.Errorf("snapshotRepository.Load() error = %v, want not found", err)
+	}
+}
+
+func Test_snapshotRepository_Apply(t *testing.T) {
+	ctx := context.Background()
+	store := &unavailableStore{Store: newMemoryStore()}
+	r := newSnapshotRepository(store, NewMemorySnapshotStore(), NewMemoryDedupeIndex(), 1)
+	s := newTestService(Dependencies{Repository: r})
+
+	spec := newLimitOrder(orderbook.Sell, 1, 100)
+	id, _ := s.CreateOrder(ctx, spec)
+
+	// an order which fails to load must not be created again over its existing event stream
+	store.unavailable = true
+	_, err := r.Apply(ctx, &orderbook.CreateOrder{Size: spec.Size, Price: spec.Price, OrderType: spec.OrderType, OrderSide: spec.OrderSide,
+		ProductID: spec.ProductID, CommandModel: eventsource.CommandModel{ID: id}})
+	if err != errStoreUnavailable {
+		t.Errorf("snapshotRepository.Apply() error = %v, want %v", err, errStoreUnavailable)
+	}
+	store.unavailable = false
+	if o, _ := s.GetOrder(ctx, id); o.Version() != 1 {
+		t.Errorf("service.GetOrder() = %+v, want the created order only", o)
+	}
+}
+
+func Test_snapshotRepository_Invalidate(t *testing.T) {
+	ctx := context.Background()
+	snapshots := NewMemorySnapshotStore()
+	r := newSnapshotRepository(newMemoryStore(), snapshots, NewMemoryDedupeIndex(), 1)
+	s := newTestService(Dependencies{Repository: r})
+
+	id, _ := s.CreateOrder(ctx, newLimitOrder(orderbook.Sell, 1, 100))
+	if err := r.(Invalidator).Invalidate(ctx); err != nil {
+		t.Fatalf("snapshotRepository.Invalidate() error = %v", err)
+	}
+	if _, err := snapshots.Load(ctx, id); err != ErrSnapshotNotFound {
+		t.Errorf("SnapshotStore.Load() error = %v, want %v", err, ErrSnapshotNotFound)
+	}
+	if o, err := s.GetOrder(ctx, id); err != nil || o.Version() != 1 {
+		t.Errorf("service.GetOrder() = %+v, %v, want the order rebuilt from its events", o, err)
+	}
+}
+
+var errStoreUnavailable = errors.New("event store unavailable")
+
+// unavailableStore fails to save, load and read event streams while it is unavailable
+type unavailableStore struct {
+	eventsource.Store
+	unavailable bool
+}
+
+func (s *unavailableStore) Save(ctx context.Context, aggregateID string, records ...eventsource.Record) error {
+	if s.unavailable {
+		return errStoreUnavailable
+	}
+	return s.Store.Save(ctx, aggregateID, records...)
+}
+
+func (s *unavailableStore) Load(ctx context.Context, aggregateID string, fromVersion, toVersion int) (eventsource.History, error) {
+	if s.unavailable {
+		return nil, errStoreUnavailable
+	}
+	return s.Store.Load(ctx, aggregateID, fromVersion, toVersion)
+}
+
+func (s *unavailableStore) Read(ctx context.Context, startingOffset uint64, recordCount int) ([]eventsource.StreamRecord, error) {
+	if s.unavailable {
+		return nil, errStoreUnavailable
+	}
+	return s.Store.(eventsource.StreamReader).Read(ctx, startingOffset, recordCount)
+}
@@ -0,0 +1,114 @@
+package orders
+
+import (
+	"sync"
+
+	"github.com/LAtanassov/godax/pkg/orderbook"
+)
+
+// MaxPageLimit is the maximum and default number of trades of a page
+const MaxPageLimit = 100
+
+// Page selects trades by the cursors of an earlier page like the GDAX API,
+// Before returns trades newer than the cursor, After trades older than the cursor and
+// without a cursor the newest trades are returned
+type Page struct {
+	Before int64
+	After  int64
+	Limit  int
+}
+
+// TradePage holds trades newest first, Before is the cursor of the newest and After the cursor of the oldest trade
+type TradePage struct {
+	Trades []orderbook.Trade
+	Before int64
+	After  int64
+}
+
+// TradeHistory records a Trade for every execution and returns them by product or by order
+type TradeHistory interface {
+	// Add records a trade
+	Add(trade orderbook.Trade)
+	// Trades returns the trades of a product
+	Trades(productID orderbook.ProductID, page Page) TradePage
+	// Fills returns the trades in which the order was maker or taker
+	Fills(orderID string, page Page) TradePage
+}
+
+// memoryTradeHistory keeps all trades in memory, the cursor of a trade is its position in the history
+type memoryTradeHistory struct {
+	mux       sync.Mutex
+	trades    []orderbook.Trade
+	byProduct map[orderbook.ProductID][]int64
+	byOrder   map[string][]int64
+}
+
+// NewTradeHistory returns an in-memory TradeHistory, the trades of earlier runs are added again by Restore
+func NewTradeHistory() TradeHistory {
+	return &memoryTradeHistory{
+		byProduct: map[orderbook.ProductID][]int64{},
+		byOrder:   map[string][]int64{},
+	}
+}
+
+func (m *memoryTradeHistory) Add(trade orderbook.Trade) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.trades = append(m.trades, trade)
+	cursor := int64(len(m.trades))
+	m.byProduct[trade.ProductID] = append(m.byProduct[trade.ProductID], cursor)
+	m.byOrder[trade.MakerOrderID] = append(m.byOrder[trade.MakerOrderID], cursor)
+	m.byOrder[trade.TakerOrderID] = append(m.byOrder[trade.TakerOrderID], cursor)
+}
+
+func (m *memoryTradeHistory) Trades(productID orderbook.ProductID, page Page) TradePage {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	return m.page(m.byProduct[productID], page)
+}
+
+func (m *memoryTradeHistory) Fills(orderID string, page Page) TradePage {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	return m.page(m.byOrder[orderID], page)
+}
+
+// page selects up to limit cursors next to the cursor of the page, cursors are in ascending order
+func (m *memoryTradeHistory) page(cursors []int64, page Page) TradePage {
+	limit := page.Limit
+	if limit <= 0 || limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+
+	from, to := 0, len(cursors)
+	switch {
+	case page.Before > 0:
+		for from < len(cursors) && cursors[from] <= page.Before {
+			from++
+		}
+		if to-from > limit {
+			to = from + limit
+		}
+	case page.After > 0:
+		for to > 0 && cursors[to-1] >= page.After {
+			to--
+		}
+		fallthrough
+	default:
+		if to-from > limit {
+			from = to - limit
+		}
+	}
+
+	p := TradePage{Trades: []orderbook.Trade{}}
+	for i := to - 1; i >= from; i-- {
+		p.Trades = append(p.Trades, m.trades[cursors[i]-1])
+	}
+	if from < to {
+		p.Before, p.After = cursors[to-1], cursors[from]
+	}
+	return p
+}
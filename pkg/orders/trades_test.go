@@ -0,0 +1,114 @@
+package orders
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	kitlog "github.com/go-kit/kit/log"
+)
+
+func TestTradeHistory_Trades(t *testing.T) {
+	h := NewTradeHistory()
+	for i := 1; i <= 5; i++ {
+		h.Add(orderbook.Trade{TradeID: strconv.Itoa(i), ProductID: orderbook.BtcUsd, MakerOrderID: "m", TakerOrderID: "t"})
+	}
+	h.Add(orderbook.Trade{TradeID: "other", ProductID: "ETH-USD", MakerOrderID: "x", TakerOrderID: "y"})
+
+	tests := []struct {
+		name       string
+		page       Page
+		want       []string
+		wantBefore int64
+		wantAfter  int64
+	}{
+		{"should return the newest trades first", Page{Limit: 2}, []string{"5", "4"}, 5, 4},
+		{"should return older trades after the cursor", Page{After: 4, Limit: 2}, []string{"3", "2"}, 3, 2},
+		{"should return newer trades before the cursor", Page{Before: 1, Limit: 2}, []string{"3", "2"}, 3, 2},
+		{"should return an empty page after the oldest trade", Page{After: 1, Limit: 2}, []string{}, 0, 0},
+		{"should limit a page to MaxPageLimit", Page{}, []string{"5", "4", "3", "2", "1"}, 5, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := h.Trades(orderbook.BtcUsd, tt.page)
+			got := []string{}
+			for _, trade := range p.Trades {
+				got = append(got, trade.TradeID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TradeHistory.Trades() = %v, want %v", got, tt.want)
+			}
+			if p.Before != tt.wantBefore || p.After != tt.wantAfter {
+				t.Errorf("TradeHistory.Trades() cursors = %v, %v, want %v, %v", p.Before, p.After, tt.wantBefore, tt.wantAfter)
+			}
+		})
+	}
+}
+
+func TestMakeHandler_trades(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(Dependencies{})
+	h := MakeHandler(s, NewAPIKeyAuthenticator(map[string]Principal{"alice-key": {AccountID: "alice"}}), kitlog.NewNopLogger())
+
+	place := func(account string, spec OrderSpec) string {
+		spec.AccountID = account
+		id, _ := s.CreateOrder(ctx, spec)
+		s.AcceptOrder(ctx, id)
+		s.PublishOrder(ctx, id)
+		return id
+	}
+	maker := place("alice", newLimitOrder(orderbook.Sell, 3, 100))
+	place("bob", newLimitOrder(orderbook.Buy, 1, 100))
+	taker := place("bob", newLimitOrder(orderbook.Buy, 2, 100))
+
+	t.Run("should return the trades of a product with their cursors", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/godax/v1/products/BTC-USD/trades?limit=1", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("MakeHandler() status = %v: %s", w.Code, w.Body.String())
+		}
+		var got []publicTrade
+		json.NewDecoder(w.Body).Decode(&got)
+		if len(got) != 1 || got[0].TakerOrderID != taker || got[0].MakerOrderID != maker || got[0].Side != "buy" {
+			t.Errorf("MakeHandler() = %+v, want the trade of %v against %v", got, taker, maker)
+		}
+		if w.Header().Get(BeforeHeader) != "2" || w.Header().Get(AfterHeader) != "2" {
+			t.Errorf("MakeHandler() cursors = %v, %v, want 2, 2", w.Header().Get(BeforeHeader), w.Header().Get(AfterHeader))
+		}
+	})
+
+	t.Run("should return the fills of the maker order", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/godax/v1/orders/"+maker+"/fills", nil)
+		r.Header.Set(APIKeyHeader, "alice-key")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("MakeHandler() status = %v: %s", w.Code, w.Body.String())
+		}
+		var got []orderFill
+		json.NewDecoder(w.Body).Decode(&got)
+		want := []decimal.Decimal{decimal.NewFromInt(2), decimal.NewFromInt(1)}
+		if len(got) != len(want) {
+			t.Fatalf("MakeHandler() = %+v, want %v fills", got, len(want))
+		}
+		for i, f := range got {
+			if !f.Size.Equal(want[i]) || f.Liquidity != orderbook.Maker || f.Side != "sell" || f.OrderID != maker {
+				t.Errorf("MakeHandler() fill = %+v, want a maker sell of %v", f, want[i])
+			}
+		}
+	})
+
+	t.Run("should reject a page with both cursors", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/godax/v1/products/BTC-USD/trades?before=1&after=2", nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("MakeHandler() status = %v, want %v", w.Code, http.StatusBadRequest)
+		}
+	})
+}
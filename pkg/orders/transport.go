@@ -5,9 +5,16 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/LAtanassov/godax/pkg/confirmation"
+	"github.com/LAtanassov/godax/pkg/decimal"
 	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/LAtanassov/godax/pkg/products"
+	"github.com/LAtanassov/godax/pkg/trading"
+	"github.com/LAtanassov/godax/pkg/wallet"
+	"github.com/altairsix/eventsource"
 	"github.com/go-kit/kit/circuitbreaker"
 	"github.com/go-kit/kit/endpoint"
 	kitlog "github.com/go-kit/kit/log"
@@ -33,13 +40,25 @@ func newRatelimitMiddleware(limit ratelimit.Allower) endpoint.Middleware {
 }
 
 // MakeHandler returns a handler for the order service.
-func MakeHandler(s Service, logger kitlog.Logger) http.Handler {
+// Every request is authenticated by its API key, accounts act only on their own orders,
+// trades are confirmed by their parties, accept, reject, clear, settle, deposits, the resolution of disputes,
+// the trial balance, the clearing cycles and changes of the trading status are reserved to operators.
+func MakeHandler(s Service, a Authenticator, logger kitlog.Logger) http.Handler {
 	opts := []kithttp.ServerOption{
+		kithttp.ServerBefore(populateAPIKey, populateMetadata),
+		kithttp.ServerAfter(setRequestIDHeader),
 		kithttp.ServerErrorLogger(logger),
 		kithttp.ServerErrorEncoder(encodeError),
 	}
 
-	c := makeCreateOrderEndpoint(s)
+	owner := func(e endpoint.Endpoint) endpoint.Endpoint {
+		return newAuthenticationMiddleware(a)(newOwnershipMiddleware(s, false)(e))
+	}
+	operator := func(e endpoint.Endpoint) endpoint.Endpoint {
+		return newAuthenticationMiddleware(a)(newOwnershipMiddleware(s, true)(e))
+	}
+
+	c := owner(makeCreateOrderEndpoint(s))
 	c = newCircuitBreakerMiddleware("create order")(c)
 	c = newRatelimitMiddleware(rate.NewLimiter(rate.Every(time.Second), 100))(c)
 	createOrderHandler := kithttp.NewServer(
@@ -49,7 +68,7 @@ func MakeHandler(s Service, logger kitlog.Logger) http.Handler {
 		opts...,
 	)
 
-	g := makeGetOrderEndpoint(s)
+	g := owner(makeGetOrderEndpoint(s))
 	g = newCircuitBreakerMiddleware("get order")(g)
 	g = newRatelimitMiddleware(rate.NewLimiter(rate.Every(time.Second), 100))(g)
 	getOrderHandler := kithttp.NewServer(
@@ -60,90 +79,234 @@ func MakeHandler(s Service, logger kitlog.Logger) http.Handler {
 	)
 
 	cancelOrderHandler := kithttp.NewServer(
-		makeCancelOrderEndpoint(s),
+		owner(makeCancelOrderEndpoint(s)),
 		decodeCommonOrderRequest,
 		encodeResponse,
 		opts...,
 	)
 
+	amendOrderHandler := kithttp.NewServer(
+		owner(makeAmendOrderEndpoint(s)),
+		decodeAmendOrderRequest,
+		encodeResponse,
+		opts...,
+	)
+
 	acceptOrderHandler := kithttp.NewServer(
-		makeAcceptOrderEndpoint(s),
+		operator(makeAcceptOrderEndpoint(s)),
 		decodeCommonOrderRequest,
 		encodeResponse,
 		opts...,
 	)
 
+	rejectOrderHandler := kithttp.NewServer(
+		operator(makeRejectOrderEndpoint(s)),
+		decodeRejectOrderRequest,
+		encodeResponse,
+		opts...,
+	)
+
 	publishOrderHandler := kithttp.NewServer(
-		makePublishOrderEndpoint(s),
+		owner(makePublishOrderEndpoint(s)),
 		decodeCommonOrderRequest,
 		encodeResponse,
 		opts...,
 	)
 
-	matchOrderHandler := kithttp.NewServer(
-		makeMatchOrderEndpoint(s),
-		decodeCommonOrderRequest,
+	// trades are confirmed or disputed by their parties, disputes are resolved by operators
+	confirmTradeHandler := kithttp.NewServer(
+		owner(makeConfirmTradeEndpoint(s)),
+		decodeTradeRequest,
 		encodeResponse,
 		opts...,
 	)
 
-	confirmOrderHandler := kithttp.NewServer(
-		makeConfirmOrderEndpoint(s),
-		decodeCommonOrderRequest,
+	disputeTradeHandler := kithttp.NewServer(
+		owner(makeDisputeTradeEndpoint(s)),
+		decodeTradeRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	resolveDisputeHandler := kithttp.NewServer(
+		operator(makeResolveDisputeEndpoint(s)),
+		decodeTradeRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	getConfirmationHandler := kithttp.NewServer(
+		owner(makeGetConfirmationEndpoint(s)),
+		decodeTradeRequest,
 		encodeResponse,
 		opts...,
 	)
 
 	clearOrderHandler := kithttp.NewServer(
-		makeClearOrderEndpoint(s),
+		operator(makeClearOrderEndpoint(s)),
 		decodeCommonOrderRequest,
 		encodeResponse,
 		opts...,
 	)
 
 	settleOrderHandler := kithttp.NewServer(
-		makeSettleOrderEndpoint(s),
+		operator(makeSettleOrderEndpoint(s)),
 		decodeCommonOrderRequest,
 		encodeResponse,
 		opts...,
 	)
 
+	// the book is public market data and not authenticated
+	getBookHandler := kithttp.NewServer(
+		makeGetBookEndpoint(s),
+		decodeGetBookRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	// trades are public market data like the book, fills are private to the account of the order
+	getTradesHandler := kithttp.NewServer(
+		makeGetTradesEndpoint(s),
+		decodeGetTradesRequest,
+		encodePageResponse,
+		opts...,
+	)
+
+	getFillsHandler := kithttp.NewServer(
+		owner(makeGetFillsEndpoint(s)),
+		decodeGetFillsRequest,
+		encodePageResponse,
+		opts...,
+	)
+
+	// balances are private to the account, deposits are made by operators
+	getBalancesHandler := kithttp.NewServer(
+		owner(makeGetBalancesEndpoint(s)),
+		decodeGetBalancesRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	depositHandler := kithttp.NewServer(
+		operator(makeDepositEndpoint(s)),
+		decodeDepositRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	// the trading status and the indicative auction price are public market data, changing it halts or restricts a product for all accounts
+	getTradingStatusHandler := kithttp.NewServer(
+		makeGetTradingStatusEndpoint(s),
+		decodeGetTradingStatusRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	getAuctionHandler := kithttp.NewServer(
+		makeGetAuctionEndpoint(s),
+		decodeGetTradingStatusRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	setTradingStatusHandler := kithttp.NewServer(
+		operator(makeSetTradingStatusEndpoint(s)),
+		decodeSetTradingStatusRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	// the trial balance of the settlement ledger covers all accounts and is reserved to operators
+	getTrialBalanceHandler := kithttp.NewServer(
+		operator(makeGetTrialBalanceEndpoint(s)),
+		decodeGetTrialBalanceRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	// clearing cycles show what every account owes before settlement and are reserved to operators
+	getClearingCyclesHandler := kithttp.NewServer(
+		operator(makeGetClearingCyclesEndpoint(s)),
+		decodeGetClearingCyclesRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	getClearingCycleHandler := kithttp.NewServer(
+		operator(makeGetClearingCycleEndpoint(s)),
+		decodeGetClearingCycleRequest,
+		encodeResponse,
+		opts...,
+	)
+
 	r := mux.NewRouter()
 
 	r.Handle("/godax/v1/orders", createOrderHandler).Methods("POST")
 	r.Handle("/godax/v1/orders/{id}", getOrderHandler).Methods("GET")
 	r.Handle("/godax/v1/orders/{id}", cancelOrderHandler).Methods("DELETE")
+	r.Handle("/godax/v1/orders/{id}", amendOrderHandler).Methods("PATCH")
+	r.Handle("/godax/v1/orders/{id}/fills", getFillsHandler).Methods("GET")
 
 	r.Handle("/godax/v1/orders/{id}/accept", acceptOrderHandler).Methods("PUT")
+	r.Handle("/godax/v1/orders/{id}/reject", rejectOrderHandler).Methods("PUT")
 	r.Handle("/godax/v1/orders/{id}/publish", publishOrderHandler).Methods("PUT")
-	r.Handle("/godax/v1/orders/{id}/match", matchOrderHandler).Methods("PUT")
-	r.Handle("/godax/v1/orders/{id}/confirm", confirmOrderHandler).Methods("PUT")
 	r.Handle("/godax/v1/orders/{id}/clear", clearOrderHandler).Methods("PUT")
 	r.Handle("/godax/v1/orders/{id}/settle", settleOrderHandler).Methods("PUT")
 
+	r.Handle("/godax/v1/accounts", getBalancesHandler).Methods("GET")
+	r.Handle("/godax/v1/accounts/{account_id}/deposits", depositHandler).Methods("POST")
+
+	r.Handle("/godax/v1/trades/{trade_id}", getConfirmationHandler).Methods("GET")
+	r.Handle("/godax/v1/trades/{trade_id}/confirm", confirmTradeHandler).Methods("PUT")
+	r.Handle("/godax/v1/trades/{trade_id}/dispute", disputeTradeHandler).Methods("PUT")
+	r.Handle("/godax/v1/trades/{trade_id}/resolve", resolveDisputeHandler).Methods("PUT")
+
+	r.Handle("/godax/v1/ledger/trial-balance", getTrialBalanceHandler).Methods("GET")
+
+	r.Handle("/godax/v1/clearing/cycles", getClearingCyclesHandler).Methods("GET")
+	r.Handle("/godax/v1/clearing/cycles/{id}", getClearingCycleHandler).Methods("GET")
+
+	r.Handle("/godax/v1/books/{product_id}", getBookHandler).Methods("GET")
+	r.Handle("/godax/v1/products/{product_id}/trades", getTradesHandler).Methods("GET")
+	r.Handle("/godax/v1/products/{product_id}/status", getTradingStatusHandler).Methods("GET")
+	r.Handle("/godax/v1/products/{product_id}/status", setTradingStatusHandler).Methods("PUT")
+	r.Handle("/godax/v1/products/{product_id}/auction", getAuctionHandler).Methods("GET")
+
 	return r
 }
 
+// IdempotencyKeyHeader is the request header which identifies retries of a request to create an order
+const IdempotencyKeyHeader = "Idempotency-Key"
+
 var errBadRoute = errors.New("bad route")
 var errIllegalArgument = errors.New("illegal argument")
 
 func decodeCreateOrderRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	var body struct {
-		Size      float32 `json:"size"`
-		Price     float32 `json:"price"`
-		OrderType string  `json:"type"`
-		OrderSide string  `json:"side"`
-		ProductID string  `json:"product_id"`
+		Size      decimal.Decimal `json:"size"`
+		Price     decimal.Decimal `json:"price"`
+		StopPrice decimal.Decimal `json:"stop_price"`
+		OrderType string          `json:"type"`
+		OrderSide string          `json:"side"`
+		ProductID string          `json:"product_id"`
+
+		TimeInForce string    `json:"time_in_force"`
+		ExpireTime  time.Time `json:"expire_time"`
+
+		PostOnly            bool   `json:"post_only"`
+		SelfTradePrevention string `json:"stp"`
+
+		ClientOID string `json:"client_oid"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		return nil, err
+		return nil, errIllegalArgument
 	}
 
 	defer r.Body.Close()
 
-	// validation
-	if floatEquals(body.Price, 0.0) || floatEquals(body.Size, 0.0) {
+	// validation, product specific rules are applied by the service
+	if !body.Size.IsPositive() {
 		return nil, errIllegalArgument
 	}
 
@@ -157,18 +320,99 @@ func decodeCreateOrderRequest(_ context.Context, r *http.Request) (interface{},
 		return nil, errIllegalArgument
 	}
 
-	productID, ok := productIDs[body.ProductID]
-	if !ok {
+	// good til canceled by default
+	timeInForce := orderbook.GoodTilCanceled
+	if body.TimeInForce != "" {
+		timeInForce, ok = timeInForces[body.TimeInForce]
+		if !ok {
+			return nil, errIllegalArgument
+		}
+	}
+
+	// keys are scoped to the account in the dedupe index
+	idempotencyKey := r.Header.Get(IdempotencyKeyHeader)
+	if len(body.ClientOID) > 64 || len(idempotencyKey) > 128 {
 		return nil, errIllegalArgument
 	}
 
-	return createOrderRequest{
+	// decrease and cancel by default
+	stp := orderbook.DecreaseAndCancel
+	if body.SelfTradePrevention != "" {
+		stp, ok = selfTradePreventions[body.SelfTradePrevention]
+		if !ok {
+			return nil, errIllegalArgument
+		}
+	}
+
+	return createOrderRequest{Spec: OrderSpec{
 		Size:      body.Size,
 		Price:     body.Price,
+		StopPrice: body.StopPrice,
 		OrderType: orderType,
 		OrderSide: orderSide,
-		ProductID: productID,
-	}, nil
+		ProductID: orderbook.ProductID(body.ProductID),
+
+		TimeInForce: timeInForce,
+		ExpireTime:  body.ExpireTime,
+
+		PostOnly:            body.PostOnly,
+		SelfTradePrevention: stp,
+
+		ClientOID:      body.ClientOID,
+		IdempotencyKey: idempotencyKey,
+	}}, nil
+}
+
+func decodeAmendOrderRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+
+	var body struct {
+		Size  decimal.Decimal `json:"size"`
+		Price decimal.Decimal `json:"price"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, errIllegalArgument
+	}
+
+	defer r.Body.Close()
+
+	// a zero size or price keeps the current value
+	if body.Size.Sign() < 0 || body.Price.Sign() < 0 {
+		return nil, errIllegalArgument
+	}
+
+	return amendOrderRequest{ID: id, Size: body.Size, Price: body.Price}, nil
+}
+
+func decodeRejectOrderRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+
+	var body struct {
+		Reason  string `json:"reason"`
+		Message string `json:"message"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, errIllegalArgument
+	}
+
+	defer r.Body.Close()
+
+	reason, ok := rejectReasons[body.Reason]
+	if !ok {
+		return nil, errIllegalArgument
+	}
+
+	return rejectOrderRequest{ID: id, Reason: reason, Message: body.Message}, nil
 }
 
 func decodeGetOrderRequest(_ context.Context, r *http.Request) (interface{}, error) {
@@ -177,7 +421,212 @@ func decodeGetOrderRequest(_ context.Context, r *http.Request) (interface{}, err
 	if !ok {
 		return nil, errBadRoute
 	}
-	return getOrderRequest{ID: id}, nil
+
+	// temporal queries by either ?as_of=2018-05-01T12:00:00Z or ?version=3
+	req := getOrderRequest{ID: id}
+	q := r.URL.Query()
+	if q.Get("as_of") != "" && q.Get("version") != "" {
+		return nil, errIllegalArgument
+	}
+	if s := q.Get("as_of"); s != "" {
+		asOf, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, errIllegalArgument
+		}
+		req.AsOf = asOf
+	}
+	if s := q.Get("version"); s != "" {
+		version, err := strconv.Atoi(s)
+		if err != nil || version <= 0 {
+			return nil, errIllegalArgument
+		}
+		req.Version = version
+	}
+	return req, nil
+}
+
+// maxBookDepth limits the price levels of a level 2 book
+const maxBookDepth = 1000
+
+func decodeGetBookRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	productID, ok := vars["product_id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+
+	// ?level=1 best bid and ask (default), ?level=2&depth=50 top price levels, ?level=3 all orders
+	req := getBookRequest{ProductID: orderbook.ProductID(productID), Level: 1, Depth: 50}
+	q := r.URL.Query()
+	if s := q.Get("level"); s != "" {
+		level, err := strconv.Atoi(s)
+		if err != nil || level < 1 || level > 3 {
+			return nil, errIllegalArgument
+		}
+		req.Level = level
+	}
+	if s := q.Get("depth"); s != "" {
+		depth, err := strconv.Atoi(s)
+		if err != nil || depth <= 0 || depth > maxBookDepth || req.Level != 2 {
+			return nil, errIllegalArgument
+		}
+		req.Depth = depth
+	}
+	return req, nil
+}
+
+func decodeGetTradesRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	productID, ok := vars["product_id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+	page, err := decodePage(r)
+	if err != nil {
+		return nil, err
+	}
+	return getTradesRequest{ProductID: orderbook.ProductID(productID), Page: page}, nil
+}
+
+func decodeGetFillsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+	page, err := decodePage(r)
+	if err != nil {
+		return nil, err
+	}
+	return getFillsRequest{ID: id, Page: page}, nil
+}
+
+// decodePage reads the cursors ?before=, ?after= and the ?limit= of a page, only one cursor is allowed
+func decodePage(r *http.Request) (Page, error) {
+	page := Page{Limit: MaxPageLimit}
+	q := r.URL.Query()
+	if q.Get("before") != "" && q.Get("after") != "" {
+		return Page{}, errIllegalArgument
+	}
+	for key, cursor := range map[string]*int64{"before": &page.Before, "after": &page.After} {
+		if s := q.Get(key); s != "" {
+			c, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || c <= 0 {
+				return Page{}, errIllegalArgument
+			}
+			*cursor = c
+		}
+	}
+	if s := q.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit <= 0 || limit > MaxPageLimit {
+			return Page{}, errIllegalArgument
+		}
+		page.Limit = limit
+	}
+	return page, nil
+}
+
+func decodeGetBalancesRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return getBalancesRequest{}, nil
+}
+
+func decodeGetTrialBalanceRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return getTrialBalanceRequest{}, nil
+}
+
+// decodeGetClearingCyclesRequest reads the optional ?account_id= to return only the instructions of an account
+func decodeGetClearingCyclesRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return getClearingCyclesRequest{AccountID: r.URL.Query().Get("account_id")}, nil
+}
+
+func decodeGetClearingCycleRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+	return getClearingCycleRequest{ID: id, AccountID: r.URL.Query().Get("account_id")}, nil
+}
+
+// decodeTradeRequest reads the trade id and the optional message of a dispute or a resolution
+func decodeTradeRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	tradeID, ok := vars["trade_id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+
+	var body struct {
+		Message string `json:"message"`
+	}
+	if r.Body != nil && r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return nil, errIllegalArgument
+		}
+		defer r.Body.Close()
+	}
+
+	return tradeRequest{TradeID: tradeID, Message: body.Message}, nil
+}
+
+func decodeGetTradingStatusRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	productID, ok := vars["product_id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+	return tradingStatusRequest{ProductID: orderbook.ProductID(productID)}, nil
+}
+
+func decodeSetTradingStatusRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	productID, ok := vars["product_id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+
+	var body struct {
+		Status trading.Status `json:"status"`
+		Reason string         `json:"reason"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, errIllegalArgument
+	}
+
+	defer r.Body.Close()
+
+	if err := body.Status.Validate(); err != nil {
+		return nil, err
+	}
+
+	return tradingStatusRequest{ProductID: orderbook.ProductID(productID), Status: body.Status, Reason: body.Reason}, nil
+}
+
+func decodeDepositRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	accountID, ok := vars["account_id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+
+	var body struct {
+		Currency string          `json:"currency"`
+		Amount   decimal.Decimal `json:"amount"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, errIllegalArgument
+	}
+
+	defer r.Body.Close()
+
+	if body.Currency == "" || !body.Amount.IsPositive() {
+		return nil, errIllegalArgument
+	}
+
+	return depositRequest{AccountID: accountID, Currency: body.Currency, Amount: body.Amount}, nil
 }
 
 func decodeCommonOrderRequest(_ context.Context, r *http.Request) (interface{}, error) {
@@ -202,15 +651,60 @@ func encodeResponse(ctx context.Context, w http.ResponseWriter, response interfa
 	return json.NewEncoder(w).Encode(response)
 }
 
+// Cursor headers of a page like the GDAX API, CB-BEFORE to request newer and CB-AFTER to request older items
+const (
+	BeforeHeader = "CB-BEFORE"
+	AfterHeader  = "CB-AFTER"
+)
+
+// pager is a response of a page of items
+type pager interface {
+	cursors() (before, after int64)
+	items() interface{}
+}
+
+// encodePageResponse encodes the items of a page as array and its cursors as headers
+func encodePageResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if e, ok := response.(errorer); ok && e.error() != nil {
+		encodeError(ctx, e.error(), w)
+		return nil
+	}
+	p, ok := response.(pager)
+	if !ok {
+		return encodeResponse(ctx, w, response)
+	}
+	if before, after := p.cursors(); before > 0 {
+		w.Header().Set(BeforeHeader, strconv.FormatInt(before, 10))
+		w.Header().Set(AfterHeader, strconv.FormatInt(after, 10))
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(w).Encode(p.items())
+}
+
 // encode errors from business-logic
 func encodeError(_ context.Context, err error, w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	switch err {
 	case errBadRoute:
 		w.WriteHeader(http.StatusNotFound)
-	case errIllegalArgument:
+	case ErrUnauthenticated:
+		w.WriteHeader(http.StatusUnauthorized)
+	case ErrForbidden:
+		w.WriteHeader(http.StatusForbidden)
+	case errIllegalArgument, products.ErrUnknownProduct, products.ErrProductOffline,
+		products.ErrInvalidSize, products.ErrInvalidPrice, products.ErrInvalidStopPrice,
+		orderbook.ErrInvalidExpireTime, orderbook.ErrInvalidPostOnly,
+		orderbook.ErrInvalidAmendment, wallet.ErrInsufficientFunds, wallet.ErrInvalidAmount, trading.ErrInvalidStatus,
+		decimal.ErrOutOfRange:
 		w.WriteHeader(http.StatusBadRequest)
+	case orderbook.ErrInvalidStateTransition, confirmation.ErrDisputed, confirmation.ErrNotDisputed, confirmation.ErrConfirmed,
+		trading.ErrHalted, trading.ErrCancelOnly, trading.ErrPostOnly, trading.ErrNoAuction, ErrInsufficientLiquidity:
+		w.WriteHeader(http.StatusConflict)
 	default:
+		if eventsource.IsNotFound(err) {
+			w.WriteHeader(http.StatusNotFound)
+			break
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -219,22 +713,34 @@ func encodeError(_ context.Context, err error, w http.ResponseWriter) {
 }
 
 var orderTypes = map[string]orderbook.OrderType{
-	orderbook.Limit.String():  orderbook.Limit,
-	orderbook.Market.String(): orderbook.Market,
+	orderbook.Limit.String():     orderbook.Limit,
+	orderbook.Market.String():    orderbook.Market,
+	orderbook.Stop.String():      orderbook.Stop,
+	orderbook.StopLimit.String(): orderbook.StopLimit,
 }
 
-var orderSides = map[string]orderbook.OrderSide{
-	orderbook.Sell.String(): orderbook.Sell,
-	orderbook.Buy.String():  orderbook.Sell,
+var timeInForces = map[string]orderbook.TimeInForce{
+	orderbook.GoodTilCanceled.String():   orderbook.GoodTilCanceled,
+	orderbook.GoodTilTime.String():       orderbook.GoodTilTime,
+	orderbook.ImmediateOrCancel.String(): orderbook.ImmediateOrCancel,
+	orderbook.FillOrKill.String():        orderbook.FillOrKill,
 }
 
-var productIDs = map[string]orderbook.ProductID{
-	orderbook.BtcUsd.String(): orderbook.BtcUsd,
+var selfTradePreventions = map[string]orderbook.SelfTradePrevention{
+	orderbook.DecreaseAndCancel.String(): orderbook.DecreaseAndCancel,
+	orderbook.CancelOldest.String():      orderbook.CancelOldest,
+	orderbook.CancelNewest.String():      orderbook.CancelNewest,
+	orderbook.CancelBoth.String():        orderbook.CancelBoth,
 }
 
-// EPSILON is a small float number
-const EPSILON float32 = 0.00000001
+var rejectReasons = map[string]orderbook.RejectReason{
+	string(orderbook.RejectedByRiskLimit):  orderbook.RejectedByRiskLimit,
+	string(orderbook.RejectedByCompliance): orderbook.RejectedByCompliance,
+	string(orderbook.RejectedAsInvalid):    orderbook.RejectedAsInvalid,
+	string(orderbook.RejectedByOther):      orderbook.RejectedByOther,
+}
 
-func floatEquals(a, b float32) bool {
-	return (a-b) < EPSILON && (b-a) < EPSILON
+var orderSides = map[string]orderbook.OrderSide{
+	orderbook.Sell.String(): orderbook.Sell,
+	orderbook.Buy.String():  orderbook.Buy,
 }
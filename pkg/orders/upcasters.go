@@ -0,0 +1,52 @@
+package orders
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/LAtanassov/godax/pkg/orderbook"
+)
+
+// legacyProductIDs maps the integer enum of product ids used before the product catalog was introduced
+var legacyProductIDs = []orderbook.ProductID{orderbook.BtcUsd}
+
+// upcastOrderCreatedV1 replaces the legacy integer product id by the product id of the catalog
+// and the float32 size and price by decimal strings
+func upcastOrderCreatedV1(fields map[string]json.RawMessage) error {
+	var i int
+	if err := json.Unmarshal(fields["ProductID"], &i); err == nil {
+		if i < 0 || i >= len(legacyProductIDs) {
+			return fmt.Errorf("unknown legacy product id %d", i)
+		}
+		b, _ := json.Marshal(legacyProductIDs[i])
+		fields["ProductID"] = b
+	}
+
+	for _, name := range []string{"Size", "Price"} {
+		var n json.Number
+		if err := json.Unmarshal(fields[name], &n); err == nil {
+			b, _ := json.Marshal(n.String())
+			fields[name] = b
+		}
+	}
+	return nil
+}
+
+// upcastOrderCanceledV1 adds the reason to orders canceled before reasons were recorded, only users could cancel them
+func upcastOrderCanceledV1(fields map[string]json.RawMessage) error {
+	var reason string
+	if err := json.Unmarshal(fields["Reason"], &reason); err != nil || reason == "" {
+		b, _ := json.Marshal(orderbook.CanceledByUser)
+		fields["Reason"] = b
+	}
+	return nil
+}
+
+// upcastOrderCreatedV2 renames the owner to the account id, owners were account ids chosen by the client
+func upcastOrderCreatedV2(fields map[string]json.RawMessage) error {
+	if owner, ok := fields["Owner"]; ok {
+		fields["AccountID"] = owner
+		delete(fields, "Owner")
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package orders
+
+import (
+	"context"
+
+	"github.com/LAtanassov/godax/pkg/accessor"
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/LAtanassov/godax/pkg/products"
+	"github.com/LAtanassov/godax/pkg/wallet"
+	"github.com/altairsix/eventsource"
+	"github.com/altairsix/eventsource/mysqlstore"
+)
+
+// walletSerializer binds the account events with their current schema version, see serializer
+var walletSerializer = newSchemaSerializer().
+	Bind(1,
+		wallet.FundsDeposited{},
+		wallet.FundsHeld{},
+		wallet.HoldReleased{},
+		wallet.HoldSettled{},
+		wallet.HoldResized{},
+	)
+
+// Wallet keeps the balances of the accounts and holds the funds of their open orders, the id of a hold is the order id
+type Wallet interface {
+	// Deposit adds funds to an account
+	Deposit(ctx context.Context, accountID, currency string, amount decimal.Decimal) error
+	// Balances returns the balance of every currency of an account
+	Balances(ctx context.Context, accountID string) (map[string]wallet.Balance, error)
+	// Hold holds funds for an order or fails with wallet.ErrInsufficientFunds
+	Hold(ctx context.Context, accountID, orderID, currency string, amount decimal.Decimal) error
+	// Release releases the hold of an order, e.g. when the order could not be created
+	Release(ctx context.Context, accountID, orderID string) error
+	// Resize changes the hold of an amended order or fails with wallet.ErrInsufficientFunds
+	Resize(ctx context.Context, accountID, orderID string, amount decimal.Decimal) error
+	// Close transfers the fills of a closed order and releases the rest of its hold, open orders are ignored
+	Close(ctx context.Context, o orderbook.Order) error
+}
+
+// eventWallet keeps the accounts as aggregates in their own event store
+type eventWallet struct {
+	repository *eventsource.Repository
+	catalog    products.Catalog
+}
+
+// NewWallet returns a Wallet depending on driver, the currencies of a fill are looked up in the catalog
+func NewWallet(dbDriver, dbURL, tableName string, catalog products.Catalog) (Wallet, error) {
+	switch dbDriver {
+	case inmem:
+		return newInMemWallet(catalog), nil
+	case mysql:
+		a, err := accessor.New(dbDriver, dbURL, tableName)
+		if err != nil {
+			return nil, err
+		}
+		store, err := mysqlstore.New(accessor.AccountsTableName(tableName), a)
+		if err != nil {
+			return nil, err
+		}
+		return &eventWallet{
+			repository: eventsource.New(&wallet.Account{}, eventsource.WithStore(store), eventsource.WithSerializer(walletSerializer)),
+			catalog:    catalog,
+		}, nil
+	default:
+		return nil, ErrUnsupportedDriver
+	}
+}
+
+func newInMemWallet(catalog products.Catalog) Wallet {
+	return &eventWallet{
+		repository: eventsource.New(&wallet.Account{}, eventsource.WithStore(newMemoryStore()), eventsource.WithSerializer(walletSerializer)),
+		catalog:    catalog,
+	}
+}
+
+func (w *eventWallet) Deposit(ctx context.Context, accountID, currency string, amount decimal.Decimal) error {
+	_, err := w.repository.Apply(ctx, &wallet.DepositFunds{
+		Currency:     currency,
+		Amount:       amount,
+		Metadata:     orderbook.MetadataFromContext(ctx),
+		CommandModel: eventsource.CommandModel{ID: accountID},
+	})
+	return err
+}
+
+func (w *eventWallet) Balances(ctx context.Context, accountID string) (map[string]wallet.Balance, error) {
+	v, err := w.repository.Load(ctx, accountID)
+	if eventsource.IsNotFound(err) {
+		return map[string]wallet.Balance{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	a, ok := v.(*wallet.Account)
+	if !ok {
+		return nil, ErrTypeCast
+	}
+	return a.Balances(), nil
+}
+
+func (w *eventWallet) Hold(ctx context.Context, accountID, orderID, currency string, amount decimal.Decimal) error {
+	_, err := w.repository.Apply(ctx, &wallet.PlaceHold{
+		HoldID:       orderID,
+		Currency:     currency,
+		Amount:       amount,
+		Metadata:     orderbook.MetadataFromContext(ctx),
+		CommandModel: eventsource.CommandModel{ID: accountID},
+	})
+	return err
+}
+
+func (w *eventWallet) Release(ctx context.Context, accountID, orderID string) error {
+	_, err := w.repository.Apply(ctx, &wallet.ReleaseHold{
+		HoldID:       orderID,
+		Metadata:     orderbook.MetadataFromContext(ctx),
+		CommandModel: eventsource.CommandModel{ID: accountID},
+	})
+	return err
+}
+
+func (w *eventWallet) Resize(ctx context.Context, accountID, orderID string, amount decimal.Decimal) error {
+	_, err := w.repository.Apply(ctx, &wallet.ResizeHold{
+		HoldID:       orderID,
+		Amount:       amount,
+		Metadata:     orderbook.MetadataFromContext(ctx),
+		CommandModel: eventsource.CommandModel{ID: accountID},
+	})
+	return err
+}
+
+// Close releases the hold of an order without fills, the hold of an order with fills is settled:
+// a seller transfers the filled size for the filled value less fees, a buyer the filled value plus fees for the filled size
+func (w *eventWallet) Close(ctx context.Context, o orderbook.Order) error {
+	if !o.Closed() {
+		return nil
+	}
+
+	if !o.FilledSize.IsPositive() {
+		return w.Release(ctx, o.AccountID, o.ID())
+	}
+
+	product, err := w.catalog.Get(o.ProductID)
+	if err != nil {
+		return err
+	}
+	settleHold := &wallet.SettleHold{
+		HoldID:         o.ID(),
+		Debit:          o.FilledSize,
+		CreditCurrency: product.QuoteCurrency,
+		Credit:         o.FilledValue().Sub(o.FillFees),
+		Metadata:       orderbook.MetadataFromContext(ctx),
+		CommandModel:   eventsource.CommandModel{ID: o.AccountID},
+	}
+	if o.OrderSide == orderbook.Buy {
+		settleHold.Debit = o.FilledValue().Add(o.FillFees)
+		settleHold.CreditCurrency = product.BaseCurrency
+		settleHold.Credit = o.FilledSize
+	}
+	_, err = w.repository.Apply(ctx, settleHold)
+	return err
+}
@@ -0,0 +1,228 @@
+package orders
+
+import (
+	"context"
+	"testing"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/LAtanassov/godax/pkg/trading"
+	"github.com/LAtanassov/godax/pkg/wallet"
+)
+
+func Test_service_wallet(t *testing.T) {
+	ctx := context.Background()
+
+	// newService returns a service where alice owns 10 BTC and bob 1000 USD
+	newService := func(t *testing.T) Service {
+		books := NewBookProjection()
+		s := newTestService(Dependencies{Repository: newInMemRepository(books.On), Books: books, Wallet: newInMemWallet(testCatalog)})
+		if err := s.Deposit(ctx, "alice", "BTC", decimal.NewFromInt(10)); err != nil {
+			t.Fatalf("service.Deposit() error = %v", err)
+		}
+		if err := s.Deposit(ctx, "bob", "USD", decimal.NewFromInt(1000)); err != nil {
+			t.Fatalf("service.Deposit() error = %v", err)
+		}
+		return s
+	}
+	place := func(t *testing.T, s Service, account string, spec OrderSpec) string {
+		spec.AccountID = account
+		id, err := s.CreateOrder(ctx, spec)
+		if err != nil {
+			t.Fatalf("service.CreateOrder() error = %v", err)
+		}
+		s.AcceptOrder(ctx, id)
+		s.PublishOrder(ctx, id)
+		return id
+	}
+	balance := func(t *testing.T, s Service, account, currency string) wallet.Balance {
+		balances, err := s.GetBalances(ctx, account)
+		if err != nil {
+			t.Fatalf("service.GetBalances() error = %v", err)
+		}
+		return balances[currency]
+	}
+
+	t.Run("should fail to create a sell order without the size available", func(t *testing.T) {
+		s := newService(t)
+		spec := newLimitOrder(orderbook.Sell, 11, 100)
+		spec.AccountID = "alice"
+		if _, err := s.CreateOrder(ctx, spec); err != wallet.ErrInsufficientFunds {
+			t.Errorf("service.CreateOrder() error = %v, want %v", err, wallet.ErrInsufficientFunds)
+		}
+	})
+
+	t.Run("should fail to create a buy order without the value and fee available", func(t *testing.T) {
+		s := newService(t)
+		spec := newLimitOrder(orderbook.Buy, 10, 100)
+		spec.AccountID = "bob"
+		if _, err := s.CreateOrder(ctx, spec); err != wallet.ErrInsufficientFunds {
+			t.Errorf("service.CreateOrder() error = %v, want %v", err, wallet.ErrInsufficientFunds)
+		}
+	})
+
+	t.Run("should reject a buy order whose value does not fit into a decimal", func(t *testing.T) {
+		s := newService(t)
+		spec := newLimitOrder(orderbook.Buy, 70, 90000000000)
+		spec.AccountID = "bob"
+		if _, err := s.CreateOrder(ctx, spec); err != decimal.ErrOutOfRange {
+			t.Errorf("service.CreateOrder() error = %v, want %v", err, decimal.ErrOutOfRange)
+		}
+	})
+
+	t.Run("should resize the hold of an amended order", func(t *testing.T) {
+		s := newService(t)
+		id := place(t, s, "bob", newLimitOrder(orderbook.Buy, 1, 100))
+		held := balance(t, s, "bob", "USD").Hold
+
+		if err := s.AmendOrder(ctx, id, decimal.NewFromInt(2), decimal.Zero); err != nil {
+			t.Fatalf("service.AmendOrder() error = %v", err)
+		}
+		if got, want := balance(t, s, "bob", "USD").Hold, held.Mul(decimal.NewFromInt(2)); !got.Equal(want) {
+			t.Errorf("Balance.Hold = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("should reject an amendment beyond the available funds and keep the hold", func(t *testing.T) {
+		s := newService(t)
+		id := place(t, s, "bob", newLimitOrder(orderbook.Buy, 1, 100))
+		held := balance(t, s, "bob", "USD").Hold
+
+		if err := s.AmendOrder(ctx, id, decimal.NewFromInt(50), decimal.NewFromInt(1000)); err != wallet.ErrInsufficientFunds {
+			t.Fatalf("service.AmendOrder() error = %v, want %v", err, wallet.ErrInsufficientFunds)
+		}
+		if got := balance(t, s, "bob", "USD").Hold; !got.Equal(held) {
+			t.Errorf("Balance.Hold = %v, want %v", got, held)
+		}
+		if o, _ := s.GetOrder(ctx, id); !o.Size.Equal(decimal.NewFromInt(1)) || !o.Price.Equal(decimal.NewFromInt(100)) {
+			t.Errorf("service.GetOrder() = %v@%v, want the order unchanged", o.Size, o.Price)
+		}
+	})
+
+	t.Run("should hold the value of a market buy at the asks on the book", func(t *testing.T) {
+		s := newService(t)
+		market := OrderSpec{Size: decimal.NewFromInt(2), OrderType: orderbook.Market, OrderSide: orderbook.Buy, ProductID: orderbook.BtcUsd, AccountID: "bob"}
+		if _, err := s.CreateOrder(ctx, market); err != ErrInsufficientLiquidity {
+			t.Errorf("service.CreateOrder() error = %v, want %v on an empty book", err, ErrInsufficientLiquidity)
+		}
+
+		place(t, s, "alice", newLimitOrder(orderbook.Sell, 1, 100))
+		place(t, s, "alice", newLimitOrder(orderbook.Sell, 1, 200))
+		if _, err := s.CreateOrder(ctx, market); err != nil {
+			t.Fatalf("service.CreateOrder() error = %v", err)
+		}
+		if got := balance(t, s, "bob", "USD"); !got.Hold.GreaterThan(decimal.NewFromInt(300)) {
+			t.Errorf("service.GetBalances() = %+v, want a hold of 300 and the taker fee", got)
+		}
+		market.Size = decimal.NewFromInt(3)
+		if _, err := s.CreateOrder(ctx, market); err != ErrInsufficientLiquidity {
+			t.Errorf("service.CreateOrder() error = %v, want %v beyond the book", err, ErrInsufficientLiquidity)
+		}
+	})
+
+	t.Run("should hold the size of a sell order and release it on cancel", func(t *testing.T) {
+		s := newService(t)
+		id := place(t, s, "alice", newLimitOrder(orderbook.Sell, 4, 100))
+		if got := balance(t, s, "alice", "BTC"); !got.Hold.Equal(decimal.NewFromInt(4)) {
+			t.Errorf("service.GetBalances() = %+v, want a hold of 4", got)
+		}
+		if err := s.CancelOrder(ctx, id); err != nil {
+			t.Fatalf("service.CancelOrder() error = %v", err)
+		}
+		if got := balance(t, s, "alice", "BTC"); !got.Hold.IsZero() || !got.Available().Equal(decimal.NewFromInt(10)) {
+			t.Errorf("service.GetBalances() = %+v, want 10 available", got)
+		}
+	})
+
+	t.Run("should release the hold of a rejected order", func(t *testing.T) {
+		s := newService(t)
+		spec := newLimitOrder(orderbook.Sell, 4, 100)
+		spec.AccountID = "alice"
+		id, _ := s.CreateOrder(ctx, spec)
+		if err := s.RejectOrder(ctx, id, orderbook.RejectedByRiskLimit, ""); err != nil {
+			t.Fatalf("service.RejectOrder() error = %v", err)
+		}
+		if got := balance(t, s, "alice", "BTC"); !got.Hold.IsZero() {
+			t.Errorf("service.GetBalances() = %+v, want no hold", got)
+		}
+	})
+
+	t.Run("should release the hold of an accepted order canceled after its product was halted", func(t *testing.T) {
+		s := newService(t)
+		spec := newLimitOrder(orderbook.Sell, 4, 100)
+		spec.AccountID = "alice"
+		id, _ := s.CreateOrder(ctx, spec)
+		s.AcceptOrder(ctx, id)
+		s.SetTradingStatus(ctx, orderbook.BtcUsd, trading.Halted, "incident")
+		if err := s.PublishOrder(ctx, id); err != trading.ErrHalted {
+			t.Fatalf("service.PublishOrder() error = %v, want %v", err, trading.ErrHalted)
+		}
+
+		if err := s.CancelOrder(ctx, id); err != nil {
+			t.Fatalf("service.CancelOrder() error = %v", err)
+		}
+		if o, _ := s.GetOrder(ctx, id); !o.Closed() {
+			t.Errorf("service.GetOrder() = %+v, want a closed order", o)
+		}
+		if got := balance(t, s, "alice", "BTC"); !got.Hold.IsZero() {
+			t.Errorf("service.GetBalances() = %+v, want no hold", got)
+		}
+	})
+
+	t.Run("should transfer the fills on settlement", func(t *testing.T) {
+		s := newService(t)
+		maker := place(t, s, "alice", newLimitOrder(orderbook.Sell, 1, 100))
+		taker := place(t, s, "bob", newLimitOrder(orderbook.Buy, 1, 100))
+		for _, id := range []string{maker, taker} {
+			s.ConfirmOrder(ctx, id)
+			s.ClearOrder(ctx, id)
+			if err := s.SettleOrder(ctx, id); err != nil {
+				t.Fatalf("service.SettleOrder() error = %v", err)
+			}
+		}
+
+		sold, _ := s.GetOrder(ctx, maker)
+		bought, _ := s.GetOrder(ctx, taker)
+		tests := []struct {
+			account  string
+			currency string
+			want     decimal.Decimal
+		}{
+			{"alice", "BTC", decimal.NewFromInt(9)},
+			{"alice", "USD", decimal.NewFromInt(100).Sub(sold.FillFees)},
+			{"bob", "USD", decimal.NewFromInt(900).Sub(bought.FillFees)},
+			{"bob", "BTC", decimal.NewFromInt(1)},
+		}
+		for _, tt := range tests {
+			got := balance(t, s, tt.account, tt.currency)
+			if !got.Total.Equal(tt.want) || !got.Hold.IsZero() {
+				t.Errorf("service.GetBalances(%v) %v = %+v, want a total of %v without hold", tt.account, tt.currency, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("should transfer the fills of an order canceled after a partial fill on settlement", func(t *testing.T) {
+		s := newService(t)
+		maker := place(t, s, "alice", newLimitOrder(orderbook.Sell, 4, 100))
+		place(t, s, "bob", newLimitOrder(orderbook.Buy, 1, 100))
+		if err := s.CancelOrder(ctx, maker); err != nil {
+			t.Fatalf("service.CancelOrder() error = %v", err)
+		}
+		if got := balance(t, s, "alice", "BTC"); !got.Hold.Equal(decimal.NewFromInt(4)) {
+			t.Errorf("service.GetBalances() = %+v, want the hold kept until settlement", got)
+		}
+
+		s.ConfirmOrder(ctx, maker)
+		s.ClearOrder(ctx, maker)
+		if err := s.SettleOrder(ctx, maker); err != nil {
+			t.Fatalf("service.SettleOrder() error = %v", err)
+		}
+		sold, _ := s.GetOrder(ctx, maker)
+		if got := balance(t, s, "alice", "BTC"); !got.Total.Equal(decimal.NewFromInt(9)) || !got.Hold.IsZero() {
+			t.Errorf("service.GetBalances() BTC = %+v, want a total of 9 without hold", got)
+		}
+		if got := balance(t, s, "alice", "USD"); !got.Total.Equal(decimal.NewFromInt(100).Sub(sold.FillFees)) {
+			t.Errorf("service.GetBalances() USD = %+v, want the fill less the fee", got)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package products
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+)
+
+// Catalog stores the tradable products
+type Catalog interface {
+	// Get returns a product or ErrUnknownProduct
+	Get(id orderbook.ProductID) (Product, error)
+	// List returns all products sorted by id
+	List() ([]Product, error)
+	// Save adds or replaces a product
+	Save(p Product) error
+}
+
+// DefaultProducts is used when no product configuration is provided
+var DefaultProducts = []Product{
+	{
+		ID:             orderbook.BtcUsd,
+		BaseCurrency:   "BTC",
+		QuoteCurrency:  "USD",
+		BaseMinSize:    decimal.RequireFromString("0.001"),
+		BaseMaxSize:    decimal.RequireFromString("70"),
+		BaseIncrement:  decimal.RequireFromString("0.00000001"),
+		QuoteIncrement: decimal.RequireFromString("0.01"),
+		Status:         Online,
+		FeeTiers: []FeeTier{
+			{MinVolume: decimal.Zero, MakerRate: decimal.Zero, TakerRate: decimal.RequireFromString("0.003")},
+			{MinVolume: decimal.NewFromInt(10000000), MakerRate: decimal.Zero, TakerRate: decimal.RequireFromString("0.002")},
+			{MinVolume: decimal.NewFromInt(100000000), MakerRate: decimal.Zero, TakerRate: decimal.RequireFromString("0.001")},
+		},
+	},
+}
+
+type inMemCatalog struct {
+	mux      sync.RWMutex
+	products map[orderbook.ProductID]Product
+}
+
+// NewCatalog returns an in memory catalog with the products provided
+func NewCatalog(products ...Product) (Catalog, error) {
+	c := &inMemCatalog{products: map[orderbook.ProductID]Product{}}
+	for _, p := range products {
+		if err := c.Save(p); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Load reads a JSON array of products, e.g. from a configuration file
+func Load(r io.Reader) ([]Product, error) {
+	var products []Product
+	if err := json.NewDecoder(r).Decode(&products); err != nil {
+		return nil, err
+	}
+	for _, p := range products {
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return products, nil
+}
+
+func (c *inMemCatalog) Get(id orderbook.ProductID) (Product, error) {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	p, ok := c.products[id]
+	if !ok {
+		return Product{}, ErrUnknownProduct
+	}
+	return p, nil
+}
+
+func (c *inMemCatalog) List() ([]Product, error) {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	products := make([]Product, 0, len(c.products))
+	for _, p := range c.products {
+		products = append(products, p)
+	}
+	sort.Slice(products, func(i, j int) bool { return products[i].ID < products[j].ID })
+	return products, nil
+}
+
+func (c *inMemCatalog) Save(p Product) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.products[p.ID] = p
+	return nil
+}
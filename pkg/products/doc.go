@@ -0,0 +1,4 @@
+// Package products represents the catalog of tradable products.
+// A product defines its base and quote currency, tick size, order size limits and status,
+// new markets like ETH-BTC are added by configuration or the admin api instead of code changes.
+package products
@@ -0,0 +1,72 @@
+package products
+
+import (
+	"context"
+	"errors"
+
+	"github.com/LAtanassov/godax/pkg/orderbook"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// ErrTypeCast represents a unexpected type cast error
+var ErrTypeCast = errors.New("type cast failed")
+
+type getProductRequest struct {
+	ID orderbook.ProductID
+}
+
+type getProductResponse struct {
+	Product Product `json:"product"`
+	Err     error   `json:"error,omitempty"`
+}
+
+func (r getProductResponse) error() error { return r.Err }
+
+func makeGetProductEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(getProductRequest)
+		if !ok {
+			return nil, ErrTypeCast
+		}
+		p, err := s.GetProduct(ctx, req.ID)
+		return getProductResponse{Product: p, Err: err}, nil
+	}
+}
+
+type listProductsRequest struct{}
+
+type listProductsResponse struct {
+	Products []Product `json:"products"`
+	Err      error     `json:"error,omitempty"`
+}
+
+func (r listProductsResponse) error() error { return r.Err }
+
+func makeListProductsEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		products, err := s.ListProducts(ctx)
+		return listProductsResponse{Products: products, Err: err}, nil
+	}
+}
+
+type saveProductRequest struct {
+	Product Product
+}
+
+type saveProductResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r saveProductResponse) error() error { return r.Err }
+
+func makeSaveProductEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(saveProductRequest)
+		if !ok {
+			return nil, ErrTypeCast
+		}
+		err := s.SaveProduct(ctx, req.Product)
+		return saveProductResponse{Err: err}, nil
+	}
+}
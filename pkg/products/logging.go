@@ -0,0 +1,49 @@
+package products
+
+import (
+	"context"
+	"time"
+
+	"github.com/LAtanassov/godax/pkg/orderbook"
+
+	"github.com/go-kit/kit/log"
+)
+
+type loggingService struct {
+	logger log.Logger
+	Service
+}
+
+// NewLoggingMiddleware returns a new instance of a logging middleware.
+func NewLoggingMiddleware(logger log.Logger) ServiceMiddleware {
+	return func(next Service) Service {
+		return &loggingService{logger, next}
+	}
+}
+
+func (s *loggingService) SaveProduct(ctx context.Context, p Product) (err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "SaveProduct",
+			"id", p.ID,
+			"status", p.Status,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return s.Service.SaveProduct(ctx, p)
+}
+
+func (s *loggingService) GetProduct(ctx context.Context, id orderbook.ProductID) (p Product, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "GetProduct",
+			"id", id,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return s.Service.GetProduct(ctx, id)
+}
@@ -0,0 +1,123 @@
+package products
+
+import (
+	"errors"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+)
+
+var (
+	// ErrUnknownProduct is returned when a product is not part of the catalog
+	ErrUnknownProduct = errors.New("unknown product")
+	// ErrProductOffline is returned when an order is placed on a product which is not online
+	ErrProductOffline = errors.New("product offline")
+	// ErrInvalidSize is returned when an order size is out of range or not a multiple of the base increment
+	ErrInvalidSize = errors.New("invalid size")
+	// ErrInvalidPrice is returned when an order price is not a multiple of the tick size
+	ErrInvalidPrice = errors.New("invalid price")
+	// ErrInvalidStopPrice is returned when the stop price of a stop order is not a multiple of the tick size
+	ErrInvalidStopPrice = errors.New("invalid stop price")
+	// ErrInvalidProduct is returned when a product definition is incomplete
+	ErrInvalidProduct = errors.New("invalid product")
+)
+
+// Status represents whether a product can be traded
+type Status string
+
+const (
+	// Online products accept orders
+	Online Status = "online"
+	// Offline products reject new orders
+	Offline Status = "offline"
+	// Delisted products are not traded anymore
+	Delisted Status = "delisted"
+)
+
+// FeeTier is a maker and taker fee rate which applies from a trailing 30 day volume in the quote currency
+type FeeTier struct {
+	MinVolume decimal.Decimal `json:"min_volume"`
+	MakerRate decimal.Decimal `json:"maker_rate"`
+	TakerRate decimal.Decimal `json:"taker_rate"`
+}
+
+// Product represents a market of a base currency quoted in a quote currency, e.g. BTC-USD
+type Product struct {
+	ID             orderbook.ProductID `json:"id"`
+	BaseCurrency   string              `json:"base_currency"`
+	QuoteCurrency  string              `json:"quote_currency"`
+	BaseMinSize    decimal.Decimal     `json:"base_min_size"`
+	BaseMaxSize    decimal.Decimal     `json:"base_max_size"`
+	BaseIncrement  decimal.Decimal     `json:"base_increment"`
+	QuoteIncrement decimal.Decimal     `json:"quote_increment"`
+	Status         Status              `json:"status"`
+	FeeTiers       []FeeTier           `json:"fee_tiers,omitempty"` // ascending by volume, no fees if empty
+}
+
+// Validate returns an error if the product definition is incomplete
+func (p Product) Validate() error {
+	if p.ID == "" || p.BaseCurrency == "" || p.QuoteCurrency == "" {
+		return ErrInvalidProduct
+	}
+	if !p.BaseIncrement.IsPositive() || !p.QuoteIncrement.IsPositive() {
+		return ErrInvalidProduct
+	}
+	if p.BaseMaxSize.IsPositive() && p.BaseMaxSize.LessThan(p.BaseMinSize) {
+		return ErrInvalidProduct
+	}
+	for i, tier := range p.FeeTiers {
+		if tier.MakerRate.Sign() < 0 || tier.TakerRate.Sign() < 0 {
+			return ErrInvalidProduct
+		}
+		if i == 0 && !tier.MinVolume.IsZero() || i > 0 && !tier.MinVolume.GreaterThan(p.FeeTiers[i-1].MinVolume) {
+			return ErrInvalidProduct
+		}
+	}
+	switch p.Status {
+	case Online, Offline, Delisted:
+		return nil
+	}
+	return ErrInvalidProduct
+}
+
+// FeeRate returns the maker or taker fee rate of the tier reached by the trailing 30 day volume
+func (p Product) FeeRate(liquidity orderbook.Liquidity, volume decimal.Decimal) decimal.Decimal {
+	rate := decimal.Zero
+	for _, tier := range p.FeeTiers {
+		if volume.LessThan(tier.MinVolume) {
+			break
+		}
+		rate = tier.TakerRate
+		if liquidity == orderbook.Maker {
+			rate = tier.MakerRate
+		}
+	}
+	return rate
+}
+
+// ValidateOrder returns an error if an order does not comply with the product rules.
+// The price of market and stop orders and the stop price of non stop orders is not validated.
+func (p Product) ValidateOrder(size, price, stopPrice decimal.Decimal, orderType orderbook.OrderType) error {
+	if p.Status != Online {
+		return ErrProductOffline
+	}
+
+	if !size.IsPositive() || size.LessThan(p.BaseMinSize) || !size.IsMultipleOf(p.BaseIncrement) {
+		return ErrInvalidSize
+	}
+	if p.BaseMaxSize.IsPositive() && size.GreaterThan(p.BaseMaxSize) {
+		return ErrInvalidSize
+	}
+
+	if orderType.IsStop() && (!stopPrice.IsPositive() || !stopPrice.IsMultipleOf(p.QuoteIncrement)) {
+		return ErrInvalidStopPrice
+	}
+
+	if !orderType.IsLimit() {
+		return nil
+	}
+	if !price.IsPositive() || !price.IsMultipleOf(p.QuoteIncrement) {
+		return ErrInvalidPrice
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package products
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+)
+
+func TestProduct_ValidateOrder(t *testing.T) {
+	btcUsd := DefaultProducts[0]
+	offline := btcUsd
+	offline.Status = Offline
+
+	tests := []struct {
+		name      string
+		product   Product
+		size      string
+		price     string
+		stopPrice string
+		orderType orderbook.OrderType
+		want      error
+	}{
+		{"should accept a valid limit order", btcUsd, "0.5", "100.01", "0", orderbook.Limit, nil},
+		{"should accept a market order without price", btcUsd, "0.5", "0", "0", orderbook.Market, nil},
+		{"should reject an order on an offline product", offline, "0.5", "100", "0", orderbook.Limit, ErrProductOffline},
+		{"should reject a size below the minimum", btcUsd, "0.0001", "100", "0", orderbook.Limit, ErrInvalidSize},
+		{"should reject a size above the maximum", btcUsd, "71", "100", "0", orderbook.Limit, ErrInvalidSize},
+		{"should accept a stop order with a stop price", btcUsd, "0.5", "0", "95.5", orderbook.Stop, nil},
+		{"should reject a stop order without stop price", btcUsd, "0.5", "0", "0", orderbook.Stop, ErrInvalidStopPrice},
+		{"should reject a stop limit order without price", btcUsd, "0.5", "0", "95.5", orderbook.StopLimit, ErrInvalidPrice},
+		{"should reject a price which is not a multiple of the tick size", btcUsd, "1", "100.001", "0", orderbook.Limit, ErrInvalidPrice},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.product.ValidateOrder(decimal.RequireFromString(tt.size), decimal.RequireFromString(tt.price),
+				decimal.RequireFromString(tt.stopPrice), tt.orderType)
+			if err != tt.want {
+				t.Errorf("Product.ValidateOrder() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProduct_FeeRate(t *testing.T) {
+	btcUsd := DefaultProducts[0]
+
+	tests := []struct {
+		name      string
+		product   Product
+		liquidity orderbook.Liquidity
+		volume    string
+		want      string
+	}{
+		{"should charge no maker fee", btcUsd, orderbook.Maker, "0", "0"},
+		{"should charge the taker fee of the lowest tier", btcUsd, orderbook.Taker, "9999999.99", "0.003"},
+		{"should charge the taker fee of the tier reached by the volume", btcUsd, orderbook.Taker, "10000000", "0.002"},
+		{"should charge the taker fee of the highest tier", btcUsd, orderbook.Taker, "500000000", "0.001"},
+		{"should charge nothing without fee tiers", Product{}, orderbook.Taker, "0", "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.product.FeeRate(tt.liquidity, decimal.RequireFromString(tt.volume)); got.String() != tt.want {
+				t.Errorf("Product.FeeRate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProduct_ValidateFeeTiers(t *testing.T) {
+	unordered := DefaultProducts[0]
+	unordered.FeeTiers = []FeeTier{unordered.FeeTiers[1], unordered.FeeTiers[0]}
+	negative := DefaultProducts[0]
+	negative.FeeTiers = []FeeTier{{TakerRate: decimal.RequireFromString("-0.001")}}
+
+	for _, p := range []Product{unordered, negative} {
+		if err := p.Validate(); err != ErrInvalidProduct {
+			t.Errorf("Product.Validate() error = %v, want %v", err, ErrInvalidProduct)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	config := `[{"id": "ETH-BTC", "base_currency": "ETH", "quote_currency": "BTC",
+		"base_min_size": "0.01", "base_max_size": "1000", "base_increment": "0.00000001",
+		"quote_increment": "0.00001", "status": "online"}]`
+
+	products, err := Load(strings.NewReader(config))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	c, err := NewCatalog(products...)
+	if err != nil {
+		t.Fatalf("NewCatalog() error = %v", err)
+	}
+	p, err := c.Get("ETH-BTC")
+	if err != nil {
+		t.Fatalf("Catalog.Get() error = %v", err)
+	}
+	if p.QuoteIncrement.String() != "0.00001" {
+		t.Errorf("Catalog.Get() = %+v", p)
+	}
+	if _, err := c.Get(orderbook.BtcUsd); err != ErrUnknownProduct {
+		t.Errorf("Catalog.Get() error = %v, want %v", err, ErrUnknownProduct)
+	}
+}
+
+func TestLoad_invalidProduct(t *testing.T) {
+	if _, err := Load(strings.NewReader(`[{"id": "ETH-BTC"}]`)); err != ErrInvalidProduct {
+		t.Errorf("Load() error = %v, want %v", err, ErrInvalidProduct)
+	}
+}
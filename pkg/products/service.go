@@ -0,0 +1,44 @@
+package products
+
+import (
+	"context"
+
+	"github.com/LAtanassov/godax/pkg/orderbook"
+)
+
+// Service specifies methods for the Product API.
+type Service interface {
+	// GetProduct returns a product of the catalog
+	GetProduct(ctx context.Context, id orderbook.ProductID) (Product, error)
+	// ListProducts returns all products of the catalog
+	ListProducts(ctx context.Context) ([]Product, error)
+	// SaveProduct adds or replaces a product in the catalog
+	SaveProduct(ctx context.Context, p Product) error
+}
+
+// ServiceMiddleware is a chainable behavior modifier for Service.
+type ServiceMiddleware func(Service) Service
+
+type service struct {
+	catalog Catalog
+}
+
+// NewService creates a product service with necessary dependencies.
+func NewService(catalog Catalog) Service {
+	return &service{catalog: catalog}
+}
+
+// GetProduct returns a product of the catalog
+func (s *service) GetProduct(ctx context.Context, id orderbook.ProductID) (Product, error) {
+	return s.catalog.Get(id)
+}
+
+// ListProducts returns all products of the catalog
+func (s *service) ListProducts(ctx context.Context) ([]Product, error) {
+	return s.catalog.List()
+}
+
+// SaveProduct adds or replaces a product in the catalog
+func (s *service) SaveProduct(ctx context.Context, p Product) error {
+	return s.catalog.Save(p)
+}
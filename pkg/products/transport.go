@@ -0,0 +1,116 @@
+package products
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	kitlog "github.com/go-kit/kit/log"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/gorilla/mux"
+)
+
+// MakeHandler returns a handler for the product service.
+// Products are listed publicly, saving a product changes the catalog for all accounts and is guarded by admin.
+func MakeHandler(s Service, admin func(http.Handler) http.Handler, logger kitlog.Logger) http.Handler {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorLogger(logger),
+		kithttp.ServerErrorEncoder(encodeError),
+	}
+
+	listProductsHandler := kithttp.NewServer(
+		makeListProductsEndpoint(s),
+		decodeListProductsRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	getProductHandler := kithttp.NewServer(
+		makeGetProductEndpoint(s),
+		decodeGetProductRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	saveProductHandler := kithttp.NewServer(
+		makeSaveProductEndpoint(s),
+		decodeSaveProductRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	r := mux.NewRouter()
+
+	r.Handle("/godax/v1/products", listProductsHandler).Methods("GET")
+	r.Handle("/godax/v1/products/{id}", getProductHandler).Methods("GET")
+	r.Handle("/godax/v1/products/{id}", admin(saveProductHandler)).Methods("PUT")
+
+	return r
+}
+
+var errBadRoute = errors.New("bad route")
+var errIllegalArgument = errors.New("illegal argument")
+
+func decodeListProductsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return listProductsRequest{}, nil
+}
+
+func decodeGetProductRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+	return getProductRequest{ID: orderbook.ProductID(id)}, nil
+}
+
+func decodeSaveProductRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+
+	var p Product
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		return nil, errIllegalArgument
+	}
+	defer r.Body.Close()
+
+	if p.ID != orderbook.ProductID(id) {
+		return nil, errIllegalArgument
+	}
+	return saveProductRequest{Product: p}, nil
+}
+
+type errorer interface {
+	error() error
+}
+
+func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if e, ok := response.(errorer); ok && e.error() != nil {
+		encodeError(ctx, e.error(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(w).Encode(response)
+}
+
+// encode errors from business-logic
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	switch err {
+	case errBadRoute, ErrUnknownProduct:
+		w.WriteHeader(http.StatusNotFound)
+	case errIllegalArgument, ErrInvalidProduct:
+		w.WriteHeader(http.StatusBadRequest)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": err.Error(),
+	})
+}
@@ -35,13 +35,13 @@ func (s *instrumentingService) AcceptOrder(ctx context.Context, id string) (err
 	return s.Service.AcceptOrder(ctx, id)
 }
 
-func (s *instrumentingService) RejectOrder(ctx context.Context, id string) (err error) {
+func (s *instrumentingService) RejectOrder(ctx context.Context, id string, reason orderbook.RejectReason, message string) (err error) {
 	defer func(begin time.Time) {
 		s.requestCount.With("method", "RejectOrder").Add(1)
 		s.requestLatency.With("method", "RejectOrder").Observe(time.Since(begin).Seconds())
 	}(time.Now())
 
-	return s.Service.RejectOrder(ctx, id)
+	return s.Service.RejectOrder(ctx, id, reason, message)
 }
 
 func (s *instrumentingService) GetPendingOrders() (orders []orderbook.Order, err error) {
@@ -34,17 +34,18 @@ func (s *loggingService) AcceptOrder(ctx context.Context, id string) (err error)
 	return s.Service.AcceptOrder(ctx, id)
 }
 
-func (s *loggingService) RejectOrder(ctx context.Context, id string) (err error) {
+func (s *loggingService) RejectOrder(ctx context.Context, id string, reason orderbook.RejectReason, message string) (err error) {
 	defer func(begin time.Time) {
 		s.logger.Log(
 			"method", "RejectOrder",
 			"id", id,
+			"reason", reason,
 			"took", time.Since(begin),
 			"err", err,
 		)
 	}(time.Now())
 
-	return s.Service.RejectOrder(ctx, id)
+	return s.Service.RejectOrder(ctx, id, reason, message)
 }
 
 func (s *loggingService) GetPendingOrders() (orders []orderbook.Order, err error) {
@@ -4,6 +4,7 @@ import "github.com/LAtanassov/godax/pkg/orderbook"
 
 // Repository abstracts database
 type Repository interface {
-	// GetPendingOrders returns them sorted (oldest first) and limited to 50
+	// GetPendingOrders returns them sorted (oldest first) and limited to 50,
+	// each order carries the AccountID of the account which placed it
 	GetPendingOrders() ([]orderbook.Order, error)
 }
@@ -11,9 +11,10 @@ import (
 type Service interface {
 	// AcceptOrder accepts an existing Order
 	AcceptOrder(ctx context.Context, id string) error
-	// RejectOrder rejects an existing Order
-	RejectOrder(ctx context.Context, id string) error
-	// GetPendingOrders returns them sorted (oldest first) and limited to 50
+	// RejectOrder rejects an existing Order with a reason code and a free text message
+	RejectOrder(ctx context.Context, id string, reason orderbook.RejectReason, message string) error
+	// GetPendingOrders returns them sorted (oldest first) and limited to 50,
+	// each order carries the AccountID of the account which placed it
 	GetPendingOrders() ([]orderbook.Order, error)
 }
 
@@ -34,13 +35,20 @@ func NewService(c orders.Client, r Repository) Service {
 }
 
 func (s *service) AcceptOrder(ctx context.Context, id string) error {
-	return s.client.AcceptOrder(ctx, id)
+	return s.client.AcceptOrder(withSource(ctx), id)
 }
 
-func (s *service) RejectOrder(ctx context.Context, id string) error {
-	return s.client.RejectOrder(ctx, id)
+func (s *service) RejectOrder(ctx context.Context, id string, reason orderbook.RejectReason, message string) error {
+	return s.client.RejectOrder(withSource(ctx), id, reason, message)
 }
 
 func (s *service) GetPendingOrders() ([]orderbook.Order, error) {
 	return s.repository.GetPendingOrders()
 }
+
+// withSource names the riskmonitor as source of the requests to the orders api
+func withSource(ctx context.Context) context.Context {
+	md := orderbook.MetadataFromContext(ctx)
+	md.Source = "riskmonitor"
+	return orderbook.NewContext(ctx, md)
+}
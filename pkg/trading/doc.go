@@ -0,0 +1,4 @@
+// Package trading represents the trading status of a product. A product is open for continuous trading
+// unless an operator restricts it to post only or cancel only orders, halts it during an incident or
+// collects its orders for an auction. Every change of the status is kept as event of the market of the product.
+package trading
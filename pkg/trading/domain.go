@@ -0,0 +1,173 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/altairsix/eventsource"
+)
+
+var (
+	// ErrHalted is returned when an order is placed, amended or published on a halted product
+	ErrHalted = errors.New("product is halted")
+	// ErrCancelOnly is returned when an order is placed, amended or published on a cancel only product
+	ErrCancelOnly = errors.New("product is cancel only")
+	// ErrPostOnly is returned when an order which is not post only is placed on a post only product
+	ErrPostOnly = errors.New("product is post only")
+	// ErrNoAuction is returned when the indicative uncross of a product is requested which is not in auction
+	ErrNoAuction = errors.New("product is not in auction")
+	// ErrInvalidStatus is returned when a product is changed to an unknown status
+	ErrInvalidStatus = errors.New("invalid trading status")
+	// ErrUnknownCommand is returned when a command is not supported by the market
+	ErrUnknownCommand = errors.New("unknown command")
+	// ErrUnknownEvent is returned when an event is not supported by the market
+	ErrUnknownEvent = errors.New("unknown event")
+)
+
+// Status of the trading of a product
+type Status string
+
+const (
+	// Open products accept and match all orders
+	Open Status = "open"
+	// PostOnly products accept post only orders, which never take liquidity
+	PostOnly Status = "post_only"
+	// CancelOnly products accept cancels only, resting orders stay in the book
+	CancelOnly Status = "cancel_only"
+	// Halted products accept cancels only and match no orders
+	Halted Status = "halted"
+	// Auction products accept orders but collect them without matching until the auction is uncrossed
+	Auction Status = "auction"
+)
+
+// Validate returns ErrInvalidStatus for unknown statuses
+func (s Status) Validate() error {
+	switch s {
+	case Open, PostOnly, CancelOnly, Halted, Auction:
+		return nil
+	}
+	return ErrInvalidStatus
+}
+
+// Accepts returns an error if an order can not be placed, amended or published in the status
+func (s Status) Accepts(postOnly bool) error {
+	switch s {
+	case PostOnly:
+		if !postOnly {
+			return ErrPostOnly
+		}
+	case CancelOnly:
+		return ErrCancelOnly
+	case Halted:
+		return ErrHalted
+	}
+	return nil
+}
+
+// Matches returns true if an order is submitted to the matching engine in the status,
+// post only orders never take liquidity and are matched by post only products.
+func (s Status) Matches(postOnly bool) bool {
+	switch s {
+	case Open:
+		return true
+	case PostOnly:
+		return postOnly
+	}
+	return false
+}
+
+// Events --------------
+
+// StatusChanged Event - an operator changed the trading status of a product
+type StatusChanged struct {
+	Status   Status
+	Reason   string
+	Metadata orderbook.Metadata
+	eventsource.Model
+}
+
+// Commands --------------
+
+// ChangeStatus Command - changing a product to its current status is ignored
+type ChangeStatus struct {
+	Status Status
+	Reason string
+
+	Metadata orderbook.Metadata
+	eventsource.CommandModel
+}
+
+// Aggregates --------------
+
+// Market of a product is an Aggregate which apply Events, its id is the product id
+type Market struct {
+	Reason string
+
+	id        string
+	version   int
+	updatedAt time.Time
+	status    Status
+}
+
+// ID returns the aggregate id of the market, the product id
+func (m Market) ID() string {
+	return m.id
+}
+
+// Version returns the version of the last event applied to the market
+func (m Market) Version() int {
+	return m.version
+}
+
+// Status returns the trading status of the product, products are open until their status is changed
+func (m Market) Status() Status {
+	if m.status == "" {
+		return Open
+	}
+	return m.status
+}
+
+// ChangedAt returns the time of the last status change, zero if it never changed
+func (m Market) ChangedAt() time.Time {
+	return m.updatedAt
+}
+
+// On applies events
+func (m *Market) On(event eventsource.Event) error {
+	switch v := event.(type) {
+	case *StatusChanged:
+		m.status = v.Status
+		m.Reason = v.Reason
+	default:
+		return ErrUnknownEvent
+	}
+
+	m.id = event.AggregateID()
+	m.version = event.EventVersion()
+	m.updatedAt = event.EventAt()
+	return nil
+}
+
+// Apply generates events from a command
+func (m *Market) Apply(ctx context.Context, command eventsource.Command) ([]eventsource.Event, error) {
+	switch v := command.(type) {
+	case *ChangeStatus:
+		if err := v.Status.Validate(); err != nil {
+			return nil, err
+		}
+		if v.Status == m.Status() {
+			return []eventsource.Event{}, nil
+		}
+		statusChanged := &StatusChanged{
+			Status:   v.Status,
+			Reason:   v.Reason,
+			Metadata: v.Metadata,
+			Model:    eventsource.Model{ID: command.AggregateID(), Version: m.version + 1, At: time.Now()},
+		}
+		return []eventsource.Event{statusChanged}, nil
+	default:
+		return nil, ErrUnknownCommand
+	}
+}
@@ -0,0 +1,83 @@
+package trading
+
+import (
+	"context"
+	"testing"
+
+	"github.com/altairsix/eventsource"
+)
+
+func TestMarket_Apply(t *testing.T) {
+	halted := &StatusChanged{Status: Halted, Reason: "incident", Model: eventsource.Model{ID: "BTC-USD", Version: 1}}
+
+	tests := []struct {
+		name       string
+		events     []eventsource.Event
+		command    eventsource.Command
+		wantErr    error
+		wantEvents int
+		wantStatus Status
+	}{
+		{"should be open until the status is changed", nil,
+			&ChangeStatus{Status: Open}, nil, 0, Open},
+		{"should halt the product", nil,
+			&ChangeStatus{Status: Halted, Reason: "incident"}, nil, 1, Halted},
+		{"should ignore the current status", []eventsource.Event{halted},
+			&ChangeStatus{Status: Halted}, nil, 0, Halted},
+		{"should reopen a halted product", []eventsource.Event{halted},
+			&ChangeStatus{Status: Open}, nil, 1, Open},
+		{"should not change to an unknown status", []eventsource.Event{halted},
+			&ChangeStatus{Status: "closed"}, ErrInvalidStatus, 0, Halted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Market{}
+			for _, e := range tt.events {
+				if err := m.On(e); err != nil {
+					t.Fatalf("Market.On() error = %v", err)
+				}
+			}
+			events, err := m.Apply(context.Background(), tt.command)
+			if err != tt.wantErr {
+				t.Fatalf("Market.Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(events) != tt.wantEvents {
+				t.Fatalf("Market.Apply() = %v events, want %v", len(events), tt.wantEvents)
+			}
+			for _, e := range events {
+				if err := m.On(e); err != nil {
+					t.Fatalf("Market.On() error = %v", err)
+				}
+			}
+			if m.Status() != tt.wantStatus {
+				t.Errorf("Market.Status() = %v, want %v", m.Status(), tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestStatus_Accepts(t *testing.T) {
+	tests := []struct {
+		status      Status
+		postOnly    bool
+		wantErr     error
+		wantMatches bool
+	}{
+		{Open, false, nil, true},
+		{PostOnly, false, ErrPostOnly, false},
+		{PostOnly, true, nil, true},
+		{CancelOnly, true, ErrCancelOnly, false},
+		{Halted, true, ErrHalted, false},
+		{Auction, false, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			if err := tt.status.Accepts(tt.postOnly); err != tt.wantErr {
+				t.Errorf("Status.Accepts(%v) error = %v, wantErr %v", tt.postOnly, err, tt.wantErr)
+			}
+			if got := tt.status.Matches(tt.postOnly); got != tt.wantMatches {
+				t.Errorf("Status.Matches(%v) = %v, want %v", tt.postOnly, got, tt.wantMatches)
+			}
+		})
+	}
+}
@@ -0,0 +1,4 @@
+// Package wallet represents the balances of an account per currency.
+// Funds are held for open orders so they can not be spent twice, the hold of an order
+// is converted into a transfer of its fills once the order is closed and the rest is released.
+package wallet
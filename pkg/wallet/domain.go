@@ -0,0 +1,314 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/LAtanassov/godax/pkg/orderbook"
+	"github.com/altairsix/eventsource"
+)
+
+var (
+	// ErrInsufficientFunds is returned when the available balance does not cover a hold or a transfer
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrInvalidAmount is returned for negative amounts, deposits have to be positive
+	ErrInvalidAmount = errors.New("invalid amount")
+	// ErrUnknownCommand is returned when a command is not supported by the account
+	ErrUnknownCommand = errors.New("unknown command")
+	// ErrUnknownEvent is returned when an event is not supported by the account
+	ErrUnknownEvent = errors.New("unknown event")
+)
+
+// Balance of a currency, the held funds are part of the total but not available
+type Balance struct {
+	Total decimal.Decimal
+	Hold  decimal.Decimal
+}
+
+// Available returns the funds which are not held
+func (b Balance) Available() decimal.Decimal {
+	return b.Total.Sub(b.Hold)
+}
+
+// Events --------------
+
+// FundsDeposited Event - funds were added to the account
+type FundsDeposited struct {
+	Currency string
+	Amount   decimal.Decimal
+	Metadata orderbook.Metadata
+	eventsource.Model
+}
+
+// FundsHeld Event - available funds were held for an order
+type FundsHeld struct {
+	HoldID   string
+	Currency string
+	Amount   decimal.Decimal
+	Metadata orderbook.Metadata
+	eventsource.Model
+}
+
+// HoldReleased Event - the funds of a hold are available again
+type HoldReleased struct {
+	HoldID   string
+	Metadata orderbook.Metadata
+	eventsource.Model
+}
+
+// HoldResized Event - the hold of an amended order was changed to Amount
+type HoldResized struct {
+	HoldID   string
+	Amount   decimal.Decimal
+	Metadata orderbook.Metadata
+	eventsource.Model
+}
+
+// HoldSettled Event - the hold was converted into a transfer, Debit was removed in the currency of the hold,
+// Credit was added in CreditCurrency and the rest of the hold is available again
+type HoldSettled struct {
+	HoldID         string
+	Debit          decimal.Decimal
+	CreditCurrency string
+	Credit         decimal.Decimal
+	Metadata       orderbook.Metadata
+	eventsource.Model
+}
+
+// Commands --------------
+
+// DepositFunds Command
+type DepositFunds struct {
+	Currency string
+	Amount   decimal.Decimal
+
+	Metadata orderbook.Metadata
+	eventsource.CommandModel
+}
+
+// PlaceHold Command - holds funds for an order, a hold is placed once per HoldID
+type PlaceHold struct {
+	HoldID   string
+	Currency string
+	Amount   decimal.Decimal
+
+	Metadata orderbook.Metadata
+	eventsource.CommandModel
+}
+
+// ReleaseHold Command - releases a hold, released or settled holds are ignored
+type ReleaseHold struct {
+	HoldID string
+
+	Metadata orderbook.Metadata
+	eventsource.CommandModel
+}
+
+// ResizeHold Command - changes the amount of a hold, released or settled holds are ignored
+type ResizeHold struct {
+	HoldID string
+	Amount decimal.Decimal
+
+	Metadata orderbook.Metadata
+	eventsource.CommandModel
+}
+
+// SettleHold Command - converts a hold into a transfer, released or settled holds are ignored
+type SettleHold struct {
+	HoldID         string
+	Debit          decimal.Decimal
+	CreditCurrency string
+	Credit         decimal.Decimal
+
+	Metadata orderbook.Metadata
+	eventsource.CommandModel
+}
+
+// Aggregates --------------
+
+// hold is funds held in a currency
+type hold struct {
+	currency string
+	amount   decimal.Decimal
+}
+
+// Account is an Aggregate which apply Events
+type Account struct {
+	balances map[string]Balance
+	holds    map[string]hold
+
+	id        string
+	version   int
+	updatedAt time.Time
+}
+
+// ID returns the aggregate id of the account
+func (a Account) ID() string {
+	return a.id
+}
+
+// Version returns the version of the last event applied to the account
+func (a Account) Version() int {
+	return a.version
+}
+
+// Balance returns the balance of a currency
+func (a Account) Balance(currency string) Balance {
+	return a.balances[currency]
+}
+
+// Balances returns the balance of every currency the account ever held
+func (a Account) Balances() map[string]Balance {
+	balances := map[string]Balance{}
+	for currency, b := range a.balances {
+		balances[currency] = b
+	}
+	return balances
+}
+
+// On applies events
+func (a *Account) On(event eventsource.Event) error {
+	if a.balances == nil {
+		a.balances = map[string]Balance{}
+		a.holds = map[string]hold{}
+	}
+
+	switch v := event.(type) {
+	case *FundsDeposited:
+		b := a.balances[v.Currency]
+		b.Total = b.Total.Add(v.Amount)
+		a.balances[v.Currency] = b
+	case *FundsHeld:
+		b := a.balances[v.Currency]
+		b.Hold = b.Hold.Add(v.Amount)
+		a.balances[v.Currency] = b
+		a.holds[v.HoldID] = hold{currency: v.Currency, amount: v.Amount}
+	case *HoldReleased:
+		a.release(v.HoldID)
+	case *HoldResized:
+		h := a.holds[v.HoldID]
+		a.release(v.HoldID)
+		b := a.balances[h.currency]
+		b.Hold = b.Hold.Add(v.Amount)
+		a.balances[h.currency] = b
+		a.holds[v.HoldID] = hold{currency: h.currency, amount: v.Amount}
+	case *HoldSettled:
+		h := a.holds[v.HoldID]
+		a.release(v.HoldID)
+		b := a.balances[h.currency]
+		b.Total = b.Total.Sub(v.Debit)
+		a.balances[h.currency] = b
+		c := a.balances[v.CreditCurrency]
+		c.Total = c.Total.Add(v.Credit)
+		a.balances[v.CreditCurrency] = c
+	default:
+		return ErrUnknownEvent
+	}
+
+	a.id = event.AggregateID()
+	a.version = event.EventVersion()
+	a.updatedAt = event.EventAt()
+	return nil
+}
+
+// release makes the funds of a hold available again
+func (a *Account) release(holdID string) {
+	h, ok := a.holds[holdID]
+	if !ok {
+		return
+	}
+	b := a.balances[h.currency]
+	b.Hold = b.Hold.Sub(h.amount)
+	a.balances[h.currency] = b
+	delete(a.holds, holdID)
+}
+
+// Apply generates events from a command
+func (a *Account) Apply(ctx context.Context, command eventsource.Command) ([]eventsource.Event, error) {
+	model := eventsource.Model{ID: command.AggregateID(), Version: a.version + 1, At: time.Now()}
+
+	switch v := command.(type) {
+	case *DepositFunds:
+		if !v.Amount.IsPositive() || v.Currency == "" {
+			return nil, ErrInvalidAmount
+		}
+		fundsDeposited := &FundsDeposited{
+			Currency: v.Currency,
+			Amount:   v.Amount,
+			Metadata: v.Metadata,
+			Model:    model,
+		}
+		return []eventsource.Event{fundsDeposited}, nil
+	case *PlaceHold:
+		if _, ok := a.holds[v.HoldID]; ok {
+			return []eventsource.Event{}, nil
+		}
+		if v.Amount.Sign() < 0 {
+			return nil, ErrInvalidAmount
+		}
+		if a.Balance(v.Currency).Available().LessThan(v.Amount) {
+			return nil, ErrInsufficientFunds
+		}
+		fundsHeld := &FundsHeld{
+			HoldID:   v.HoldID,
+			Currency: v.Currency,
+			Amount:   v.Amount,
+			Metadata: v.Metadata,
+			Model:    model,
+		}
+		return []eventsource.Event{fundsHeld}, nil
+	case *ReleaseHold:
+		if _, ok := a.holds[v.HoldID]; !ok {
+			return []eventsource.Event{}, nil
+		}
+		holdReleased := &HoldReleased{
+			HoldID:   v.HoldID,
+			Metadata: v.Metadata,
+			Model:    model,
+		}
+		return []eventsource.Event{holdReleased}, nil
+	case *ResizeHold:
+		h, ok := a.holds[v.HoldID]
+		if !ok {
+			return []eventsource.Event{}, nil
+		}
+		if v.Amount.Sign() < 0 {
+			return nil, ErrInvalidAmount
+		}
+		if a.Balance(h.currency).Available().Add(h.amount).LessThan(v.Amount) {
+			return nil, ErrInsufficientFunds
+		}
+		holdResized := &HoldResized{
+			HoldID:   v.HoldID,
+			Amount:   v.Amount,
+			Metadata: v.Metadata,
+			Model:    model,
+		}
+		return []eventsource.Event{holdResized}, nil
+	case *SettleHold:
+		h, ok := a.holds[v.HoldID]
+		if !ok {
+			return []eventsource.Event{}, nil
+		}
+		if v.Debit.Sign() < 0 || v.Credit.Sign() < 0 {
+			return nil, ErrInvalidAmount
+		}
+		// the debit may exceed the hold, e.g. for the slippage of a market order, as long as it is available
+		if a.Balance(h.currency).Available().Add(h.amount).LessThan(v.Debit) {
+			return nil, ErrInsufficientFunds
+		}
+		holdSettled := &HoldSettled{
+			HoldID:         v.HoldID,
+			Debit:          v.Debit,
+			CreditCurrency: v.CreditCurrency,
+			Credit:         v.Credit,
+			Metadata:       v.Metadata,
+			Model:          model,
+		}
+		return []eventsource.Event{holdSettled}, nil
+	default:
+		return nil, ErrUnknownCommand
+	}
+}
@@ -0,0 +1,93 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/LAtanassov/godax/pkg/decimal"
+	"github.com/altairsix/eventsource"
+)
+
+// newTestAccount returns an account with 10 BTC of which 4 are held for order o1
+func newTestAccount(t *testing.T) *Account {
+	a := &Account{}
+	for _, e := range []eventsource.Event{
+		&FundsDeposited{Currency: "BTC", Amount: decimal.NewFromInt(10), Model: eventsource.Model{ID: "alice", Version: 1}},
+		&FundsHeld{HoldID: "o1", Currency: "BTC", Amount: decimal.NewFromInt(4), Model: eventsource.Model{ID: "alice", Version: 2}},
+	} {
+		if err := a.On(e); err != nil {
+			t.Fatalf("Account.On() error = %v", err)
+		}
+	}
+	return a
+}
+
+func TestAccount_Apply(t *testing.T) {
+	tests := []struct {
+		name          string
+		command       eventsource.Command
+		wantErr       error
+		wantEvents    int
+		wantBTC       Balance
+		wantUSDTotal  int64
+		wantAvailable int64
+	}{
+		{"should deposit funds", &DepositFunds{Currency: "BTC", Amount: decimal.NewFromInt(1)},
+			nil, 1, Balance{Total: decimal.NewFromInt(11), Hold: decimal.NewFromInt(4)}, 0, 7},
+		{"should reject a deposit without amount", &DepositFunds{Currency: "BTC"},
+			ErrInvalidAmount, 0, Balance{}, 0, 0},
+		{"should hold available funds", &PlaceHold{HoldID: "o2", Currency: "BTC", Amount: decimal.NewFromInt(6)},
+			nil, 1, Balance{Total: decimal.NewFromInt(10), Hold: decimal.NewFromInt(10)}, 0, 0},
+		{"should not hold more than the available funds", &PlaceHold{HoldID: "o2", Currency: "BTC", Amount: decimal.NewFromInt(7)},
+			ErrInsufficientFunds, 0, Balance{}, 0, 0},
+		{"should place a hold only once", &PlaceHold{HoldID: "o1", Currency: "BTC", Amount: decimal.NewFromInt(4)},
+			nil, 0, Balance{Total: decimal.NewFromInt(10), Hold: decimal.NewFromInt(4)}, 0, 6},
+		{"should release a hold", &ReleaseHold{HoldID: "o1"},
+			nil, 1, Balance{Total: decimal.NewFromInt(10)}, 0, 10},
+		{"should ignore the release of an unknown hold", &ReleaseHold{HoldID: "o2"},
+			nil, 0, Balance{Total: decimal.NewFromInt(10), Hold: decimal.NewFromInt(4)}, 0, 6},
+		{"should resize a hold within the available funds", &ResizeHold{HoldID: "o1", Amount: decimal.NewFromInt(10)},
+			nil, 1, Balance{Total: decimal.NewFromInt(10), Hold: decimal.NewFromInt(10)}, 0, 0},
+		{"should not resize a hold beyond the available funds", &ResizeHold{HoldID: "o1", Amount: decimal.NewFromInt(11)},
+			ErrInsufficientFunds, 0, Balance{}, 0, 0},
+		{"should ignore the resize of an unknown hold", &ResizeHold{HoldID: "o2", Amount: decimal.NewFromInt(1)},
+			nil, 0, Balance{Total: decimal.NewFromInt(10), Hold: decimal.NewFromInt(4)}, 0, 6},
+		{"should transfer the debit and release the rest of a settled hold",
+			&SettleHold{HoldID: "o1", Debit: decimal.NewFromInt(3), CreditCurrency: "USD", Credit: decimal.NewFromInt(300)},
+			nil, 1, Balance{Total: decimal.NewFromInt(7)}, 300, 7},
+		{"should not settle more than the hold and the available funds",
+			&SettleHold{HoldID: "o1", Debit: decimal.NewFromInt(11), CreditCurrency: "USD", Credit: decimal.NewFromInt(1100)},
+			ErrInsufficientFunds, 0, Balance{}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAccount(t)
+			events, err := a.Apply(context.Background(), tt.command)
+			if err != tt.wantErr {
+				t.Fatalf("Account.Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(events) != tt.wantEvents {
+				t.Fatalf("Account.Apply() = %v, want %v events", events, tt.wantEvents)
+			}
+			for _, e := range events {
+				if err := a.On(e); err != nil {
+					t.Fatalf("Account.On() error = %v", err)
+				}
+			}
+
+			btc := a.Balance("BTC")
+			if !btc.Total.Equal(tt.wantBTC.Total) || !btc.Hold.Equal(tt.wantBTC.Hold) {
+				t.Errorf("Account.Balance(BTC) = %+v, want %+v", btc, tt.wantBTC)
+			}
+			if !btc.Available().Equal(decimal.NewFromInt(tt.wantAvailable)) {
+				t.Errorf("Balance.Available() = %v, want %v", btc.Available(), tt.wantAvailable)
+			}
+			if usd := a.Balance("USD"); !usd.Total.Equal(decimal.NewFromInt(tt.wantUSDTotal)) {
+				t.Errorf("Account.Balance(USD) = %+v, want total %v", usd, tt.wantUSDTotal)
+			}
+		})
+	}
+}